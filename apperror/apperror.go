@@ -0,0 +1,221 @@
+package apperror
+
+import (
+	"errors"
+
+	"baseApi/dto"
+	"baseApi/i18n"
+)
+
+/* Kind classifies an application error independently of the transport */
+type Kind string
+
+const (
+	KindBadRequest           Kind = "bad_request"
+	KindValidation           Kind = "validation"
+	KindUnauthorized         Kind = "unauthorized"
+	KindForbidden            Kind = "forbidden"
+	KindNotFound             Kind = "not_found"
+	KindAlreadyExists        Kind = "already_exists"
+	KindConflict             Kind = "conflict"
+	KindPreconditionFailed   Kind = "precondition_failed"
+	KindPreconditionRequired Kind = "precondition_required"
+	KindUnsupportedMedia     Kind = "unsupported_media"
+	KindUnprocessable        Kind = "unprocessable"
+	KindInternal             Kind = "internal"
+)
+
+/* Error is a typed application error carrying everything needed to render a response */
+type Error struct {
+	Kind        Kind
+	Code        string
+	Status      int
+	Message     string
+	Details     string
+	Validations []dto.ValidationError
+	Err         error
+
+	// Key and Params select the i18n catalog message; without a Key the generic
+	// "error.<Code>" entry is used for non-default locales
+	Key    string
+	Params map[string]string
+}
+
+/* Error implements the error interface; it never includes the cause */
+func (e *Error) Error() string {
+	return e.Message
+}
+
+/* Unwrap returns the wrapped cause */
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+/* WithCause returns a copy of the error wrapping the given cause */
+func (e *Error) WithCause(err error) *Error {
+	clone := *e
+	clone.Err = err
+	return &clone
+}
+
+/* WithDetails returns a copy of the error with client-safe details */
+func (e *Error) WithDetails(details string) *Error {
+	clone := *e
+	clone.Details = details
+	return &clone
+}
+
+/* WithKey returns a copy of the error localized through the given catalog key */
+func (e *Error) WithKey(key string, params map[string]string) *Error {
+	clone := *e
+	clone.Key = key
+	clone.Params = params
+	return &clone
+}
+
+/* New creates an application error of the given kind, status and code */
+func New(kind Kind, status int, code, message string) *Error {
+	return &Error{Kind: kind, Status: status, Code: code, Message: message}
+}
+
+/* BadRequest creates a 400 error */
+func BadRequest(message string) *Error {
+	return New(KindBadRequest, dto.StatusBadRequest, dto.ErrorCodeBadRequest, message)
+}
+
+/* InvalidFormat creates a 400 error for malformed payloads */
+func InvalidFormat(message string) *Error {
+	return New(KindBadRequest, dto.StatusBadRequest, dto.ErrorCodeInvalidFormat, message)
+}
+
+/* Validation creates a 400 error listing field validation failures */
+func Validation(validations []dto.ValidationError) *Error {
+	err := New(KindValidation, dto.StatusBadRequest, dto.ErrorCodeValidation, "Request validation failed")
+	err.Validations = validations
+	err.Key = "error.validation_failed"
+	return err
+}
+
+/* Unauthorized creates a 401 error */
+func Unauthorized(message string) *Error {
+	return New(KindUnauthorized, dto.StatusUnauthorized, dto.ErrorCodeUnauthorized, message)
+}
+
+/* Forbidden creates a 403 error */
+func Forbidden(message string) *Error {
+	return New(KindForbidden, dto.StatusForbidden, dto.ErrorCodeForbidden, message)
+}
+
+/* NotFound creates a 404 error for a missing resource */
+func NotFound(resource string) *Error {
+	return New(KindNotFound, dto.StatusNotFound, dto.ErrorCodeNotFound, resource+" not found").
+		WithKey("error.resource_not_found", map[string]string{"resource": resource})
+}
+
+/* AlreadyExists creates a 409 error naming the field whose value is taken */
+func AlreadyExists(resource, field string) *Error {
+	err := New(KindAlreadyExists, dto.StatusConflict, dto.ErrorCodeAlreadyExists, resource+" with this "+field+" already exists")
+	err.Key = "error.resource_already_exists"
+	err.Params = map[string]string{"resource": resource, "field": field}
+	err.Validations = []dto.ValidationError{{
+		Field:   field,
+		Rule:    "unique",
+		Message: field + " already exists",
+		Key:     "validation.already_exists",
+		Params:  map[string]string{"field": field},
+	}}
+	return err
+}
+
+/* Conflict creates a 409 error */
+func Conflict(message string) *Error {
+	return New(KindConflict, dto.StatusConflict, dto.ErrorCodeConflict, message)
+}
+
+/* PreconditionFailed creates a 412 error for stale conditional writes */
+func PreconditionFailed(message string) *Error {
+	return New(KindPreconditionFailed, dto.StatusPreconditionFailed, dto.ErrorCodeConflict, message)
+}
+
+/* PreconditionRequired creates a 428 error */
+func PreconditionRequired(message string) *Error {
+	return New(KindPreconditionRequired, dto.StatusPreconditionRequired, dto.ErrorCodePreconditionRequired, message)
+}
+
+/* UnsupportedMediaType creates a 415 error */
+func UnsupportedMediaType(message string) *Error {
+	return New(KindUnsupportedMedia, dto.StatusUnsupportedMedia, dto.ErrorCodeUnsupportedMedia, message)
+}
+
+/* Unprocessable creates a 422 error listing fields that violate data constraints */
+func Unprocessable(validations []dto.ValidationError) *Error {
+	err := New(KindUnprocessable, dto.StatusUnprocessableEntity, dto.ErrorCodeValidation, "Request violates data constraints")
+	err.Validations = validations
+	err.Key = "error.constraint_violation"
+	return err
+}
+
+/* Internal creates a 500 error with a safe message, wrapping the real cause */
+func Internal(code, message string, cause error) *Error {
+	err := New(KindInternal, dto.StatusInternalServerError, code, message)
+	err.Err = cause
+	return err
+}
+
+/* From returns the application error in err's chain, or wraps err as an internal error */
+func From(err error) *Error {
+	var appErr *Error
+	if errors.As(err, &appErr) {
+		return appErr
+	}
+	return Internal(dto.ErrorCodeInternalServer, "Internal server error occurred", err)
+}
+
+/* IsKind reports whether err is an application error of the given kind */
+func IsKind(err error, kind Kind) bool {
+	var appErr *Error
+	return errors.As(err, &appErr) && appErr.Kind == kind
+}
+
+/* Localize returns a copy of the error with its message and validations in the given locale */
+func (e *Error) Localize(locale string) *Error {
+	clone := *e
+
+	switch {
+	case e.Key != "":
+		params := make(map[string]string, len(e.Params))
+		for name, value := range e.Params {
+			params[name] = value
+		}
+		// Resource names are catalog terms too ("User" -> "người dùng")
+		if resource, ok := params["resource"]; ok {
+			if term, found := i18n.Lookup(locale, "resource."+resource); found {
+				params["resource"] = term
+			}
+		}
+		clone.Message = i18n.T(locale, e.Key, params)
+	case locale != i18n.DefaultLocale:
+		if message, found := i18n.Lookup(locale, "error."+e.Code); found {
+			clone.Message = message
+		}
+	}
+
+	if len(e.Validations) > 0 {
+		clone.Validations = make([]dto.ValidationError, len(e.Validations))
+		for i, v := range e.Validations {
+			if v.Key != "" {
+				v.Message = i18n.T(locale, v.Key, v.Params)
+			}
+			clone.Validations[i] = v
+		}
+	}
+
+	return &clone
+}
+
+/* ToResponse renders the error as the standard API response */
+func (e *Error) ToResponse() dto.APIResponse {
+	response := dto.ErrorResponseWithDetails(e.Status, e.Code, e.Message, e.Details)
+	response.Error.Validations = e.Validations
+	return response
+}
@@ -52,17 +52,59 @@ func Get(key string, dest interface{}) error {
 	return json.Unmarshal([]byte(val), dest)
 }
 
+/* GetMany retrieves several values in one round trip, calling found with the index of each key present */
+func GetMany(keys []string, found func(i int, value []byte)) error {
+	values, err := RedisClient.MGet(ctx, keys...).Result()
+	if err != nil {
+		return err
+	}
+
+	for i, value := range values {
+		if s, ok := value.(string); ok {
+			found(i, []byte(s))
+		}
+	}
+	return nil
+}
+
 /* Delete removes a key from Redis */
 func Delete(key string) error {
 	return RedisClient.Del(ctx, key).Err()
 }
 
+// deleteIfValue deletes a key only while it still holds the expected value
+var deleteIfValue = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0`)
+
+/* DeleteIfValue removes a key only if it still holds value, so an expired lock taken over by someone else is kept */
+func DeleteIfValue(key string, value interface{}) error {
+	json, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+
+	return deleteIfValue.Run(ctx, RedisClient, []string{key}, string(json)).Err()
+}
+
 /* Exists checks if a key exists in Redis */
 func Exists(key string) (bool, error) {
 	count, err := RedisClient.Exists(ctx, key).Result()
 	return count > 0, err
 }
 
+/* SetNX stores a value only if the key does not exist yet, reporting whether it was stored */
+func SetNX(key string, value interface{}, expiration time.Duration) (bool, error) {
+	json, err := json.Marshal(value)
+	if err != nil {
+		return false, err
+	}
+
+	return RedisClient.SetNX(ctx, key, json, expiration).Result()
+}
+
 /* SetWithoutExpiration stores a value in Redis without expiration */
 func SetWithoutExpiration(key string, value interface{}) error {
 	json, err := json.Marshal(value)
@@ -46,6 +46,9 @@ func InitDatabase(cfg *config.Config) {
 func AutoMigrate() error {
 	return DB.AutoMigrate(
 		&models.User{},
+		&models.OutboxEvent{},
+		&models.WebhookEndpoint{},
+		&models.WebhookDelivery{},
 	)
 }
 
@@ -1,6 +1,12 @@
 package dto
 
-import "time"
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"baseApi/i18n"
+)
 
 // ===========================================
 // STANDARD API RESPONSE STRUCTURE
@@ -152,6 +158,63 @@ func NewPaginationMeta(currentPage, perPage int, totalItems int64) *PaginationMe
 	}
 }
 
+// ===========================================
+// RFC 7807 PROBLEM DETAILS
+// ===========================================
+
+/* ProblemDetails represents an RFC 7807 problem document, the alternative error envelope */
+type ProblemDetails struct {
+	Type        string            `json:"type"`
+	Title       string            `json:"title"`
+	Status      int               `json:"status"`
+	Detail      string            `json:"detail,omitempty"`
+	Instance    string            `json:"instance,omitempty"`
+	Code        string            `json:"code"`
+	Validations []ValidationError `json:"validations,omitempty"`
+	RequestID   string            `json:"requestId,omitempty"`
+	Timestamp   string            `json:"timestamp"`
+}
+
+// ProblemTypeBaseURI prefixes problem type URIs; one URI per ErrorCode* value
+var ProblemTypeBaseURI = "https://api.example.com/problems/"
+
+/* ProblemTypeURI returns the problem type URI for an error code, e.g. NOT_FOUND -> .../not-found */
+func ProblemTypeURI(code string) string {
+	if code == "" {
+		return "about:blank"
+	}
+	return ProblemTypeBaseURI + strings.ToLower(strings.ReplaceAll(code, "_", "-"))
+}
+
+/* ToProblem converts an error APIResponse into an RFC 7807 problem document */
+func (r APIResponse) ToProblem(instance, locale string) ProblemDetails {
+	problem := ProblemDetails{
+		Type:      "about:blank",
+		Title:     http.StatusText(r.StatusCode),
+		Status:    r.StatusCode,
+		Detail:    r.Message,
+		Instance:  instance,
+		Timestamp: getCurrentTimestamp(),
+	}
+	if r.Error == nil {
+		return problem
+	}
+
+	problem.Type = ProblemTypeURI(r.Error.Code)
+	problem.Code = r.Error.Code
+	// Titles come from the same i18n catalog entry as the generic message for the code
+	if title, ok := i18n.Lookup(locale, "error."+r.Error.Code); ok {
+		problem.Title = title
+	}
+	if r.Error.Details != "" {
+		problem.Detail = r.Error.Message + ": " + r.Error.Details
+	}
+	problem.Validations = r.Error.Validations
+	problem.RequestID = r.Error.RequestID
+	problem.Timestamp = r.Error.Timestamp
+	return problem
+}
+
 // ===========================================
 // HELPER FUNCTIONS
 // ===========================================
@@ -178,7 +241,10 @@ const (
 	StatusNotFound            = 404
 	StatusMethodNotAllowed    = 405
 	StatusConflict            = 409
+	StatusPreconditionFailed  = 412
+	StatusUnsupportedMedia    = 415
 	StatusUnprocessableEntity = 422
+	StatusPreconditionRequired = 428
 	StatusTooManyRequests     = 429
 	
 	// Server error codes
@@ -188,6 +254,18 @@ const (
 	StatusGatewayTimeout     = 504
 )
 
+// ===========================================
+// CONTENT TYPES
+// ===========================================
+
+const (
+	ContentTypeJSON       = "application/json"
+	ContentTypeProblem    = "application/problem+json" // RFC 7807
+	ContentTypeMergePatch = "application/merge-patch+json" // RFC 7386
+	ContentTypeJSONPatch  = "application/json-patch+json"  // RFC 6902
+	ContentTypeSchema     = "application/schema+json"      // JSON Schema
+)
+
 // ===========================================
 // ERROR CODES
 // ===========================================
@@ -203,11 +281,13 @@ const (
 	ErrorCodeValidation      = "VALIDATION_ERROR"
 	ErrorCodeBadRequest      = "BAD_REQUEST"
 	ErrorCodeInvalidFormat   = "INVALID_FORMAT"
+	ErrorCodeUnsupportedMedia = "UNSUPPORTED_MEDIA_TYPE"
 	
 	// Resource
 	ErrorCodeNotFound        = "NOT_FOUND"
 	ErrorCodeAlreadyExists   = "ALREADY_EXISTS"
 	ErrorCodeConflict        = "CONFLICT"
+	ErrorCodePreconditionRequired = "PRECONDITION_REQUIRED"
 	
 	// Server
 	ErrorCodeInternalServer  = "INTERNAL_SERVER_ERROR"
@@ -8,8 +8,8 @@ import "time"
 
 /* CreateUserRequest represents the request structure for creating a user */
 type CreateUserRequest struct {
-	Username  string `json:"username" binding:"required,min=3,max=50"`
-	Email     string `json:"email" binding:"required,email,max=100"`
+	Username  string `json:"username" binding:"required,username_format"`
+	Email     string `json:"email" binding:"required,email,email_format,max=100"`
 	Password  string `json:"password" binding:"required,min=6,max=255"`
 	FirstName string `json:"firstName" binding:"max=50"`
 	LastName  string `json:"lastName" binding:"max=50"`
@@ -17,13 +17,22 @@ type CreateUserRequest struct {
 
 /* UpdateUserRequest represents the request structure for updating a user */
 type UpdateUserRequest struct {
-	Username  string `json:"username" binding:"omitempty,min=3,max=50"`
-	Email     string `json:"email" binding:"omitempty,email,max=100"`
+	Username  string `json:"username" binding:"omitempty,username_format"`
+	Email     string `json:"email" binding:"omitempty,email,email_format,max=100"`
 	FirstName string `json:"firstName" binding:"omitempty,max=50"`
 	LastName  string `json:"lastName" binding:"omitempty,max=50"`
 	IsActive  *bool  `json:"isActive"`
 }
 
+/* PatchUserDocument represents the patchable view of a user targeted by PATCH requests */
+type PatchUserDocument struct {
+	Username  string `json:"username" binding:"required,username_format"`
+	Email     string `json:"email" binding:"required,email,email_format,max=100"`
+	FirstName string `json:"firstName" binding:"max=50"`
+	LastName  string `json:"lastName" binding:"max=50"`
+	IsActive  *bool  `json:"isActive" binding:"required"`
+}
+
 /* LoginRequest represents the request structure for user login */
 type LoginRequest struct {
 	Username string `json:"username" binding:"required"`
@@ -46,6 +55,16 @@ type UserSearchRequest struct {
 	IsActive *bool  `json:"isActive" form:"isActive"`
 }
 
+/* UserCursorRequest represents the request structure for listing users page by page with a cursor */
+type UserCursorRequest struct {
+	Query    string `json:"query" form:"query"`
+	Limit    int    `json:"limit" form:"limit" binding:"omitempty,min=1,max=100"`
+	Cursor   string `json:"cursor" form:"cursor"`
+	SortBy   string `json:"sortBy" form:"sortBy" binding:"omitempty,oneof=username email firstName lastName isActive createdAt updatedAt"`
+	SortDesc bool   `json:"sortDesc" form:"sortDesc"`
+	IsActive *bool  `json:"isActive" form:"isActive"`
+}
+
 // ===========================================
 // RESPONSE DTOs
 // ===========================================
@@ -58,6 +77,7 @@ type UserResponse struct {
 	FirstName string    `json:"firstName"`
 	LastName  string    `json:"lastName"`
 	IsActive  bool      `json:"isActive"`
+	Version   uint      `json:"version"`
 	CreatedAt time.Time `json:"createdAt"`
 	UpdatedAt time.Time `json:"updatedAt"`
 }
@@ -68,6 +88,13 @@ type UserListResponse struct {
 	Pagination PaginationMeta `json:"pagination"`
 }
 
+/* UserCursorResponse represents one cursor page of users; NextCursor is empty on the last page */
+type UserCursorResponse struct {
+	Users      []UserResponse `json:"users"`
+	NextCursor string         `json:"nextCursor"`
+	TotalItems int64          `json:"totalItems"`
+}
+
 /* UserStatsResponse represents the response structure for user statistics */
 type UserStatsResponse struct {
 	TotalUsers       int64 `json:"totalUsers"`
@@ -103,51 +130,19 @@ type PaginationMeta struct {
 /* ValidationError represents field validation error */
 type ValidationError struct {
 	Field   string `json:"field"`
+	Rule    string `json:"rule,omitempty"`
 	Message string `json:"message"`
 	Value   string `json:"value,omitempty"`
+
+	// i18n catalog key and placeholders used to localize Message
+	Key    string            `json:"-"`
+	Params map[string]string `json:"-"`
 }
 
 // ===========================================
 // VALIDATION HELPERS
 // ===========================================
 
-/* Validate validates CreateUserRequest */
-func (r *CreateUserRequest) Validate() []ValidationError {
-	var errors []ValidationError
-
-	if len(r.Username) < 3 || len(r.Username) > 50 {
-		errors = append(errors, ValidationError{
-			Field:   "username",
-			Message: "Username must be between 3 and 50 characters",
-			Value:   r.Username,
-		})
-	}
-
-	if len(r.Password) < 6 || len(r.Password) > 255 {
-		errors = append(errors, ValidationError{
-			Field:   "password",
-			Message: "Password must be between 6 and 255 characters",
-		})
-	}
-
-	return errors
-}
-
-/* Validate validates UpdateUserRequest */
-func (r *UpdateUserRequest) Validate() []ValidationError {
-	var errors []ValidationError
-
-	if r.Username != "" && (len(r.Username) < 3 || len(r.Username) > 50) {
-		errors = append(errors, ValidationError{
-			Field:   "username",
-			Message: "Username must be between 3 and 50 characters",
-			Value:   r.Username,
-		})
-	}
-
-	return errors
-}
-
 /* SetDefaults sets default values for UserSearchRequest */
 func (r *UserSearchRequest) SetDefaults() {
 	if r.Page <= 0 {
@@ -162,4 +157,17 @@ func (r *UserSearchRequest) SetDefaults() {
 	if r.SortBy == "" {
 		r.SortBy = "createdAt"
 	}
+}
+
+/* SetDefaults sets default values for UserCursorRequest */
+func (r *UserCursorRequest) SetDefaults() {
+	if r.Limit <= 0 {
+		r.Limit = 10
+	}
+	if r.Limit > 100 {
+		r.Limit = 100
+	}
+	if r.SortBy == "" {
+		r.SortBy = "createdAt"
+	}
 }
\ No newline at end of file
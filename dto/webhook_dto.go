@@ -0,0 +1,96 @@
+package dto
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// ===========================================
+// REQUEST DTOs
+// ===========================================
+
+/* CreateWebhookRequest represents the request structure for registering a webhook endpoint */
+type CreateWebhookRequest struct {
+	URL         string   `json:"url" binding:"required,http_url,max=500"`
+	Description string   `json:"description" binding:"max=255"`
+	Events      []string `json:"events" binding:"required,min=1,dive,webhook_event"`
+	Secret      string   `json:"secret" binding:"omitempty,min=16,max=128"` // generated when empty
+}
+
+/* UpdateWebhookRequest represents the request structure for updating a webhook endpoint */
+type UpdateWebhookRequest struct {
+	URL         string   `json:"url" binding:"omitempty,http_url,max=500"`
+	Description *string  `json:"description" binding:"omitempty,max=255"`
+	Events      []string `json:"events" binding:"omitempty,min=1,dive,webhook_event"`
+	IsActive    *bool    `json:"isActive"` // true re-enables an auto-disabled endpoint
+}
+
+/* WebhookDeliverySearchRequest represents the query for a webhook's delivery log */
+type WebhookDeliverySearchRequest struct {
+	Status    string `form:"status" binding:"omitempty,oneof=pending retrying succeeded failed"`
+	EventType string `form:"eventType"`
+	Page      int    `form:"page" binding:"omitempty,min=1"`
+	Limit     int    `form:"limit" binding:"omitempty,min=1,max=100"`
+}
+
+// ===========================================
+// RESPONSE DTOs
+// ===========================================
+
+/* WebhookResponse represents the response structure for a webhook endpoint */
+type WebhookResponse struct {
+	ID                  uint       `json:"id"`
+	URL                 string     `json:"url"`
+	Description         string     `json:"description"`
+	Events              []string   `json:"events"`
+	Secret              string     `json:"secret,omitempty"` // only returned when created
+	IsActive            bool       `json:"isActive"`
+	ConsecutiveFailures int        `json:"consecutiveFailures"`
+	LastSuccessAt       *time.Time `json:"lastSuccessAt,omitempty"`
+	DisabledAt          *time.Time `json:"disabledAt,omitempty"`
+	DisabledReason      string     `json:"disabledReason,omitempty"`
+	CreatedAt           time.Time  `json:"createdAt"`
+	UpdatedAt           time.Time  `json:"updatedAt"`
+}
+
+/* WebhookDeliveryResponse represents one entry of a webhook's delivery log */
+type WebhookDeliveryResponse struct {
+	ID             uint64          `json:"id"`
+	EndpointID     uint            `json:"endpointId"`
+	EventID        string          `json:"eventId"`
+	EventType      string          `json:"eventType"`
+	Status         string          `json:"status"`
+	Attempts       int             `json:"attempts"`
+	NextAttemptAt  time.Time       `json:"nextAttemptAt"`
+	ResponseStatus int             `json:"responseStatus,omitempty"`
+	ResponseBody   string          `json:"responseBody,omitempty"`
+	LastError      string          `json:"lastError,omitempty"`
+	DurationMs     int64           `json:"durationMs"`
+	DeliveredAt    *time.Time      `json:"deliveredAt,omitempty"`
+	CreatedAt      time.Time       `json:"createdAt"`
+	UpdatedAt      time.Time       `json:"updatedAt"`
+	Payload        json.RawMessage `json:"payload,omitempty"`
+}
+
+/* WebhookDeliveryListResponse represents a page of a webhook's delivery log */
+type WebhookDeliveryListResponse struct {
+	Deliveries []WebhookDeliveryResponse `json:"deliveries"`
+	Pagination PaginationMeta            `json:"pagination"`
+}
+
+// ===========================================
+// VALIDATION HELPERS
+// ===========================================
+
+/* SetDefaults sets default values for WebhookDeliverySearchRequest */
+func (r *WebhookDeliverySearchRequest) SetDefaults() {
+	if r.Page <= 0 {
+		r.Page = 1
+	}
+	if r.Limit <= 0 {
+		r.Limit = 20
+	}
+	if r.Limit > 100 {
+		r.Limit = 100
+	}
+}
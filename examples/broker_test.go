@@ -0,0 +1,355 @@
+package examples
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"baseApi/config"
+	"baseApi/logger"
+	"baseApi/messaging"
+	"baseApi/routes"
+)
+
+/* TestMain initializes the logger the brokers log through */
+func TestMain(m *testing.M) {
+	logger.InitLogger()
+	os.Exit(m.Run())
+}
+
+/* newTestBroker returns an in-memory broker that is closed when the test ends */
+func newTestBroker(t *testing.T) *messaging.MemoryBroker {
+	t.Helper()
+	broker := messaging.NewMemoryBroker()
+	t.Cleanup(func() { broker.Close() })
+	return broker
+}
+
+/* startConsumer subscribes a consumer to the broker and drains it when the test ends */
+func startConsumer(t *testing.T, broker messaging.Broker, consumer *messaging.Consumer) {
+	t.Helper()
+	if err := consumer.StartOn(context.Background(), broker); err != nil {
+		t.Fatalf("failed to start consumer: %v", err)
+	}
+	t.Cleanup(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		consumer.Shutdown(ctx)
+	})
+}
+
+/* publishJSON publishes data as a JSON message */
+func publishJSON(t *testing.T, broker messaging.Publisher, routingKey string, data interface{}) {
+	t.Helper()
+	body, err := json.Marshal(data)
+	if err != nil {
+		t.Fatalf("failed to marshal %s: %v", routingKey, err)
+	}
+	msg := &messaging.Message{RoutingKey: routingKey, ContentType: "application/json", Body: body}
+	if err := broker.Publish(context.Background(), msg); err != nil {
+		t.Fatalf("failed to publish %s: %v", routingKey, err)
+	}
+}
+
+/* receive waits for one message on ch */
+func receive(t *testing.T, ch <-chan *messaging.Message) *messaging.Message {
+	t.Helper()
+	select {
+	case msg := <-ch:
+		return msg
+	case <-time.After(2 * time.Second):
+		t.Fatal("timed out waiting for a message")
+		return nil
+	}
+}
+
+/* TestUserEventIsDeliveredAsCloudEvent publishes a user event and decodes it on the consumer side */
+func TestUserEventIsDeliveredAsCloudEvent(t *testing.T) {
+	broker := newTestBroker(t)
+
+	received := make(chan *messaging.Message, 1)
+	consumer := messaging.NewConsumer(messaging.ConsumerConfig{Queue: "user-projector"}).
+		Handle("user.*", func(ctx context.Context, msg *messaging.Message) error {
+			received <- msg
+			return nil
+		})
+	startConsumer(t, broker, consumer)
+
+	event, err := messaging.NewUserEvent("deleted", 123, map[string]interface{}{"id": 123})
+	if err != nil {
+		t.Fatalf("failed to build user event: %v", err)
+	}
+	body, _ := json.Marshal(event)
+	err = broker.Publish(context.Background(), &messaging.Message{
+		ID:          event.ID,
+		RoutingKey:  messaging.UserEventRoutingKey("deleted"),
+		ContentType: messaging.CloudEventsContentType,
+		Body:        body,
+	})
+	if err != nil {
+		t.Fatalf("failed to publish user event: %v", err)
+	}
+
+	msg := receive(t, received)
+	if msg.ID != event.ID || msg.RoutingKey != "user.deleted" {
+		t.Errorf("got message %s with key %s, want %s with key user.deleted", msg.ID, msg.RoutingKey, event.ID)
+	}
+	var decoded messaging.CloudEvent
+	if err := json.Unmarshal(msg.Body, &decoded); err != nil {
+		t.Fatalf("body is not a CloudEvent: %v", err)
+	}
+	if decoded.Type != "com.baseapi.user.deleted" || decoded.Subject != "users/123" {
+		t.Errorf("got type %q subject %q", decoded.Type, decoded.Subject)
+	}
+}
+
+/* TestUserEventRejectsInvalidData checks events are validated against their schema before publishing */
+func TestUserEventRejectsInvalidData(t *testing.T) {
+	_, err := messaging.NewUserEvent("deleted", 123, map[string]interface{}{"id": "not-a-number"})
+	if err == nil {
+		t.Fatal("expected a schema validation error")
+	}
+}
+
+/* TestTopicRouting checks each queue receives exactly the routing keys its patterns match */
+func TestTopicRouting(t *testing.T) {
+	broker := newTestBroker(t)
+
+	var mu sync.Mutex
+	got := map[string][]string{}
+	record := func(queue string) messaging.HandlerFunc {
+		return func(ctx context.Context, msg *messaging.Message) error {
+			mu.Lock()
+			defer mu.Unlock()
+			got[queue] = append(got[queue], msg.RoutingKey)
+			return nil
+		}
+	}
+
+	startConsumer(t, broker, messaging.NewConsumer(messaging.ConsumerConfig{Queue: "orders", Workers: 1}).
+		Handle("orders.#", record("orders")))
+	startConsumer(t, broker, messaging.NewConsumer(messaging.ConsumerConfig{Queue: "batch-items", Workers: 1}).
+		Handle("batch.item.*", record("batch-items")))
+	startConsumer(t, broker, messaging.NewConsumer(messaging.ConsumerConfig{Queue: "audit", Workers: 1}).
+		Handle("#", record("audit")))
+
+	keys := []string{"orders.payment.processed", "batch.item.1", "batch.item.2", "system.health_check"}
+	for _, key := range keys {
+		publishJSON(t, broker, key, map[string]interface{}{"key": key})
+	}
+
+	want := map[string][]string{
+		"orders":      {"orders.payment.processed"},
+		"batch-items": {"batch.item.1", "batch.item.2"},
+		"audit":       keys,
+	}
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		mu.Lock()
+		done := fmt.Sprint(got) == fmt.Sprint(want)
+		snapshot := fmt.Sprint(got)
+		mu.Unlock()
+		if done {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("got %s, want %s", snapshot, fmt.Sprint(want))
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+/* TestUnroutableMessage checks a message no queue is bound for is reported, like a RabbitMQ mandatory return */
+func TestUnroutableMessage(t *testing.T) {
+	broker := newTestBroker(t)
+
+	err := broker.Publish(context.Background(), &messaging.Message{RoutingKey: "nobody.listens"})
+	if !errors.Is(err, messaging.ErrUnroutable) {
+		t.Fatalf("got %v, want ErrUnroutable", err)
+	}
+}
+
+/* TestRetryThenDeadLetter checks transient failures are retried, then dead-lettered and replayed to their queue only */
+func TestRetryThenDeadLetter(t *testing.T) {
+	broker := newTestBroker(t)
+
+	var mu sync.Mutex
+	attempts := []int{}
+	fail := true
+	delivered := make(chan *messaging.Message, 10)
+	consumer := messaging.NewConsumer(messaging.ConsumerConfig{
+		Queue: "notifications",
+		Retry: &messaging.RetryPolicy{MaxRetries: 2, InitialDelay: 10 * time.Millisecond, MaxDelay: 20 * time.Millisecond},
+	}).Handle("notification.*", func(ctx context.Context, msg *messaging.Message) error {
+		mu.Lock()
+		defer mu.Unlock()
+		attempts = append(attempts, msg.Attempt())
+		if fail {
+			return messaging.Requeue(errors.New("smtp unavailable"))
+		}
+		delivered <- msg
+		return nil
+	})
+	startConsumer(t, broker, consumer)
+
+	// Another queue bound to the same key must not get the replayed message again
+	audited := make(chan *messaging.Message, 10)
+	audit := messaging.NewConsumer(messaging.ConsumerConfig{Queue: "audit"}).
+		Handle("notification.#", func(ctx context.Context, msg *messaging.Message) error {
+			audited <- msg
+			return nil
+		})
+	startConsumer(t, broker, audit)
+
+	publishJSON(t, broker, "notification.email", map[string]string{"recipient": "user@example.com"})
+	receive(t, audited)
+
+	var info *messaging.DeadLetterQueueInfo
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		var err error
+		info, err = broker.InspectDeadLetters("notifications", 10)
+		if err != nil {
+			t.Fatalf("failed to inspect dead letters: %v", err)
+		}
+		if info.Count == 1 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("message was not dead-lettered")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	mu.Lock()
+	if fmt.Sprint(attempts) != "[0 1 2]" {
+		t.Errorf("got attempts %v, want [0 1 2]", attempts)
+	}
+	fail = false
+	mu.Unlock()
+
+	letter := info.Messages[0]
+	if letter.RoutingKey != "notification.email" || letter.Reason != "retries_exhausted" || letter.LastError != "smtp unavailable" {
+		t.Errorf("unexpected dead letter %+v", letter)
+	}
+
+	replayed, err := broker.ReplayDeadLetters("notifications", 10)
+	if err != nil || replayed != 1 {
+		t.Fatalf("replayed %d (%v), want 1", replayed, err)
+	}
+	if msg := receive(t, delivered); msg.Attempt() != 0 || msg.RoutingKey != "notification.email" {
+		t.Errorf("replayed message has attempt %d and key %s, want a fresh count and the original key", msg.Attempt(), msg.RoutingKey)
+	}
+	select {
+	case <-audited:
+		t.Error("replay reached the audit queue")
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+/* TestReplayOnlyReachesItsQueue checks replaying one queue's dead letters leaves other queues on the same key alone */
+func TestReplayOnlyReachesItsQueue(t *testing.T) {
+	broker := newTestBroker(t)
+
+	// Both queues reject the first delivery, so each dead-letters its own copy
+	received := map[string]chan *messaging.Message{}
+	for _, queue := range []string{"billing", "emails"} {
+		queue := queue
+		received[queue] = make(chan *messaging.Message, 10)
+		var calls int32
+		consumer := messaging.NewConsumer(messaging.ConsumerConfig{Queue: queue, Retry: &messaging.RetryPolicy{MaxRetries: 1}}).
+			Handle("order.*", func(ctx context.Context, msg *messaging.Message) error {
+				if atomic.AddInt32(&calls, 1) == 1 {
+					return errors.New("rejected")
+				}
+				received[queue] <- msg
+				return nil
+			})
+		startConsumer(t, broker, consumer)
+	}
+
+	publishJSON(t, broker, "order.placed", map[string]int{"id": 1})
+	for _, queue := range []string{"billing", "emails"} {
+		waitForDeadLetters(t, broker, queue, 1)
+	}
+
+	if _, err := broker.ReplayDeadLetters("unknown", 10); !errors.Is(err, messaging.ErrQueueNotFound) {
+		t.Errorf("unknown queue: got %v, want ErrQueueNotFound", err)
+	}
+	if replayed, err := broker.ReplayDeadLetters("billing", 10); err != nil || replayed != 1 {
+		t.Fatalf("replayed %d (%v), want 1", replayed, err)
+	}
+
+	if msg := receive(t, received["billing"]); msg.RoutingKey != "order.placed" {
+		t.Errorf("billing got key %s, want order.placed", msg.RoutingKey)
+	}
+	select {
+	case <-received["emails"]:
+		t.Error("replaying billing reached the emails queue")
+	case <-time.After(50 * time.Millisecond):
+	}
+	waitForDeadLetters(t, broker, "billing", 0)
+	waitForDeadLetters(t, broker, "emails", 1)
+}
+
+/* waitForDeadLetters waits until a queue holds count dead letters */
+func waitForDeadLetters(t *testing.T, broker messaging.DeadLetterAdmin, queue string, count int) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		info, err := broker.InspectDeadLetters(queue, 0)
+		if err != nil {
+			t.Fatalf("failed to inspect %s dead letters: %v", queue, err)
+		}
+		if info.Count == count {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("%s has %d dead letters, want %d", queue, info.Count, count)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+/* TestRejectedMessageWithoutRetryIsDropped checks plain errors are not redelivered */
+func TestRejectedMessageWithoutRetryIsDropped(t *testing.T) {
+	broker := newTestBroker(t)
+
+	calls := make(chan struct{}, 10)
+	consumer := messaging.NewConsumer(messaging.ConsumerConfig{Queue: "reports"}).
+		Handle("report.#", func(ctx context.Context, msg *messaging.Message) error {
+			calls <- struct{}{}
+			return errors.New("malformed report")
+		})
+	startConsumer(t, broker, consumer)
+
+	publishJSON(t, broker, "report.daily", map[string]string{"day": "monday"})
+
+	<-calls
+	select {
+	case <-calls:
+		t.Fatal("rejected message was redelivered")
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+/* TestPublisherStatsRequireAdminToken checks /debug/vars is only served to the admin token */
+func TestPublisherStatsRequireAdminToken(t *testing.T) {
+	router := routes.SetupRoutes(&config.Config{AdminToken: testAdminToken})
+
+	if status, _ := serveJSON(t, router, http.MethodGet, "/debug/vars", "", nil); status != http.StatusUnauthorized {
+		t.Errorf("without a token: got %d, want 401", status)
+	}
+	header := http.Header{"Authorization": {"Bearer " + testAdminToken}}
+	status, vars := serveJSON(t, router, http.MethodGet, "/debug/vars", "", header)
+	if _, ok := vars["rabbitmq_publisher"]; status != http.StatusOK || !ok {
+		t.Errorf("with the admin token: got %d and %v, want 200 with rabbitmq_publisher", status, vars)
+	}
+}
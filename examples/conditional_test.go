@@ -0,0 +1,175 @@
+package examples
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"baseApi/config"
+	"baseApi/dto"
+	"baseApi/routes"
+)
+
+/* getUser sends GET /v1/users/7 with the given headers, the user being looked up in the database */
+func getUser(t *testing.T, path string, header http.Header) *httptest.ResponseRecorder {
+	t.Helper()
+	mock := useTestDB(t)
+	useTestRedis(t)
+	router := routes.SetupRoutes(&config.Config{UserCacheControl: "private, no-cache"})
+	expectUserSelect(mock, newTestUser(3))
+	return sendRequest(router, http.MethodGet, path, header)
+}
+
+/* TestUserETagPerLocale checks each locale gets its own tag and caches are told what the locale depends on */
+func TestUserETagPerLocale(t *testing.T) {
+	tests := []struct {
+		name   string
+		path   string
+		header http.Header
+		want   string
+	}{
+		{"default locale", "/v1/users/7", nil, `"3-en"`},
+		{"Accept-Language", "/v1/users/7", http.Header{"Accept-Language": {"vi-VN,vi;q=0.9"}}, `"3-vi"`},
+		{"lang query", "/v1/users/7?lang=vi", nil, `"3-vi"`},
+		{"locale cookie", "/v1/users/7", http.Header{"Cookie": {"locale=vi"}}, `"3-vi"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := getUser(t, tt.path, tt.header)
+			if rec.Code != http.StatusOK {
+				t.Fatalf("got status %d (%s), want 200", rec.Code, rec.Body.String())
+			}
+			if etag := rec.Header().Get("ETag"); etag != tt.want {
+				t.Errorf("got ETag %s, want %s", etag, tt.want)
+			}
+			if vary := rec.Header().Get("Vary"); vary != "Accept, Accept-Language, Cookie" {
+				t.Errorf("got Vary %q, want Accept, Accept-Language, Cookie", vary)
+			}
+			if got := rec.Header().Get("Last-Modified"); got != "Fri, 02 Jan 2026 03:04:05 GMT" {
+				t.Errorf("got Last-Modified %q", got)
+			}
+			if got := rec.Header().Get("Cache-Control"); got != "private, no-cache" {
+				t.Errorf("got Cache-Control %q, want the route policy", got)
+			}
+		})
+	}
+}
+
+/* TestUserConditionalGet checks If-None-Match and If-Modified-Since against the stored user */
+func TestUserConditionalGet(t *testing.T) {
+	tests := []struct {
+		name   string
+		header http.Header
+		want   int
+	}{
+		{"matching tag", http.Header{"If-None-Match": {`"3-en"`}}, http.StatusNotModified},
+		{"weak comparison", http.Header{"If-None-Match": {`W/"3-en"`}}, http.StatusNotModified},
+		{"one of several tags", http.Header{"If-None-Match": {`"2-en", "3-en"`}}, http.StatusNotModified},
+		{"wildcard", http.Header{"If-None-Match": {`*`}}, http.StatusNotModified},
+		{"stale tag", http.Header{"If-None-Match": {`"2-en"`}}, http.StatusOK},
+		{"tag of another locale", http.Header{"If-None-Match": {`"3-vi"`}}, http.StatusOK},
+		{"not modified since", http.Header{"If-Modified-Since": {"Fri, 02 Jan 2026 03:04:05 GMT"}}, http.StatusNotModified},
+		{"modified since", http.Header{"If-Modified-Since": {"Fri, 02 Jan 2026 03:04:04 GMT"}}, http.StatusOK},
+		{"If-None-Match takes precedence", http.Header{
+			"If-None-Match":     {`"2-en"`},
+			"If-Modified-Since": {"Fri, 02 Jan 2026 03:04:05 GMT"},
+		}, http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := getUser(t, "/v1/users/7", tt.header)
+			if rec.Code != tt.want {
+				t.Fatalf("got status %d (%s), want %d", rec.Code, rec.Body.String(), tt.want)
+			}
+			if rec.Code == http.StatusNotModified {
+				if rec.Body.Len() != 0 {
+					t.Errorf("got body %q on 304", rec.Body.String())
+				}
+				if etag := rec.Header().Get("ETag"); etag != `"3-en"` {
+					t.Errorf("got ETag %s on 304, want \"3-en\"", etag)
+				}
+			}
+		})
+	}
+}
+
+/* TestUserListETag checks the list is validated by a weak content-hash tag */
+func TestUserListETag(t *testing.T) {
+	mock := useTestDB(t)
+	router := routes.SetupRoutes(&config.Config{})
+
+	list := func(header http.Header) *httptest.ResponseRecorder {
+		return sendRequest(router, http.MethodGet, "/v1/users", header)
+	}
+
+	expectUserList(mock, newTestUser(3))
+	first := list(nil)
+	etag := first.Header().Get("ETag")
+	if first.Code != http.StatusOK || !strings.HasPrefix(etag, `W/"`) {
+		t.Fatalf("got status %d and ETag %q, want 200 and a weak tag", first.Code, etag)
+	}
+	if first.Header().Get("Last-Modified") != "" {
+		t.Errorf("got Last-Modified %q, want none for the list", first.Header().Get("Last-Modified"))
+	}
+
+	expectUserList(mock, newTestUser(3))
+	if rec := list(http.Header{"If-None-Match": {etag}}); rec.Code != http.StatusNotModified {
+		t.Errorf("unchanged list: got status %d, want 304", rec.Code)
+	}
+
+	expectUserList(mock, newTestUser(4))
+	if rec := list(http.Header{"If-None-Match": {etag}}); rec.Code != http.StatusOK || rec.Header().Get("ETag") == etag {
+		t.Errorf("changed list: got status %d and ETag %s, want 200 and a new tag", rec.Code, rec.Header().Get("ETag"))
+	}
+
+	expectUserList(mock, newTestUser(3))
+	if rec := list(http.Header{"If-None-Match": {etag}, "Accept-Language": {"vi"}}); rec.Code != http.StatusOK {
+		t.Errorf("other locale: got status %d, want 200", rec.Code)
+	}
+}
+
+/* TestRequireIfMatch checks writes without If-Match get 428 when REQUIRE_IF_MATCH is set */
+func TestRequireIfMatch(t *testing.T) {
+	router := routes.SetupRoutes(&config.Config{RequireIfMatch: true})
+
+	for _, method := range []string{http.MethodPut, http.MethodPatch, http.MethodDelete} {
+		req := httptest.NewRequest(method, "/v1/users/7", strings.NewReader(`{"firstName":"Johnny"}`))
+		req.Header.Set("Content-Type", dto.ContentTypeJSON)
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+
+		if rec.Code != http.StatusPreconditionRequired {
+			t.Errorf("%s: got status %d, want 428", method, rec.Code)
+		}
+		if !strings.Contains(rec.Body.String(), `"code":"`+dto.ErrorCodePreconditionRequired+`"`) {
+			t.Errorf("%s: got %s, want error code %s", method, rec.Body.String(), dto.ErrorCodePreconditionRequired)
+		}
+	}
+
+	// Reads and conditional writes pass through
+	mock := useTestDB(t)
+	useTestRedis(t)
+	expectUserSelect(mock, newTestUser(3))
+	expectUserSelect(mock, newTestUser(3))
+
+	if rec := sendRequest(router, http.MethodGet, "/v1/users/7", nil); rec.Code != http.StatusOK {
+		t.Errorf("GET: got status %d, want 200", rec.Code)
+	}
+	if rec := sendRequest(router, http.MethodDelete, "/v1/users/7", http.Header{"If-Match": {`"2-en"`}}); rec.Code != http.StatusPreconditionFailed {
+		t.Errorf("DELETE with a stale If-Match: got status %d, want 412", rec.Code)
+	}
+}
+
+/* sendRequest sends a request without a body to router */
+func sendRequest(router http.Handler, method, path string, header http.Header) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, nil)
+	for key, values := range header {
+		req.Header[key] = values
+	}
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	return rec
+}
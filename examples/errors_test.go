@@ -0,0 +1,212 @@
+package examples
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"baseApi/apperror"
+	"baseApi/dto"
+	"baseApi/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+/* newErrorRouter serves GET /fail through the middleware that renders errors, running handle */
+func newErrorRouter(handle gin.HandlerFunc) *gin.Engine {
+	router := gin.New()
+	router.Use(middleware.RequestIDMiddleware())
+	router.Use(middleware.LocaleMiddleware())
+	router.Use(middleware.ErrorHandler())
+	router.GET("/fail", handle)
+	return router
+}
+
+/* TestErrorHandlerStatus checks each kind of application error is rendered with its status and code */
+func TestErrorHandlerStatus(t *testing.T) {
+	tests := []struct {
+		err        error
+		wantStatus int
+		wantCode   string
+	}{
+		{apperror.BadRequest("bad"), http.StatusBadRequest, dto.ErrorCodeBadRequest},
+		{apperror.InvalidFormat("bad"), http.StatusBadRequest, dto.ErrorCodeInvalidFormat},
+		{apperror.Validation([]dto.ValidationError{{Field: "email", Rule: "email"}}), http.StatusBadRequest, dto.ErrorCodeValidation},
+		{apperror.Unauthorized("who"), http.StatusUnauthorized, dto.ErrorCodeUnauthorized},
+		{apperror.Forbidden("no"), http.StatusForbidden, dto.ErrorCodeForbidden},
+		{apperror.NotFound("User"), http.StatusNotFound, dto.ErrorCodeNotFound},
+		{apperror.AlreadyExists("User", "email"), http.StatusConflict, dto.ErrorCodeAlreadyExists},
+		{apperror.Conflict("busy"), http.StatusConflict, dto.ErrorCodeConflict},
+		{apperror.PreconditionFailed("stale"), http.StatusPreconditionFailed, dto.ErrorCodeConflict},
+		{apperror.PreconditionRequired("If-Match"), http.StatusPreconditionRequired, dto.ErrorCodePreconditionRequired},
+		{apperror.UnsupportedMediaType("type"), http.StatusUnsupportedMediaType, dto.ErrorCodeUnsupportedMedia},
+		{apperror.Unprocessable([]dto.ValidationError{{Field: "email", Rule: "check"}}), http.StatusUnprocessableEntity, dto.ErrorCodeValidation},
+		{apperror.Internal(dto.ErrorCodeDatabaseError, "Failed", nil), http.StatusInternalServerError, dto.ErrorCodeDatabaseError},
+		{errors.New("plain error"), http.StatusInternalServerError, dto.ErrorCodeInternalServer},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.wantCode+" "+http.StatusText(tt.wantStatus), func(t *testing.T) {
+			router := newErrorRouter(func(c *gin.Context) { c.Error(tt.err) })
+			status, body := serveJSON(t, router, http.MethodGet, "/fail", "", nil)
+
+			if status != tt.wantStatus || body["statusCode"] != float64(tt.wantStatus) {
+				t.Errorf("got status %d (statusCode %v), want %d", status, body["statusCode"], tt.wantStatus)
+			}
+			errorInfo := body["error"].(map[string]interface{})
+			if errorInfo["code"] != tt.wantCode {
+				t.Errorf("got code %v, want %s", errorInfo["code"], tt.wantCode)
+			}
+			if body["success"] != false || errorInfo["requestId"] == "" {
+				t.Errorf("got %v, want a failed response with the request ID", body)
+			}
+		})
+	}
+}
+
+/* TestErrorHandlerRendersLastError checks only the error that ended the request is rendered */
+func TestErrorHandlerRendersLastError(t *testing.T) {
+	router := newErrorRouter(func(c *gin.Context) {
+		c.Error(apperror.BadRequest("first"))
+		c.Error(apperror.NotFound("User"))
+	})
+
+	status, body := serveJSON(t, router, http.MethodGet, "/fail", "", nil)
+	if status != http.StatusNotFound || body["message"] != "User not found" {
+		t.Errorf("got %d %v, want the last error (404 User not found)", status, body["message"])
+	}
+}
+
+/* TestErrorHandlerHidesCauses checks internal causes are logged, never sent */
+func TestErrorHandlerHidesCauses(t *testing.T) {
+	cause := errors.New(`pq: password authentication failed for user "postgres"`)
+	tests := []struct {
+		name string
+		err  error
+	}{
+		{"internal error", apperror.Internal(dto.ErrorCodeDatabaseError, "Failed to retrieve user", cause)},
+		{"plain error", cause},
+		{"client error with a cause", apperror.Conflict("busy").WithCause(cause)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := newErrorRouter(func(c *gin.Context) { c.Error(tt.err) })
+			for _, accept := range []string{"", dto.ContentTypeProblem} {
+				req := httptest.NewRequest(http.MethodGet, "/fail", nil)
+				req.Header.Set("Accept", accept)
+				rec := httptest.NewRecorder()
+				router.ServeHTTP(rec, req)
+
+				if strings.Contains(rec.Body.String(), "password") || strings.Contains(rec.Body.String(), "postgres") {
+					t.Errorf("Accept %q: response %s exposes the cause", accept, rec.Body.String())
+				}
+			}
+		})
+	}
+}
+
+/* TestErrorHandlerKeepsWrittenResponses checks an error attached after the response was written does not replace it */
+func TestErrorHandlerKeepsWrittenResponses(t *testing.T) {
+	router := newErrorRouter(func(c *gin.Context) {
+		c.JSON(http.StatusAccepted, gin.H{"queued": true})
+		c.Error(errors.New("audit log unavailable"))
+	})
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/fail", nil))
+
+	var body map[string]interface{}
+	json.Unmarshal(rec.Body.Bytes(), &body)
+	if rec.Code != http.StatusAccepted || body["queued"] != true || body["error"] != nil {
+		t.Errorf("got %d %s, want the handler's 202", rec.Code, rec.Body.String())
+	}
+}
+
+/* TestProblemJSONNegotiation checks which Accept headers get a problem document, q-values included */
+func TestProblemJSONNegotiation(t *testing.T) {
+	tests := []struct {
+		accept string
+		want   bool
+	}{
+		{"", false},
+		{"*/*", false},
+		{"application/json", false},
+		{"application/*", false},
+		{"text/html", false},
+		{"application/problem+json", true},
+		{"application/problem+json, application/json", false},
+		{"application/json;q=0.1, application/problem+json", true},
+		{"application/problem+json;q=0.5, application/json", false},
+		{"application/problem+json, */*;q=0.1", true},
+		{"application/problem+json;q=0, */*", false},
+		{"application/problem+json; charset=utf-8", true},
+		{"Application/Problem+JSON", true},
+		{"application/problem+json;q=0.8, application/*;q=0.9", false},
+		{"application/problem+json;q=0.9, application/*;q=0.8", true},
+	}
+
+	router := newErrorRouter(func(c *gin.Context) { c.Error(apperror.NotFound("User")) })
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodGet, "/fail", nil)
+		if tt.accept != "" {
+			req.Header.Set("Accept", tt.accept)
+		}
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+
+		got := strings.HasPrefix(rec.Header().Get("Content-Type"), dto.ContentTypeProblem)
+		if got != tt.want {
+			t.Errorf("Accept %q: got Content-Type %q, want problem+json %v", tt.accept, rec.Header().Get("Content-Type"), tt.want)
+		}
+	}
+}
+
+/* TestProblemDocument checks the members of a rendered problem document */
+func TestProblemDocument(t *testing.T) {
+	previous := dto.ProblemTypeBaseURI
+	dto.ProblemTypeBaseURI = "https://errors.example.test/"
+	defer func() { dto.ProblemTypeBaseURI = previous }()
+
+	router := newErrorRouter(func(c *gin.Context) {
+		c.Error(apperror.Validation([]dto.ValidationError{{Field: "email", Rule: "email", Message: "email must be a valid email address"}}))
+	})
+	header := http.Header{"Accept": {dto.ContentTypeProblem}}
+	header.Set(middleware.RequestIDHeader, "req-123")
+	status, problem := serveJSON(t, router, http.MethodGet, "/fail", "", header)
+
+	want := map[string]interface{}{
+		"type":      "https://errors.example.test/validation-error",
+		"status":    float64(http.StatusBadRequest),
+		"code":      dto.ErrorCodeValidation,
+		"instance":  "urn:request-id:req-123",
+		"requestId": "req-123",
+	}
+	if status != http.StatusBadRequest {
+		t.Errorf("got status %d, want 400", status)
+	}
+	for member, value := range want {
+		if problem[member] != value {
+			t.Errorf("got %s %v, want %v", member, problem[member], value)
+		}
+	}
+	if problem["title"] == "" || problem["detail"] == "" || problem["timestamp"] == "" {
+		t.Errorf("got %v, want title, detail and timestamp", problem)
+	}
+	validations, _ := problem["validations"].([]interface{})
+	if len(validations) != 1 || validations[0].(map[string]interface{})["field"] != "email" {
+		t.Errorf("got validations %v, want the email error", problem["validations"])
+	}
+
+	// Titles are localized, type URIs are not
+	_, localized := serveJSON(t, router, http.MethodGet, "/fail", "", http.Header{
+		"Accept":          {dto.ContentTypeProblem},
+		"Accept-Language": {"vi"},
+	})
+	if localized["type"] != want["type"] || localized["title"] == problem["title"] {
+		t.Errorf("vi: got type %v and title %v, want the same type and a translated title", localized["type"], localized["title"])
+	}
+}
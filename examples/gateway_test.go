@@ -0,0 +1,135 @@
+package examples
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"baseApi/config"
+	usergrpc "baseApi/grpc"
+	grpcv2 "baseApi/grpc/v2"
+	"baseApi/routes"
+
+	"github.com/gin-gonic/gin"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+)
+
+/* newGatewayRouter starts a gRPC server on a free port and returns routes serving it through the gateway */
+func newGatewayRouter(t *testing.T, apiResponse bool) *gin.Engine {
+	t.Helper()
+	listener, err := net.Listen("tcp", "localhost:0")
+	if err != nil {
+		t.Fatalf("failed to find a free port: %v", err)
+	}
+	_, port, _ := net.SplitHostPort(listener.Addr().String())
+	listener.Close()
+
+	cfg := &config.Config{GRPCPort: port, GRPCGatewayEnabled: true, GRPCGatewayAPIResponse: apiResponse}
+	server, err := usergrpc.StartGRPCServer(cfg)
+	if err != nil {
+		t.Fatalf("failed to start gRPC server: %v", err)
+	}
+	t.Cleanup(server.Stop)
+	return routes.SetupRoutes(cfg)
+}
+
+/* serveJSON sends a JSON request to router and decodes the response body */
+func serveJSON(t *testing.T, router http.Handler, method, path, body string, header http.Header) (int, map[string]interface{}) {
+	t.Helper()
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	for key, values := range header {
+		req.Header[key] = values
+	}
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+
+	var decoded map[string]interface{}
+	if err := json.Unmarshal(rec.Body.Bytes(), &decoded); err != nil {
+		t.Fatalf("%s %s: invalid JSON %q: %v", method, path, rec.Body.String(), err)
+	}
+	return rec.Code, decoded
+}
+
+/* TestGatewayREST checks /v2 JSON routes reach the gRPC server and report google.rpc.Status errors */
+func TestGatewayREST(t *testing.T) {
+	router := newGatewayRouter(t, false)
+
+	code, body := serveJSON(t, router, http.MethodGet, "/v2/users/0", "", nil)
+	if code != http.StatusBadRequest || body["code"] != float64(codes.InvalidArgument) || body["message"] != "Invalid user ID format" {
+		t.Errorf("get: got %d %v, want 400 with an InvalidArgument status", code, body)
+	}
+
+	// The negotiated locale reaches the gRPC server as accept-language
+	_, body = serveJSON(t, router, http.MethodGet, "/v2/users/0?lang=vi", "", nil)
+	if body["message"] == "Invalid user ID format" {
+		t.Errorf("get: got %q, want a Vietnamese message", body["message"])
+	}
+
+	// The request ID reaches the gRPC server and comes back in a RequestInfo detail
+	header := http.Header{"X-Request-Id": {"rest-test"}}
+	code, body = serveJSON(t, router, http.MethodPost, "/v2/users", `{"username":"john_doe","email":"bad"}`, header)
+	details, _ := body["details"].([]interface{})
+	if code != http.StatusBadRequest || len(details) != 3 {
+		t.Fatalf("create: got %d %v, want 400 with ErrorInfo, BadRequest and RequestInfo details", code, body)
+	}
+	if requestInfo := details[2].(map[string]interface{}); requestInfo["requestId"] != "rest-test" {
+		t.Errorf("create: got %v, want request ID rest-test", requestInfo)
+	}
+}
+
+/* TestGatewayAPIResponse checks the option wrapping /v2 errors in the APIResponse envelope of /v1 */
+func TestGatewayAPIResponse(t *testing.T) {
+	router := newGatewayRouter(t, true)
+
+	header := http.Header{"X-Request-Id": {"gateway-test"}}
+	code, body := serveJSON(t, router, http.MethodPost, "/v2/users", `{"username":"john_doe","email":"bad"}`, header)
+	apiError, _ := body["error"].(map[string]interface{})
+	validations, _ := apiError["validations"].([]interface{})
+	if code != http.StatusBadRequest || body["success"] != false || apiError["code"] != "VALIDATION_ERROR" || len(validations) != 2 {
+		t.Fatalf("create: got %d %v, want a VALIDATION_ERROR APIResponse for email and password", code, body)
+	}
+	if field := validations[0].(map[string]interface{})["field"]; field != "email" || apiError["requestId"] != "gateway-test" {
+		t.Errorf("create: got field %v and request ID %v", field, apiError["requestId"])
+	}
+
+	code, body = serveJSON(t, router, http.MethodGet, "/v2/unknown", "", nil)
+	if apiError, _ = body["error"].(map[string]interface{}); code != http.StatusNotFound || apiError["code"] != "NOT_FOUND" {
+		t.Errorf("unknown route: got %d %v, want a NOT_FOUND APIResponse", code, body)
+	}
+}
+
+/* TestGatewayGRPCWeb checks gRPC-Web and native gRPC calls are served on the HTTP port */
+func TestGatewayGRPCWeb(t *testing.T) {
+	router := newGatewayRouter(t, false)
+
+	// An empty GetUserRequest as a single uncompressed gRPC-Web frame
+	req := httptest.NewRequest(http.MethodPost, "/"+grpcv2.UserService_GetUser_FullMethodName[1:], bytes.NewReader([]byte{0, 0, 0, 0, 0}))
+	req.Header.Set("Content-Type", "application/grpc-web+proto")
+	req.Header.Set("X-Grpc-Web", "1")
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	if grpcStatus := rec.Header().Get("Grpc-Status"); rec.Code != http.StatusOK || (grpcStatus != "3" && !strings.Contains(strings.ToLower(rec.Body.String()), "grpc-status: 3")) {
+		t.Errorf("grpc-web: got %d %v %q, want grpc-status 3", rec.Code, rec.Header(), rec.Body.String())
+	}
+
+	server := httptest.NewServer(router.Handler())
+	t.Cleanup(server.Close)
+	conn, err := grpc.Dial(server.Listener.Addr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatalf("failed to dial: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	_, err = grpcv2.NewUserServiceClient(conn).GetUser(context.Background(), &grpcv2.GetUserRequest{})
+	if status.Code(err) != codes.InvalidArgument {
+		t.Errorf("h2c: got %v, want InvalidArgument", err)
+	}
+}
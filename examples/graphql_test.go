@@ -0,0 +1,239 @@
+package examples
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"baseApi/config"
+	"baseApi/dto"
+	usergraphql "baseApi/graphql"
+	"baseApi/routes"
+	"baseApi/streaming"
+
+	"golang.org/x/net/websocket"
+)
+
+/* newGraphQLConfig enables GraphQL and the user stream with the given limits */
+func newGraphQLConfig(maxDepth, maxComplexity int) *config.Config {
+	return &config.Config{
+		GraphQLEnabled:        true,
+		GraphQLMaxDepth:       maxDepth,
+		GraphQLMaxComplexity:  maxComplexity,
+		AdminToken:            testAdminToken,
+		UserStreamEnabled:     true,
+		UserStreamHeartbeat:   time.Minute,
+		UserStreamReplayLimit: 100,
+	}
+}
+
+/* graphQLErrors returns the codes and messages of a response's errors */
+func graphQLErrors(body map[string]interface{}) (codes, messages []string) {
+	errs, _ := body["errors"].([]interface{})
+	for _, e := range errs {
+		queryErr := e.(map[string]interface{})
+		extensions, _ := queryErr["extensions"].(map[string]interface{})
+		code, _ := extensions["code"].(string)
+		codes = append(codes, code)
+		messages = append(messages, queryErr["message"].(string))
+	}
+	return codes, messages
+}
+
+/* TestGraphQLBatchesUserLookups checks the ID lookups of one request reach the fetcher as one batch */
+func TestGraphQLBatchesUserLookups(t *testing.T) {
+	schema, err := usergraphql.NewSchema(newGraphQLConfig(15, 2000))
+	if err != nil {
+		t.Fatalf("failed to parse schema: %v", err)
+	}
+
+	var mu sync.Mutex
+	var batches [][]uint
+	loader := usergraphql.NewUserLoader(func(ids []uint) (map[uint]*dto.UserResponse, error) {
+		mu.Lock()
+		batches = append(batches, ids)
+		mu.Unlock()
+		users := make(map[uint]*dto.UserResponse)
+		for _, id := range ids {
+			if id != 4 {
+				users[id] = &dto.UserResponse{ID: id, Username: "user" + string(rune('0'+id))}
+			}
+		}
+		return users, nil
+	})
+
+	ctx := usergraphql.WithUserLoader(context.Background(), loader)
+	response := schema.Exec(ctx, usergraphql.Request{
+		Query: `{
+			a: user(id: "1") { username }
+			b: user(id: "2") { username }
+			missing: user(id: "4") { username }
+			list: usersByIds(ids: ["1", "3", "4"]) { id }
+		}`,
+	})
+	if len(response.Errors) > 0 {
+		t.Fatalf("got errors %v", response.Errors)
+	}
+
+	var data struct {
+		A, B, Missing *struct{ Username string }
+		List          []*struct{ ID string }
+	}
+	json.Unmarshal(response.Data, &data)
+	if data.A == nil || data.A.Username != "user1" || data.B == nil || data.B.Username != "user2" || data.Missing != nil {
+		t.Errorf("got %s, want user1, user2 and null", response.Data)
+	}
+	if len(data.List) != 3 || data.List[0].ID != "1" || data.List[1].ID != "3" || data.List[2] != nil {
+		t.Errorf("usersByIds: got %s, want users 1, 3 and null", response.Data)
+	}
+
+	if len(batches) != 1 {
+		t.Fatalf("got %d batches %v, want one", len(batches), batches)
+	}
+	ids := batches[0]
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	if len(ids) != 4 || ids[0] != 1 || ids[3] != 4 {
+		t.Errorf("got batch %v, want each of 1-4 once", ids)
+	}
+}
+
+/* TestGraphQLLimits checks deep and expensive queries are rejected before any resolver runs */
+func TestGraphQLLimits(t *testing.T) {
+	router := routes.SetupRoutes(newGraphQLConfig(3, 300))
+	post := func(query string, variables map[string]interface{}) map[string]interface{} {
+		body, _ := json.Marshal(map[string]interface{}{"query": query, "variables": variables})
+		code, decoded := serveJSON(t, router, http.MethodPost, "/graphql", string(body), http.Header{"Content-Type": {"application/json"}})
+		if code != http.StatusOK {
+			t.Fatalf("%s: got status %d", query, code)
+		}
+		return decoded
+	}
+
+	if _, messages := graphQLErrors(post(`{ __type(name: "User") { fields { name } } }`, nil)); len(messages) != 0 {
+		t.Errorf("depth 3: got errors %v", messages)
+	}
+	_, messages := graphQLErrors(post(`query { ...root } fragment root on Query { __type(name: "User") { name } }`, nil))
+	if len(messages) != 0 {
+		t.Errorf("fragment: got errors %v", messages)
+	}
+	if _, messages := graphQLErrors(post(`{ __schema { types { fields { type { name } } } } }`, nil)); len(messages) == 0 || !strings.Contains(messages[0], "exceeds max depth 3") {
+		t.Errorf("depth 5: got %v, want a max depth error", messages)
+	}
+
+	// 1 + 100 users x (users + id + pagination + totalItems)
+	codes, messages := graphQLErrors(post(`query($limit: Int) { users(limit: $limit) { users { id } pagination { totalItems } } }`, map[string]interface{}{"limit": 100}))
+	if len(codes) != 1 || codes[0] != "BAD_REQUEST" || !strings.Contains(messages[0], "cost 401 exceeds the limit of 300") {
+		t.Errorf("complexity: got %v %v, want cost 401 over 300", codes, messages)
+	}
+
+	// Fragments spread twice per level are estimated without expanding them
+	_, messages = graphQLErrors(post(`{ users { ...a ...a } } fragment a on UserPage { ...b ...b } fragment b on UserPage { ...c ...c } fragment c on UserPage { ...d ...d } fragment d on UserPage { users { id } }`, nil))
+	if len(messages) != 1 || !strings.Contains(messages[0], "cost 321") {
+		t.Errorf("fragments: got %v, want cost 321", messages)
+	}
+}
+
+/* TestGraphQLErrors checks resolver errors carry the API error codes, validations and locale */
+func TestGraphQLErrors(t *testing.T) {
+	router := routes.SetupRoutes(newGraphQLConfig(15, 2000))
+	header := http.Header{"Content-Type": {"application/json"}, "X-Request-Id": {"graphql-test"}}
+
+	_, body := serveJSON(t, router, http.MethodPost, "/graphql", `{"query": "{ users(limit: 500) { pagination { totalItems } } }"}`, header)
+	errs, _ := body["errors"].([]interface{})
+	if len(errs) != 1 {
+		t.Fatalf("got %v, want one error", body)
+	}
+	extensions := errs[0].(map[string]interface{})["extensions"].(map[string]interface{})
+	validations, _ := extensions["validations"].([]interface{})
+	if extensions["code"] != "VALIDATION_ERROR" || extensions["requestId"] != "graphql-test" || len(validations) != 1 || validations[0].(map[string]interface{})["field"] != "limit" {
+		t.Errorf("limit: got %v, want a VALIDATION_ERROR for limit", extensions)
+	}
+
+	_, body = serveJSON(t, router, http.MethodPost, "/graphql?lang=vi", `{"query": "mutation { deleteUser(id: \"abc\") }"}`, header)
+	if codes, messages := graphQLErrors(body); len(codes) != 1 || codes[0] != "BAD_REQUEST" || messages[0] == "Invalid user ID format" {
+		t.Errorf("delete: got %v %v, want a Vietnamese BAD_REQUEST", codes, messages)
+	}
+
+	code, body := serveJSON(t, router, http.MethodPost, "/graphql", `{"variables": {}}`, header)
+	if code != http.StatusBadRequest || body["success"] != false {
+		t.Errorf("no query: got %d %v, want a 400 APIResponse", code, body)
+	}
+}
+
+/* TestGraphQLSubscription checks userChanged streams user events over graphql-transport-ws */
+func TestGraphQLSubscription(t *testing.T) {
+	broker := newTestBroker(t)
+	hub := streaming.NewHub(4)
+	streaming.SetHub(hub)
+	startConsumer(t, broker, hub.Consumer("graphql-test"))
+	server := httptest.NewServer(routes.SetupRoutes(newGraphQLConfig(15, 2000)))
+	t.Cleanup(server.Close)
+
+	url := "ws" + strings.TrimPrefix(server.URL, "http") + "/graphql/ws?access_token=" + testAdminToken
+	ws, err := websocket.Dial(url, "graphql-transport-ws", server.URL)
+	if err != nil {
+		t.Fatalf("failed to connect: %v", err)
+	}
+	defer ws.Close()
+	ws.SetReadDeadline(time.Now().Add(5 * time.Second))
+
+	type message struct {
+		ID      string          `json:"id,omitempty"`
+		Type    string          `json:"type"`
+		Payload json.RawMessage `json:"payload,omitempty"`
+	}
+	receive := func() message {
+		var msg message
+		if err := websocket.JSON.Receive(ws, &msg); err != nil {
+			t.Fatalf("failed to receive: %v", err)
+		}
+		return msg
+	}
+
+	websocket.JSON.Send(ws, message{Type: "connection_init"})
+	if ack := receive(); ack.Type != "connection_ack" {
+		t.Fatalf("got %+v, want connection_ack", ack)
+	}
+
+	subscribe, _ := json.Marshal(usergraphql.Request{
+		Query:     `subscription($ids: [ID!]) { userChanged(events: [created, deleted], userIds: $ids) { id type userId user { username } } }`,
+		Variables: map[string]interface{}{"ids": []string{"7"}},
+	})
+	websocket.JSON.Send(ws, message{ID: "1", Type: "subscribe", Payload: subscribe})
+	waitForSubscribers(t, hub, 1)
+
+	publishUserEvent(t, broker, "updated", 7) // filtered out by event
+	publishUserEvent(t, broker, "created", 8) // filtered out by user
+	createdID := publishUserEvent(t, broker, "created", 7)
+
+	next := receive()
+	var result struct {
+		Data struct {
+			UserChanged struct {
+				ID, Type, UserID string
+				User             struct{ Username string }
+			}
+		}
+	}
+	json.Unmarshal(next.Payload, &result)
+	if changed := result.Data.UserChanged; next.ID != "1" || next.Type != "next" || changed.ID != createdID || changed.Type != "user.created" || changed.UserID != "7" || changed.User.Username != "john" {
+		t.Fatalf("got %+v %s, want user.created for 7", next, next.Payload)
+	}
+
+	// Invalid filters fail the operation with an error message
+	invalid, _ := json.Marshal(usergraphql.Request{Query: `subscription { userChanged(userIds: ["0"]) { id } }`})
+	websocket.JSON.Send(ws, message{ID: "2", Type: "subscribe", Payload: invalid})
+	if failed := receive(); failed.ID != "2" || !strings.Contains(string(failed.Payload), "userIds") {
+		t.Errorf("got %+v %s, want a userIds error for 2", failed, failed.Payload)
+	}
+
+	// Completing the operation unsubscribes it
+	websocket.JSON.Send(ws, message{ID: "1", Type: "complete"})
+	waitForSubscribers(t, hub, 0)
+}
@@ -0,0 +1,277 @@
+package examples
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"baseApi/apperror"
+	"baseApi/config"
+	usergrpc "baseApi/grpc"
+	grpcv2 "baseApi/grpc/v2"
+	"baseApi/services"
+	"baseApi/streaming"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	reflectionpb "google.golang.org/grpc/reflection/grpc_reflection_v1alpha"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/types/known/fieldmaskpb"
+)
+
+/* newGRPCConn serves the gRPC API over an in-memory listener and returns a connection to it */
+func newGRPCConn(t *testing.T, cfg *config.Config) *grpc.ClientConn {
+	t.Helper()
+	listener := bufconn.Listen(1 << 20)
+	server := usergrpc.NewServer(cfg)
+	go server.Serve(listener)
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.Dial("bufnet",
+		grpc.WithContextDialer(func(context.Context, string) (net.Conn, error) { return listener.Dial() }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatalf("failed to dial: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+/* statusDetails returns the ErrorInfo reason and BadRequest fields of a status */
+func statusDetails(st *status.Status) (reason string, fields []string) {
+	for _, detail := range st.Details() {
+		switch d := detail.(type) {
+		case *errdetails.ErrorInfo:
+			reason = d.Reason
+		case *errdetails.BadRequest:
+			for _, violation := range d.FieldViolations {
+				fields = append(fields, violation.Field)
+			}
+		}
+	}
+	return reason, fields
+}
+
+/* TestGRPCCreateUserValidation checks invalid requests are rejected with field violations before reaching the database */
+func TestGRPCCreateUserValidation(t *testing.T) {
+	client := usergrpc.NewUserServiceClient(newGRPCConn(t, &config.Config{}))
+
+	_, err := client.CreateUser(context.Background(), &usergrpc.CreateUserRequest{Name: "x", Email: "not-an-email"})
+	st := status.Convert(err)
+	if st.Code() != codes.InvalidArgument {
+		t.Fatalf("got %v, want InvalidArgument", err)
+	}
+	reason, fields := statusDetails(st)
+	if reason != "VALIDATION_ERROR" || len(fields) != 3 || fields[0] != "name" || fields[1] != "email" || fields[2] != "password" {
+		t.Errorf("got reason %q and fields %v, want VALIDATION_ERROR for name, email, password", reason, fields)
+	}
+
+	// Messages follow accept-language like the HTTP API
+	ctx := metadata.AppendToOutgoingContext(context.Background(), "accept-language", "vi")
+	_, err = client.GetUser(ctx, &usergrpc.GetUserRequest{})
+	st = status.Convert(err)
+	if st.Code() != codes.InvalidArgument || st.Message() == "Invalid user ID format" {
+		t.Errorf("got %v %q, want a Vietnamese InvalidArgument", st.Code(), st.Message())
+	}
+}
+
+/* TestGRPCV2Validation checks v2 reports failures under its own field names, beside a still-served v1 */
+func TestGRPCV2Validation(t *testing.T) {
+	conn := newGRPCConn(t, &config.Config{})
+	client := grpcv2.NewUserServiceClient(conn)
+	ctx := context.Background()
+
+	long := strings.Repeat("x", 51)
+	_, err := client.CreateUser(ctx, &grpcv2.CreateUserRequest{Username: "john_doe", Email: "john@example.com", Password: "secret1", LastName: long})
+	if reason, fields := statusDetails(status.Convert(err)); reason != "VALIDATION_ERROR" || len(fields) != 1 || fields[0] != "last_name" {
+		t.Errorf("create: got %v (%v), want a last_name violation", err, fields)
+	}
+
+	_, err = client.UpdateUser(ctx, &grpcv2.UpdateUserRequest{
+		User:       &grpcv2.User{Id: 1},
+		UpdateMask: &fieldmaskpb.FieldMask{Paths: []string{"password"}},
+	})
+	if _, fields := statusDetails(status.Convert(err)); status.Code(err) != codes.InvalidArgument || len(fields) != 1 || fields[0] != "update_mask" {
+		t.Errorf("update: got %v (%v), want an update_mask violation", err, fields)
+	}
+
+	_, err = client.ListUsers(ctx, &grpcv2.ListUsersRequest{PageSize: 500})
+	if _, fields := statusDetails(status.Convert(err)); len(fields) != 1 || fields[0] != "page_size" {
+		t.Errorf("list: got %v (%v), want a page_size violation", err, fields)
+	}
+
+	_, err = client.ListUsers(ctx, &grpcv2.ListUsersRequest{PageToken: "not-a-token"})
+	if reason, _ := statusDetails(status.Convert(err)); status.Code(err) != codes.InvalidArgument || reason != "BAD_REQUEST" {
+		t.Errorf("list: got %v, want an invalid page token", err)
+	}
+
+	// v1 is served on the same server
+	_, err = usergrpc.NewUserServiceClient(conn).GetUser(ctx, &usergrpc.GetUserRequest{})
+	if status.Code(err) != codes.InvalidArgument {
+		t.Errorf("v1: got %v, want InvalidArgument", err)
+	}
+}
+
+/* TestGRPCErrorStatus checks domain errors map to gRPC codes without leaking internal causes */
+func TestGRPCErrorStatus(t *testing.T) {
+	cases := []struct {
+		err  error
+		want codes.Code
+	}{
+		{apperror.NotFound("User"), codes.NotFound},
+		{apperror.AlreadyExists("User", "email"), codes.AlreadyExists},
+		{services.ErrVersionMismatch, codes.FailedPrecondition},
+		{services.ErrUserStreamLagged, codes.Aborted},
+		{apperror.Conflict("User was modified concurrently"), codes.Aborted},
+		{apperror.Unauthorized("Authentication required"), codes.Unauthenticated},
+		{context.DeadlineExceeded, codes.DeadlineExceeded},
+		{errors.New("pq: connection refused"), codes.Internal},
+	}
+	for _, tc := range cases {
+		st := usergrpc.ErrorStatus(tc.err, "en")
+		if st.Code() != tc.want {
+			t.Errorf("%v: got %v, want %v", tc.err, st.Code(), tc.want)
+		}
+	}
+
+	st := usergrpc.ErrorStatus(errors.New("pq: connection refused"), "en")
+	if reason, _ := statusDetails(st); st.Message() != "Internal server error occurred" || reason != "INTERNAL_SERVER_ERROR" {
+		t.Errorf("internal error exposed %q (%s)", st.Message(), reason)
+	}
+}
+
+/* TestGRPCInterceptors checks token auth, request ID propagation and panic recovery */
+func TestGRPCInterceptors(t *testing.T) {
+	client := grpcv2.NewUserServiceClient(newGRPCConn(t, &config.Config{GRPCAuthToken: "grpc-secret"}))
+
+	ctx := metadata.AppendToOutgoingContext(context.Background(), "accept-language", "vi")
+	_, err := client.GetUser(ctx, &grpcv2.GetUserRequest{Id: 1})
+	if st := status.Convert(err); st.Code() != codes.Unauthenticated || st.Message() == "Authentication required" {
+		t.Fatalf("no token: got %v %q, want a Vietnamese Unauthenticated", st.Code(), st.Message())
+	}
+
+	// The caller's x-request-id is echoed in the header and in the error's RequestInfo
+	ctx = metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer grpc-secret", "x-request-id", "grpc-test")
+	var header metadata.MD
+	_, err = client.GetUser(ctx, &grpcv2.GetUserRequest{}, grpc.Header(&header))
+	var requestID string
+	for _, detail := range status.Convert(err).Details() {
+		if info, ok := detail.(*errdetails.RequestInfo); ok {
+			requestID = info.RequestId
+		}
+	}
+	if status.Code(err) != codes.InvalidArgument || requestID != "grpc-test" || strings.Join(header.Get("x-request-id"), "") != "grpc-test" {
+		t.Errorf("request ID: got %v, RequestInfo %q and header %v", err, requestID, header.Get("x-request-id"))
+	}
+
+	// Without a database the service panics; the call fails with INTERNAL and the server keeps serving
+	ctx = metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer grpc-secret")
+	_, err = client.GetUser(ctx, &grpcv2.GetUserRequest{Id: 1}, grpc.Header(&header))
+	if st := status.Convert(err); st.Code() != codes.Internal || st.Message() != "Internal server error occurred" {
+		t.Errorf("panic: got %v %q, want INTERNAL without the cause", st.Code(), st.Message())
+	}
+	if len(header.Get("x-request-id")) != 1 {
+		t.Errorf("panic: got header %v, want a generated x-request-id", header)
+	}
+	_, err = client.GetUser(ctx, &grpcv2.GetUserRequest{})
+	if status.Code(err) != codes.InvalidArgument {
+		t.Errorf("after panic: got %v, want InvalidArgument", err)
+	}
+}
+
+/* TestGRPCHealthAndReflection checks probes see the real dependency status without a token, and reflection lists the services */
+func TestGRPCHealthAndReflection(t *testing.T) {
+	conn := newGRPCConn(t, &config.Config{GRPCAuthToken: "grpc-secret", GRPCReflectionEnabled: true})
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	// No database in tests: the database and the user services go NOT_SERVING
+	for _, service := range []string{"database", grpcv2.UserService_ServiceDesc.ServiceName, ""} {
+		watch, err := healthpb.NewHealthClient(conn).Watch(ctx, &healthpb.HealthCheckRequest{Service: service})
+		if err != nil {
+			t.Fatalf("health %q: %v", service, err)
+		}
+		for {
+			resp, err := watch.Recv()
+			if err != nil {
+				t.Fatalf("health %q: %v", service, err)
+			}
+			if resp.Status == healthpb.HealthCheckResponse_NOT_SERVING {
+				break
+			}
+		}
+	}
+
+	info, err := reflectionpb.NewServerReflectionClient(conn).ServerReflectionInfo(ctx)
+	if err != nil {
+		t.Fatalf("reflection: %v", err)
+	}
+	info.Send(&reflectionpb.ServerReflectionRequest{MessageRequest: &reflectionpb.ServerReflectionRequest_ListServices{}})
+	resp, err := info.Recv()
+	if err != nil {
+		t.Fatalf("reflection: %v", err)
+	}
+	var services []string
+	for _, service := range resp.GetListServicesResponse().GetService() {
+		services = append(services, service.Name)
+	}
+	if listed := strings.Join(services, ","); !strings.Contains(listed, "grpc.v2.UserService") || !strings.Contains(listed, "grpc.health.v1.Health") {
+		t.Errorf("reflection: got %v, want the user and health services", services)
+	}
+}
+
+/* TestGRPCWatchUsers checks WatchUsers streams the published user events matching its filter */
+func TestGRPCWatchUsers(t *testing.T) {
+	broker := newTestBroker(t)
+	hub := streaming.NewHub(4)
+	streaming.SetHub(hub)
+	startConsumer(t, broker, hub.Consumer("grpc-watch-test"))
+
+	client := grpcv2.NewUserServiceClient(newGRPCConn(t, &config.Config{UserStreamEnabled: true, UserStreamReplayLimit: 100}))
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	stream, err := client.WatchUsers(ctx, &grpcv2.WatchUsersRequest{Events: []string{"created", "deleted"}, UserIds: []uint32{7}})
+	if err != nil {
+		t.Fatalf("watch: %v", err)
+	}
+	waitForSubscribers(t, hub, 1)
+
+	publishUserEvent(t, broker, "updated", 7) // filtered out by event
+	publishUserEvent(t, broker, "created", 8) // filtered out by user
+	createdID := publishUserEvent(t, broker, "created", 7)
+	publishUserEvent(t, broker, "deleted", 7)
+
+	created, err := stream.Recv()
+	if err != nil || created.Id != createdID || created.Type != "user.created" || created.GetUser().GetUsername() != "john" || created.Time == nil {
+		t.Fatalf("created: got %v (%v)", created, err)
+	}
+	deleted, err := stream.Recv()
+	if err != nil || deleted.Type != "user.deleted" || deleted.GetUser().GetId() != 7 || deleted.GetUser().GetUsername() != "" {
+		t.Fatalf("deleted: got %v (%v)", deleted, err)
+	}
+
+	// Stream errors arrive with the first Recv
+	invalid, err := client.WatchUsers(ctx, &grpcv2.WatchUsersRequest{UserIds: []uint32{0}})
+	if err == nil {
+		_, err = invalid.Recv()
+	}
+	if _, fields := statusDetails(status.Convert(err)); len(fields) != 1 || fields[0] != "user_ids" {
+		t.Errorf("invalid filter: got %v (%v), want a user_ids violation", err, fields)
+	}
+
+	// Shutdown ends the stream with Unavailable rather than OK, so clients know to resume
+	hub.Close()
+	if _, err := stream.Recv(); status.Code(err) != codes.Unavailable {
+		t.Errorf("after hub.Close: got %v, want Unavailable", err)
+	}
+}
@@ -0,0 +1,122 @@
+package examples
+
+import (
+	"encoding/json"
+	"os"
+	"reflect"
+	"regexp"
+	"sort"
+	"testing"
+
+	"baseApi/apperror"
+	"baseApi/dto"
+	"baseApi/i18n"
+)
+
+// placeholderPattern matches {name} placeholders in catalog messages
+var placeholderPattern = regexp.MustCompile(`\{\w+\}`)
+
+/* readCatalog reads a locale's message catalog */
+func readCatalog(t *testing.T, locale string) map[string]string {
+	t.Helper()
+	data, err := os.ReadFile("../i18n/locales/" + locale + ".json")
+	if err != nil {
+		t.Fatalf("failed to read the %s catalog: %v", locale, err)
+	}
+	catalog := map[string]string{}
+	if err := json.Unmarshal(data, &catalog); err != nil {
+		t.Fatalf("invalid %s catalog: %v", locale, err)
+	}
+	return catalog
+}
+
+/* TestCatalogsAreComplete checks every message is translated, with the same placeholders */
+func TestCatalogsAreComplete(t *testing.T) {
+	en := readCatalog(t, i18n.English)
+	vi := readCatalog(t, i18n.Vietnamese)
+
+	for key, message := range en {
+		translation, ok := vi[key]
+		if !ok {
+			t.Errorf("%s is missing from the vi catalog", key)
+			continue
+		}
+		if translation == "" {
+			t.Errorf("%s is empty in the vi catalog", key)
+		}
+		want := placeholderPattern.FindAllString(message, -1)
+		got := placeholderPattern.FindAllString(translation, -1)
+		sort.Strings(want)
+		sort.Strings(got)
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: vi uses placeholders %v, en uses %v", key, got, want)
+		}
+	}
+	for key := range vi {
+		if _, ok := en[key]; !ok {
+			t.Errorf("%s is missing from the en catalog", key)
+		}
+	}
+
+	// Problem titles and non-English messages without a key come from error.<Code>
+	for _, code := range []string{
+		dto.ErrorCodeUnauthorized, dto.ErrorCodeForbidden, dto.ErrorCodeValidation, dto.ErrorCodeBadRequest,
+		dto.ErrorCodeInvalidFormat, dto.ErrorCodeUnsupportedMedia, dto.ErrorCodeNotFound, dto.ErrorCodeAlreadyExists,
+		dto.ErrorCodeConflict, dto.ErrorCodePreconditionRequired, dto.ErrorCodeInternalServer, dto.ErrorCodeDatabaseError,
+	} {
+		if _, ok := en["error."+code]; !ok {
+			t.Errorf("error.%s is missing", code)
+		}
+	}
+}
+
+/* TestNegotiate checks Accept-Language negotiation and its fallback to English */
+func TestNegotiate(t *testing.T) {
+	tests := []struct {
+		acceptLanguage string
+		want           string
+	}{
+		{"", i18n.English},
+		{"vi", i18n.Vietnamese},
+		{"vi-VN", i18n.Vietnamese},
+		{"vi-VN,vi;q=0.9,en;q=0.8", i18n.Vietnamese},
+		{"en-US", i18n.English},
+		{"fr", i18n.English},
+		{"fr-FR, vi;q=0.5", i18n.Vietnamese},
+		{"en;q=0.2, vi;q=0.8", i18n.Vietnamese},
+		{"*", i18n.English},
+		{";;not a header", i18n.English},
+	}
+
+	for _, tt := range tests {
+		if got := i18n.Negotiate(tt.acceptLanguage); got != tt.want {
+			t.Errorf("Negotiate(%q) = %s, want %s", tt.acceptLanguage, got, tt.want)
+		}
+	}
+}
+
+/* TestLocalize checks messages, placeholders and resource terms are translated */
+func TestLocalize(t *testing.T) {
+	if got := i18n.T(i18n.Vietnamese, "validation.required", map[string]string{"field": "email"}); got != "email là bắt buộc" {
+		t.Errorf("got %q, want the vi message with the field filled in", got)
+	}
+	if got := i18n.T(i18n.Vietnamese, "error.no_such_key", nil); got != "error.no_such_key" {
+		t.Errorf("got %q for an unknown key, want the key", got)
+	}
+
+	err := apperror.NotFound("User")
+	if got := err.Localize(i18n.Vietnamese).Message; got != "Không tìm thấy người dùng" {
+		t.Errorf("vi: got %q", got)
+	}
+	if got := err.Localize(i18n.English).Message; got != "User not found" {
+		t.Errorf("en: got %q", got)
+	}
+
+	// Without a key, non-English locales get the generic message for the code
+	if got := apperror.Conflict("busy").Localize(i18n.Vietnamese).Message; got == "busy" {
+		t.Errorf("vi: got the English message %q", got)
+	}
+	if got := apperror.Conflict("busy").Localize(i18n.English).Message; got != "busy" {
+		t.Errorf("en: got %q, want the original message", got)
+	}
+}
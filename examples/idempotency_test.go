@@ -0,0 +1,179 @@
+package examples
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"baseApi/apperror"
+	"baseApi/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+const testIdempotencyLockKey = "idempotency:POST:/things:key-1:lock"
+
+/* newIdempotentRouter serves POST /things through the idempotency middleware, calling handle for requests that are not replayed */
+func newIdempotentRouter(handle gin.HandlerFunc) *gin.Engine {
+	router := gin.New()
+	router.Use(middleware.ErrorHandler())
+	router.POST("/things", middleware.IdempotencyMiddleware(time.Hour, 30*time.Second), handle)
+	return router
+}
+
+/* postThing sends POST /things with an Idempotency-Key */
+func postThing(router http.Handler, key, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/things", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(middleware.IdempotencyKeyHeader, key)
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	return rec
+}
+
+/* TestIdempotentReplay checks a retry gets the stored response without running the handler again */
+func TestIdempotentReplay(t *testing.T) {
+	redis := useTestRedis(t)
+	var calls int32
+	router := newIdempotentRouter(func(c *gin.Context) {
+		n := atomic.AddInt32(&calls, 1)
+		c.Header("Location", "/things/1")
+		c.JSON(http.StatusCreated, gin.H{"id": 1, "call": n})
+	})
+
+	first := postThing(router, "key-1", `{"name":"a"}`)
+	retry := postThing(router, "key-1", `{"name":"a"}`)
+
+	if calls != 1 {
+		t.Fatalf("handler ran %d times, want 1", calls)
+	}
+	if retry.Code != http.StatusCreated || retry.Body.String() != first.Body.String() {
+		t.Errorf("got %d %s, want the stored %d %s", retry.Code, retry.Body.String(), first.Code, first.Body.String())
+	}
+	if retry.Header().Get(middleware.IdempotencyReplayedHeader) != "true" || first.Header().Get(middleware.IdempotencyReplayedHeader) != "" {
+		t.Errorf("only the retry should carry %s", middleware.IdempotencyReplayedHeader)
+	}
+	if retry.Header().Get("Location") != "/things/1" {
+		t.Errorf("got Location %q, want the stored one", retry.Header().Get("Location"))
+	}
+	if redis.Exists(testIdempotencyLockKey) {
+		t.Errorf("lock was not released")
+	}
+
+	// Another key is another request
+	if other := postThing(router, "key-2", `{"name":"a"}`); other.Header().Get(middleware.IdempotencyReplayedHeader) != "" || calls != 2 {
+		t.Errorf("a new key was replayed")
+	}
+}
+
+/* TestIdempotencyKeyReuse checks a key reused with a different body is rejected */
+func TestIdempotencyKeyReuse(t *testing.T) {
+	useTestRedis(t)
+	var calls int32
+	router := newIdempotentRouter(func(c *gin.Context) {
+		atomic.AddInt32(&calls, 1)
+		c.JSON(http.StatusCreated, gin.H{"id": 1})
+	})
+
+	postThing(router, "key-1", `{"name":"a"}`)
+	rec := postThing(router, "key-1", `{"name":"b"}`)
+
+	if rec.Code != http.StatusConflict || !strings.Contains(rec.Body.String(), "different request body") {
+		t.Errorf("got %d %s, want 409 for a reused key", rec.Code, rec.Body.String())
+	}
+	if calls != 1 {
+		t.Errorf("handler ran %d times, want 1", calls)
+	}
+}
+
+/* TestIdempotencyInProgress checks a duplicate sent while the first request runs is told to retry */
+func TestIdempotencyInProgress(t *testing.T) {
+	useTestRedis(t)
+	started := make(chan struct{})
+	release := make(chan struct{})
+	router := newIdempotentRouter(func(c *gin.Context) {
+		close(started)
+		<-release
+		c.JSON(http.StatusCreated, gin.H{"id": 1})
+	})
+
+	done := make(chan *httptest.ResponseRecorder)
+	go func() { done <- postThing(router, "key-1", `{"name":"a"}`) }()
+	<-started
+
+	duplicate := postThing(router, "key-1", `{"name":"a"}`)
+	if duplicate.Code != http.StatusConflict || duplicate.Header().Get("Retry-After") != "1" {
+		t.Errorf("got %d with Retry-After %q, want 409 and Retry-After 1", duplicate.Code, duplicate.Header().Get("Retry-After"))
+	}
+	if !strings.Contains(duplicate.Body.String(), "already in progress") {
+		t.Errorf("got %s, want the in-progress error", duplicate.Body.String())
+	}
+
+	close(release)
+	if first := <-done; first.Code != http.StatusCreated {
+		t.Errorf("first request: got %d, want 201", first.Code)
+	}
+	if retry := postThing(router, "key-1", `{"name":"a"}`); retry.Header().Get(middleware.IdempotencyReplayedHeader) != "true" {
+		t.Errorf("retry after completion was not replayed")
+	}
+}
+
+/* TestIdempotencyDoesNotStoreFailures checks failed requests can be retried with the same key */
+func TestIdempotencyDoesNotStoreFailures(t *testing.T) {
+	tests := []struct {
+		name string
+		fail gin.HandlerFunc
+	}{
+		{"c.Error", func(c *gin.Context) {
+			c.Error(apperror.Conflict("User with this email already exists"))
+		}},
+		{"server error", func(c *gin.Context) {
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": "try again"})
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			useTestRedis(t)
+			var calls int32
+			router := newIdempotentRouter(func(c *gin.Context) {
+				if atomic.AddInt32(&calls, 1) == 1 {
+					tt.fail(c)
+					return
+				}
+				c.JSON(http.StatusCreated, gin.H{"id": 1})
+			})
+
+			if first := postThing(router, "key-1", `{"name":"a"}`); first.Code < 400 {
+				t.Fatalf("first request: got %d, want it to fail", first.Code)
+			}
+			retry := postThing(router, "key-1", `{"name":"a"}`)
+			if retry.Code != http.StatusCreated || retry.Header().Get(middleware.IdempotencyReplayedHeader) != "" {
+				t.Errorf("retry: got %d replayed=%q, want a fresh 201", retry.Code, retry.Header().Get(middleware.IdempotencyReplayedHeader))
+			}
+			if calls != 2 {
+				t.Errorf("handler ran %d times, want 2", calls)
+			}
+		})
+	}
+}
+
+/* TestIdempotencyLockReleasesOnlyItsOwn checks a lock that expired and was taken over is left to its new holder */
+func TestIdempotencyLockReleasesOnlyItsOwn(t *testing.T) {
+	redis := useTestRedis(t)
+	router := newIdempotentRouter(func(c *gin.Context) {
+		// The request outlived its lock and another one took the key
+		redis.FastForward(time.Minute)
+		redis.Set(testIdempotencyLockKey, `"other-request"`)
+		c.JSON(http.StatusCreated, gin.H{"id": 1})
+	})
+
+	postThing(router, "key-1", `{"name":"a"}`)
+
+	if holder, err := redis.Get(testIdempotencyLockKey); err != nil || holder != `"other-request"` {
+		t.Errorf("got lock %q (%v), want it still held by the other request", holder, err)
+	}
+}
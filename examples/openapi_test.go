@@ -0,0 +1,169 @@
+package examples
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"baseApi/config"
+	"baseApi/dto"
+	"baseApi/openapi"
+	"baseApi/routes"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/santhosh-tekuri/jsonschema/v5"
+)
+
+/* newDocsRouter serves the API with every optional route group enabled */
+func newDocsRouter() *gin.Engine {
+	return routes.SetupRoutes(&config.Config{
+		AdminToken:          testAdminToken,
+		UserStreamEnabled:   true,
+		UserStreamHeartbeat: time.Minute,
+		GRPCPort:            "0",
+		GRPCGatewayEnabled:  true,
+		GraphQLEnabled:      true,
+		GraphQLMaxDepth:     15,
+		APIDocsEnabled:      true,
+		AppVersion:          "v1.0.0",
+	})
+}
+
+/* compileSpec serves /openapi.json and returns a compiler for schemas addressed by JSON pointer into it */
+func compileSpec(t *testing.T, router http.Handler) (map[string]interface{}, func(pointer string) *jsonschema.Schema) {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/openapi.json", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("GET /openapi.json: got %d", rec.Code)
+	}
+
+	var spec map[string]interface{}
+	json.Unmarshal(rec.Body.Bytes(), &spec)
+	compiler := jsonschema.NewCompiler()
+	compiler.Draft = jsonschema.Draft2020
+	compiler.AssertFormat = true
+	if err := compiler.AddResource("mem://openapi.json", bytes.NewReader(rec.Body.Bytes())); err != nil {
+		t.Fatalf("invalid document: %v", err)
+	}
+	return spec, func(pointer string) *jsonschema.Schema {
+		schema, err := compiler.Compile("mem://openapi.json#" + pointer)
+		if err != nil {
+			t.Fatalf("schema %s: %v", pointer, err)
+		}
+		return schema
+	}
+}
+
+/* TestOpenAPIMatchesRoutes fails when a route is missing from openapi/endpoints.go or an endpoint there has no route */
+func TestOpenAPIMatchesRoutes(t *testing.T) {
+	router := newDocsRouter()
+
+	undocumented, unrouted := openapi.Diff(router.Routes())
+	if len(undocumented) > 0 {
+		t.Errorf("routes missing from openapi/endpoints.go: %v", undocumented)
+	}
+	if len(unrouted) > 0 {
+		t.Errorf("openapi/endpoints.go entries without a route: %v", unrouted)
+	}
+	if _, err := openapi.Build(router.Routes(), "v1.0.0"); err != nil {
+		t.Errorf("failed to build the document: %v", err)
+	}
+
+	spec, _ := compileSpec(t, router)
+	paths, _ := spec["paths"].(map[string]interface{})
+	user, _ := paths["/v1/users/{id}"].(map[string]interface{})
+	if spec["openapi"] != "3.1.0" || len(user) != 4 {
+		t.Errorf("got openapi %v and /v1/users/{id} %v, want 3.1.0 with get, put, patch and delete", spec["openapi"], user)
+	}
+
+	// Optional route groups are only documented when they are served
+	_, spec = serveJSON(t, routes.SetupRoutes(&config.Config{APIDocsEnabled: true}), http.MethodGet, "/openapi.json", "", nil)
+	paths, _ = spec["paths"].(map[string]interface{})
+	if _, ok := paths["/v1/webhooks"]; ok || paths["/v1/users"] == nil {
+		t.Errorf("without ADMIN_TOKEN: got paths %v, want /v1/users but no /v1/webhooks", paths)
+	}
+}
+
+/* TestOpenAPISchemasFollowBindingRules checks request schemas accept exactly the bodies the binding rules accept */
+func TestOpenAPISchemasFollowBindingRules(t *testing.T) {
+	_, compile := compileSpec(t, newDocsRouter())
+
+	cases := []struct {
+		schema string
+		body   string
+		target func() interface{}
+	}{
+		{"CreateUserRequest", `{"username": "john_doe", "email": "john@example.com", "password": "secret123"}`, func() interface{} { return &dto.CreateUserRequest{} }},
+		{"CreateUserRequest", `{"username": "jo", "email": "john@example.com", "password": "secret123"}`, func() interface{} { return &dto.CreateUserRequest{} }},
+		{"CreateUserRequest", `{"username": "john_doe", "email": "john@localhost", "password": "secret123"}`, func() interface{} { return &dto.CreateUserRequest{} }},
+		{"CreateUserRequest", `{"username": "john_doe", "email": "john@example.com", "password": "short"}`, func() interface{} { return &dto.CreateUserRequest{} }},
+		{"CreateUserRequest", `{"username": "john_doe", "password": "secret123"}`, func() interface{} { return &dto.CreateUserRequest{} }},
+		{"UpdateUserRequest", `{"isActive": null, "firstName": "John"}`, func() interface{} { return &dto.UpdateUserRequest{} }},
+		{"UpdateUserRequest", `{"lastName": "` + strings.Repeat("x", 51) + `"}`, func() interface{} { return &dto.UpdateUserRequest{} }},
+		{"CreateWebhookRequest", `{"url": "https://example.com/hook", "events": ["user.*", "user.created"]}`, func() interface{} { return &dto.CreateWebhookRequest{} }},
+		{"CreateWebhookRequest", `{"url": "https://example.com/hook", "events": []}`, func() interface{} { return &dto.CreateWebhookRequest{} }},
+		{"CreateWebhookRequest", `{"url": "https://example.com/hook", "events": ["User Created"]}`, func() interface{} { return &dto.CreateWebhookRequest{} }},
+		{"CreateWebhookRequest", `{"url": "https://example.com/hook", "events": ["user.created"], "secret": "short"}`, func() interface{} { return &dto.CreateWebhookRequest{} }},
+	}
+	for _, tc := range cases {
+		target := tc.target()
+		json.Unmarshal([]byte(tc.body), target)
+		bindingErr := binding.Validator.ValidateStruct(target)
+
+		var doc interface{}
+		json.Unmarshal([]byte(tc.body), &doc)
+		schemaErr := compile("/components/schemas/" + tc.schema).Validate(doc)
+
+		if (bindingErr == nil) != (schemaErr == nil) {
+			t.Errorf("%s %s: binding says %v, schema says %v", tc.schema, tc.body, bindingErr, schemaErr)
+		}
+	}
+}
+
+/* TestOpenAPIResponsesMatch checks served responses match the documented envelope */
+func TestOpenAPIResponsesMatch(t *testing.T) {
+	router := newDocsRouter()
+	_, compile := compileSpec(t, router)
+
+	responses := []struct {
+		method, path, accept, pointer string
+	}{
+		{http.MethodGet, "/health", "", "/paths/~1health/get/responses/200/content/application~1json/schema"},
+		{http.MethodGet, "/v1/events/schemas", "", "/paths/~1v1~1events~1schemas/get/responses/200/content/application~1json/schema"},
+		{http.MethodGet, "/v1/users/abc", "", "/components/responses/BadRequest/content/application~1json/schema"},
+		{http.MethodGet, "/v1/webhooks", "application/problem+json", "/components/responses/Unauthorized/content/application~1problem+json/schema"},
+	}
+	for _, r := range responses {
+		header := http.Header{}
+		if r.accept != "" {
+			header.Set("Accept", r.accept)
+		}
+		_, body := serveJSON(t, router, r.method, r.path, "", header)
+		if err := compile(r.pointer).Validate(body); err != nil {
+			t.Errorf("%s %s does not match %s: %v", r.method, r.path, r.pointer, err)
+		}
+	}
+}
+
+/* TestSwaggerUI checks the UI page and its embedded assets are served */
+func TestSwaggerUI(t *testing.T) {
+	router := newDocsRouter()
+
+	for path, want := range map[string]string{
+		"/docs/":                     "../openapi.json",
+		"/docs/swagger-ui-bundle.js": "SwaggerUIBundle",
+		"/docs/swagger-ui.css":       ".swagger-ui",
+	} {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), want) {
+			t.Errorf("GET %s: got %d, want a body with %q", path, rec.Code, want)
+		}
+	}
+}
@@ -0,0 +1,110 @@
+package examples
+
+import (
+	"database/sql/driver"
+	"testing"
+	"time"
+
+	"baseApi/cache"
+	"baseApi/database"
+	"baseApi/models"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+/* useTestDB points database.DB at a sqlmock connection for the rest of the test */
+func useTestDB(t *testing.T) sqlmock.Sqlmock {
+	t.Helper()
+	conn, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create sqlmock: %v", err)
+	}
+	db, err := gorm.Open(postgres.New(postgres.Config{Conn: conn}), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	if err != nil {
+		t.Fatalf("failed to open gorm on sqlmock: %v", err)
+	}
+
+	previous := database.DB
+	database.DB = db
+	t.Cleanup(func() {
+		database.DB = previous
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Errorf("database: %v", err)
+		}
+		conn.Close()
+	})
+	return mock
+}
+
+/* useTestRedis points cache.RedisClient at an in-memory Redis for the rest of the test */
+func useTestRedis(t *testing.T) *miniredis.Miniredis {
+	t.Helper()
+	server := miniredis.RunT(t)
+
+	previous := cache.RedisClient
+	cache.RedisClient = redis.NewClient(&redis.Options{Addr: server.Addr()})
+	t.Cleanup(func() {
+		cache.RedisClient.Close()
+		cache.RedisClient = previous
+	})
+	return server
+}
+
+/* newTestUser returns a stored user at the given version */
+func newTestUser(version uint) models.User {
+	created := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	return models.User{
+		ID:        7,
+		Username:  "john",
+		Email:     "john@example.com",
+		Password:  "$2a$10$hash",
+		FirstName: "John",
+		LastName:  "Doe",
+		IsActive:  true,
+		Version:   version,
+		CreatedAt: created,
+		UpdatedAt: created,
+	}
+}
+
+/* userRows returns users as rows of the users table */
+func userRows(users ...models.User) *sqlmock.Rows {
+	rows := sqlmock.NewRows([]string{"id", "username", "email", "password", "first_name", "last_name", "is_active", "version", "created_at", "updated_at", "deleted_at"})
+	for _, user := range users {
+		rows.AddRow([]driver.Value{user.ID, user.Username, user.Email, user.Password, user.FirstName, user.LastName, user.IsActive, user.Version, user.CreatedAt, user.UpdatedAt, nil}...)
+	}
+	return rows
+}
+
+/* expectUserSelect expects the lookup of user by primary key */
+func expectUserSelect(mock sqlmock.Sqlmock, user models.User) {
+	mock.ExpectQuery(`SELECT \* FROM "users" WHERE "users"."id" = \$1`).WillReturnRows(userRows(user))
+}
+
+/* expectUserList expects the count and page queries of GET /v1/users */
+func expectUserList(mock sqlmock.Sqlmock, users ...models.User) {
+	mock.ExpectQuery(`SELECT count\(\*\) FROM "users"`).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(len(users)))
+	mock.ExpectQuery(`SELECT \* FROM "users" .*ORDER BY created_at`).WillReturnRows(userRows(users...))
+}
+
+/* expectVersionedSave expects the conditional update of a user and, if it succeeds, its outbox event */
+func expectVersionedSave(mock sqlmock.Sqlmock, rowsAffected int64) {
+	mock.ExpectBegin()
+	mock.ExpectExec(`UPDATE "users" SET .* WHERE version = \$\d+`).
+		WillReturnResult(sqlmock.NewResult(0, rowsAffected))
+	if rowsAffected == 0 {
+		mock.ExpectRollback()
+		return
+	}
+	mock.ExpectQuery(`INSERT INTO "outbox"`).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	mock.ExpectCommit()
+}
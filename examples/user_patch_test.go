@@ -0,0 +1,102 @@
+package examples
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"baseApi/config"
+	"baseApi/dto"
+	"baseApi/routes"
+)
+
+/* TestPatchUser patches a stored user with both patch media types */
+func TestPatchUser(t *testing.T) {
+	tests := []struct {
+		name        string
+		contentType string
+		patch       string
+	}{
+		{"merge patch", dto.ContentTypeMergePatch, `{"firstName":"Johnny","lastName":null}`},
+		{"JSON patch", dto.ContentTypeJSONPatch, `[{"op":"replace","path":"/firstName","value":"Johnny"},{"op":"remove","path":"/lastName"}]`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mock := useTestDB(t)
+			useTestRedis(t)
+			router := routes.SetupRoutes(&config.Config{})
+
+			expectUserSelect(mock, newTestUser(3))
+			expectVersionedSave(mock, 1)
+
+			status, body := serveJSON(t, router, http.MethodPatch, "/v1/users/7", tt.patch, http.Header{
+				"Content-Type": {tt.contentType},
+			})
+			if status != http.StatusOK {
+				t.Fatalf("got status %d (%v), want 200", status, body)
+			}
+			data := body["data"].(map[string]interface{})
+			if data["firstName"] != "Johnny" || data["lastName"] != "" || data["username"] != "john" {
+				t.Errorf("got %v, want firstName changed and lastName removed", data)
+			}
+			if data["version"] != float64(4) {
+				t.Errorf("got version %v, want 4", data["version"])
+			}
+		})
+	}
+}
+
+/* TestPatchUserRejectsInvalidPatches checks patches that never reach the update */
+func TestPatchUserRejectsInvalidPatches(t *testing.T) {
+	tests := []struct {
+		name        string
+		contentType string
+		patch       string
+		wantStatus  int
+		wantCode    string
+	}{
+		{"read-only id", dto.ContentTypeMergePatch, `{"id":99}`, http.StatusBadRequest, dto.ErrorCodeInvalidFormat},
+		{"read-only version", dto.ContentTypeJSONPatch, `[{"op":"add","path":"/version","value":9}]`, http.StatusBadRequest, dto.ErrorCodeInvalidFormat},
+		{"required field removed", dto.ContentTypeMergePatch, `{"email":null}`, http.StatusBadRequest, dto.ErrorCodeValidation},
+		{"failed test operation", dto.ContentTypeJSONPatch, `[{"op":"test","path":"/username","value":"jane"}]`, http.StatusConflict, dto.ErrorCodeConflict},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mock := useTestDB(t)
+			router := routes.SetupRoutes(&config.Config{})
+
+			expectUserSelect(mock, newTestUser(3))
+
+			status, body := serveJSON(t, router, http.MethodPatch, "/v1/users/7", tt.patch, http.Header{
+				"Content-Type": {tt.contentType},
+			})
+			if status != tt.wantStatus {
+				t.Fatalf("got status %d (%v), want %d", status, body, tt.wantStatus)
+			}
+			if code := body["error"].(map[string]interface{})["code"]; code != tt.wantCode {
+				t.Errorf("got code %v, want %s", code, tt.wantCode)
+			}
+		})
+	}
+}
+
+/* TestPatchUserUnsupportedMediaType checks other content types get 415 and the accepted patch types */
+func TestPatchUserUnsupportedMediaType(t *testing.T) {
+	router := routes.SetupRoutes(&config.Config{})
+
+	req := httptest.NewRequest(http.MethodPatch, "/v1/users/7", strings.NewReader(`{"firstName":"Johnny"}`))
+	req.Header.Set("Content-Type", dto.ContentTypeJSON)
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusUnsupportedMediaType {
+		t.Fatalf("got status %d (%s), want 415", rec.Code, rec.Body.String())
+	}
+	want := dto.ContentTypeMergePatch + ", " + dto.ContentTypeJSONPatch
+	if got := rec.Header().Get("Accept-Patch"); got != want {
+		t.Errorf("got Accept-Patch %q, want %q", got, want)
+	}
+}
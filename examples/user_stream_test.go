@@ -0,0 +1,257 @@
+package examples
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"baseApi/config"
+	"baseApi/dto"
+	"baseApi/messaging"
+	"baseApi/routes"
+	"baseApi/services"
+	"baseApi/streaming"
+
+	"golang.org/x/net/websocket"
+)
+
+const testAdminToken = "test-admin-token"
+
+/* newStreamServer serves the API with a fresh hub fed by an in-memory broker */
+func newStreamServer(t *testing.T) (*httptest.Server, *streaming.Hub, *messaging.MemoryBroker) {
+	t.Helper()
+	broker := newTestBroker(t)
+	hub := streaming.NewHub(4)
+	streaming.SetHub(hub)
+	startConsumer(t, broker, hub.Consumer("user-stream-test"))
+
+	cfg := &config.Config{
+		AdminToken:            testAdminToken,
+		UserStreamEnabled:     true,
+		UserStreamHeartbeat:   time.Minute,
+		UserStreamBuffer:      4,
+		UserStreamReplayLimit: 100,
+	}
+	server := httptest.NewServer(routes.SetupRoutes(cfg))
+	t.Cleanup(server.Close)
+	return server, hub, broker
+}
+
+/* publishUserEvent publishes a user event the way the outbox relay does and returns its id */
+func publishUserEvent(t *testing.T, broker messaging.Publisher, eventType string, userID uint) string {
+	t.Helper()
+	var data interface{} = map[string]interface{}{"id": userID}
+	if eventType != "deleted" {
+		now := time.Now()
+		data = dto.UserResponse{ID: userID, Username: "john", Email: "john@example.com", IsActive: true, Version: 1, CreatedAt: now, UpdatedAt: now}
+	}
+	event, err := messaging.NewUserEvent(eventType, userID, data)
+	if err != nil {
+		t.Fatalf("failed to build user event: %v", err)
+	}
+	body, _ := json.Marshal(event)
+	err = broker.Publish(context.Background(), &messaging.Message{
+		ID:          event.ID,
+		RoutingKey:  messaging.UserEventRoutingKey(eventType),
+		ContentType: messaging.CloudEventsContentType,
+		Body:        body,
+	})
+	if err != nil {
+		t.Fatalf("failed to publish user event: %v", err)
+	}
+	return event.ID
+}
+
+/* waitForSubscribers waits until the hub has n subscribers */
+func waitForSubscribers(t *testing.T, hub *streaming.Hub, n int) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for hub.Len() != n {
+		if time.Now().After(deadline) {
+			t.Fatalf("got %d stream subscribers, want %d", hub.Len(), n)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+/* readSSE reads one event (id, event name, data) from an SSE stream, skipping comments and retry lines */
+func readSSE(t *testing.T, reader *bufio.Reader) (id, event, data string) {
+	t.Helper()
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			t.Fatalf("stream ended: %v", err)
+		}
+		line = strings.TrimRight(line, "\n")
+		switch {
+		case line == "" && event != "":
+			return id, event, data
+		case strings.HasPrefix(line, "id: "):
+			id = strings.TrimPrefix(line, "id: ")
+		case strings.HasPrefix(line, "event: "):
+			event = strings.TrimPrefix(line, "event: ")
+		case strings.HasPrefix(line, "data: "):
+			data = strings.TrimPrefix(line, "data: ")
+		}
+	}
+}
+
+/* TestUserStreamSSE checks filtered user events reach an SSE subscriber */
+func TestUserStreamSSE(t *testing.T) {
+	server, hub, broker := newStreamServer(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, server.URL+"/v1/users/stream?events=deleted&userId=7", nil)
+	req.Header.Set("Authorization", "Bearer "+testAdminToken)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("failed to open stream: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("Content-Type") != "text/event-stream" {
+		t.Fatalf("got %d %q, want a 200 event stream", resp.StatusCode, resp.Header.Get("Content-Type"))
+	}
+	waitForSubscribers(t, hub, 1)
+
+	publishUserEvent(t, broker, "updated", 7) // filtered out by event
+	publishUserEvent(t, broker, "deleted", 8) // filtered out by user
+	want := publishUserEvent(t, broker, "deleted", 7)
+
+	id, event, data := readSSE(t, bufio.NewReader(resp.Body))
+	if id != want || event != "user.deleted" {
+		t.Fatalf("got event %s %s, want %s user.deleted", id, event, want)
+	}
+	var cloudEvent messaging.CloudEvent
+	if err := json.Unmarshal([]byte(data), &cloudEvent); err != nil || cloudEvent.Subject != "users/7" {
+		t.Errorf("data is not the user's CloudEvent: %s (%v)", data, err)
+	}
+}
+
+/* TestUserStreamWebSocket checks the WebSocket endpoint, authorized with ?access_token= */
+func TestUserStreamWebSocket(t *testing.T) {
+	server, hub, broker := newStreamServer(t)
+
+	url := "ws" + strings.TrimPrefix(server.URL, "http") + "/v1/users/stream/ws?access_token=" + testAdminToken
+	ws, err := websocket.Dial(url, "", server.URL)
+	if err != nil {
+		t.Fatalf("failed to connect: %v", err)
+	}
+	defer ws.Close()
+	waitForSubscribers(t, hub, 1)
+
+	want := publishUserEvent(t, broker, "created", 3)
+
+	var frame struct {
+		ID    string          `json:"id"`
+		Event string          `json:"event"`
+		Data  json.RawMessage `json:"data"`
+	}
+	ws.SetReadDeadline(time.Now().Add(2 * time.Second))
+	if err := websocket.JSON.Receive(ws, &frame); err != nil {
+		t.Fatalf("failed to receive: %v", err)
+	}
+	if frame.ID != want || frame.Event != "user.created" || len(frame.Data) == 0 {
+		t.Errorf("got frame %+v, want %s user.created", frame, want)
+	}
+}
+
+/* TestUserStreamRequiresToken checks every subscriber is authorized and filters are validated */
+func TestUserStreamRequiresToken(t *testing.T) {
+	server, hub, _ := newStreamServer(t)
+
+	cases := map[string]int{
+		"/v1/users/stream":                                                http.StatusUnauthorized,
+		"/v1/users/stream?access_token=wrong":                             http.StatusUnauthorized,
+		"/v1/users/stream?access_token=" + testAdminToken + "&userId=abc": http.StatusBadRequest,
+	}
+	for path, want := range cases {
+		resp, err := http.Get(server.URL + path)
+		if err != nil {
+			t.Fatalf("GET %s: %v", path, err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != want {
+			t.Errorf("GET %s: got %d, want %d", path, resp.StatusCode, want)
+		}
+	}
+	if hub.Len() != 0 {
+		t.Errorf("rejected requests left %d subscribers", hub.Len())
+	}
+}
+
+/* TestSlowSubscriberIsDropped checks a subscriber that stops reading is disconnected instead of blocking the hub */
+func TestSlowSubscriberIsDropped(t *testing.T) {
+	hub := streaming.NewHub(2)
+	slow := hub.Subscribe(streaming.Filter{})
+	fast := hub.Subscribe(streaming.Filter{})
+
+	for i := 0; i < 3; i++ {
+		hub.Broadcast(streaming.Event{ID: string(rune('a' + i)), Event: "user.updated"})
+		<-fast.Events()
+	}
+
+	received := 0
+	for range slow.Events() {
+		received++
+	}
+	if received != 2 || !slow.Lagged() {
+		t.Errorf("slow subscriber got %d events (lagged=%v), want 2 then dropped", received, slow.Lagged())
+	}
+	if hub.Len() != 1 {
+		t.Errorf("got %d subscribers, want only the fast one", hub.Len())
+	}
+}
+
+/* TestServeReportsLag checks a stream whose subscriber was dropped ends with ErrUserStreamLagged, not a clean end */
+func TestServeReportsLag(t *testing.T) {
+	hub := streaming.NewHub(1)
+	release := make(chan struct{})
+	done := make(chan error, 1)
+	go func() {
+		send := func(streaming.Event) error {
+			<-release
+			return nil
+		}
+		done <- services.NewUserStreamService(0).Serve(context.Background(), hub, streaming.Filter{}, "", 0, send, nil)
+	}()
+	waitForSubscribers(t, hub, 1)
+
+	for i := 0; i < 3; i++ {
+		hub.Broadcast(streaming.Event{ID: string(rune('a' + i)), Event: "user.updated"})
+	}
+	close(release)
+
+	select {
+	case err := <-done:
+		if !errors.Is(err, services.ErrUserStreamLagged) {
+			t.Errorf("got %v, want ErrUserStreamLagged", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Serve did not return after the subscriber was dropped")
+	}
+}
+
+/* TestHubCloseEndsStreams checks closing the hub on shutdown disconnects current and new subscribers */
+func TestHubCloseEndsStreams(t *testing.T) {
+	hub := streaming.NewHub(2)
+	connected := hub.Subscribe(streaming.Filter{})
+
+	hub.Close()
+	late := hub.Subscribe(streaming.Filter{})
+
+	for name, s := range map[string]*streaming.Subscriber{"connected": connected, "late": late} {
+		if _, open := <-s.Events(); open || s.Lagged() {
+			t.Errorf("%s subscriber: got open=%v lagged=%v, want closed and not lagged", name, open, s.Lagged())
+		}
+	}
+	if hub.Len() != 0 {
+		t.Errorf("got %d subscribers after Close, want 0", hub.Len())
+	}
+}
@@ -0,0 +1,67 @@
+package examples
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"baseApi/config"
+	"baseApi/dto"
+	"baseApi/routes"
+)
+
+/* TestConditionalUpdate checks If-Match against the stored version and the outcome of a lost race */
+func TestConditionalUpdate(t *testing.T) {
+	tests := []struct {
+		name       string
+		ifMatch    string
+		saved      int64 // rows the versioned UPDATE affects; -1 when it must not run
+		wantStatus int
+		wantCode   string
+	}{
+		{"matching tag", `"3-en"`, 1, http.StatusOK, ""},
+		{"bare version", `"3"`, 1, http.StatusOK, ""},
+		{"tag of another locale", `"3-vi"`, 1, http.StatusOK, ""},
+		{"wildcard", `*`, 1, http.StatusOK, ""},
+		{"unconditional", ``, 1, http.StatusOK, ""},
+		{"stale version", `"2-en"`, -1, http.StatusPreconditionFailed, dto.ErrorCodeConflict},
+		{"weak tag only", `W/"3-en"`, -1, http.StatusPreconditionFailed, dto.ErrorCodeConflict},
+		{"no usable tags", `"abc"`, -1, http.StatusPreconditionFailed, dto.ErrorCodeConflict},
+		{"lost race with If-Match", `"3-en"`, 0, http.StatusPreconditionFailed, dto.ErrorCodeConflict},
+		{"lost race without If-Match", ``, 0, http.StatusConflict, dto.ErrorCodeConflict},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mock := useTestDB(t)
+			useTestRedis(t)
+			router := routes.SetupRoutes(&config.Config{})
+
+			expectUserSelect(mock, newTestUser(3))
+			if tt.saved >= 0 {
+				expectVersionedSave(mock, tt.saved)
+			}
+
+			req := httptest.NewRequest(http.MethodPut, "/v1/users/7", strings.NewReader(`{"firstName":"Johnny"}`))
+			req.Header.Set("Content-Type", dto.ContentTypeJSON)
+			if tt.ifMatch != "" {
+				req.Header.Set("If-Match", tt.ifMatch)
+			}
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("got status %d (%s), want %d", rec.Code, rec.Body.String(), tt.wantStatus)
+			}
+			if tt.wantCode != "" && !strings.Contains(rec.Body.String(), `"code":"`+tt.wantCode+`"`) {
+				t.Errorf("got %s, want error code %s", rec.Body.String(), tt.wantCode)
+			}
+			if rec.Code == http.StatusOK {
+				if etag := rec.Header().Get("ETag"); etag != `"4-en"` {
+					t.Errorf("got ETag %s, want \"4-en\"", etag)
+				}
+			}
+		})
+	}
+}
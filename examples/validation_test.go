@@ -0,0 +1,230 @@
+package examples
+
+import (
+	"os"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+
+	"baseApi/dto"
+	"baseApi/validation"
+
+	"github.com/gin-gonic/gin/binding"
+)
+
+/* TestCustomRules checks the custom binding rules value by value */
+func TestCustomRules(t *testing.T) {
+	validation.RegisterRules()
+
+	tests := []struct {
+		rule  string
+		value string
+		valid bool
+	}{
+		{"username_format", "john_doe", true},
+		{"username_format", "abc", true},
+		{"username_format", strings.Repeat("a", 50), true},
+		{"username_format", "ab", false},
+		{"username_format", strings.Repeat("a", 51), false},
+		{"username_format", "john.doe", false},
+		{"username_format", "john doe", false},
+		{"username_format", "jöhn", false},
+
+		{"email_format", "john@example.com", true},
+		{"email_format", "john.doe+tag@mail.example.co", true},
+		{"email_format", "john@localhost", false},
+		{"email_format", "john@example.c", false},
+		{"email_format", "john@@example.com", false},
+		{"email_format", "john doe@example.com", false},
+
+		{"http_url", "https://partner.example.com/hooks", true},
+		{"http_url", "http://localhost:9000/hooks?x=1", true},
+		{"http_url", "ftp://partner.example.com/hooks", false},
+		{"http_url", "https:///hooks", false},
+		{"http_url", "/hooks", false},
+		{"http_url", "not a url", false},
+
+		{"webhook_event", "user.created", true},
+		{"webhook_event", "user.*", true},
+		{"webhook_event", "#", true},
+		{"webhook_event", "user.#.deleted", true},
+		{"webhook_event", "User.Created", false},
+		{"webhook_event", "user..created", false},
+		{"webhook_event", "user.", false},
+		{"webhook_event", "user.cre*", false},
+		{"webhook_event", "user." + strings.Repeat("a", validation.MaxWebhookEventLength), false},
+	}
+
+	for _, tt := range tests {
+		value := reflect.New(reflect.StructOf([]reflect.StructField{{
+			Name: "Value",
+			Type: reflect.TypeOf(""),
+			Tag:  reflect.StructTag(`json:"value" binding:"` + tt.rule + `"`),
+		}})).Interface()
+		reflect.ValueOf(value).Elem().Field(0).SetString(tt.value)
+
+		err := binding.Validator.ValidateStruct(value)
+		if (err == nil) != tt.valid {
+			t.Errorf("%s(%q): got error %v, want valid %v", tt.rule, tt.value, err, tt.valid)
+		}
+	}
+}
+
+/* TestRulesMirrorDatabase checks the rules against the constraints and column sizes in scripts/manual_setup.sql */
+func TestRulesMirrorDatabase(t *testing.T) {
+	script, err := os.ReadFile("../scripts/manual_setup.sql")
+	if err != nil {
+		t.Fatalf("failed to read the setup script: %v", err)
+	}
+	sql := string(script)
+
+	// CHECK constraints use the same pattern as the rule
+	for constraint, rule := range map[string]string{
+		"chk_users_username_format": "username_format",
+		"chk_users_email_format":    "email_format",
+	} {
+		match := regexp.MustCompile(constraint + `\s+CHECK \(\w+ ~\*? '([^']+)'\)`).FindStringSubmatch(sql)
+		if match == nil {
+			t.Errorf("%s: constraint not found", constraint)
+			continue
+		}
+		if pattern, _ := validation.Pattern(rule); pattern != match[1] {
+			t.Errorf("%s: rule %s checks %s, the database checks %s", constraint, rule, pattern, match[1])
+		}
+	}
+
+	// max= matches the VARCHAR size of the column a field is stored in
+	columns := varcharSizes(sql)
+	tests := []struct {
+		dto    interface{}
+		field  string
+		column string
+	}{
+		{dto.CreateUserRequest{}, "email", "users.email"},
+		{dto.CreateUserRequest{}, "password", "users.password"},
+		{dto.CreateUserRequest{}, "firstName", "users.first_name"},
+		{dto.CreateUserRequest{}, "lastName", "users.last_name"},
+		{dto.UpdateUserRequest{}, "email", "users.email"},
+		{dto.UpdateUserRequest{}, "firstName", "users.first_name"},
+		{dto.UpdateUserRequest{}, "lastName", "users.last_name"},
+		{dto.PatchUserDocument{}, "email", "users.email"},
+		{dto.PatchUserDocument{}, "firstName", "users.first_name"},
+		{dto.PatchUserDocument{}, "lastName", "users.last_name"},
+		{dto.CreateWebhookRequest{}, "url", "webhook_endpoints.url"},
+		{dto.CreateWebhookRequest{}, "description", "webhook_endpoints.description"},
+		{dto.CreateWebhookRequest{}, "secret", "webhook_endpoints.secret"},
+		{dto.UpdateWebhookRequest{}, "url", "webhook_endpoints.url"},
+		{dto.UpdateWebhookRequest{}, "description", "webhook_endpoints.description"},
+	}
+	for _, tt := range tests {
+		size, ok := columns[tt.column]
+		if !ok {
+			t.Errorf("%s: column not found", tt.column)
+			continue
+		}
+		if max := bindingMax(tt.dto, tt.field); max != size {
+			t.Errorf("%T.%s: max=%d, %s is VARCHAR(%d)", tt.dto, tt.field, max, tt.column, size)
+		}
+	}
+
+	// Usernames are bounded by the pattern, event filters by MaxWebhookEventLength
+	if pattern, _ := validation.Pattern("username_format"); !strings.Contains(pattern, "{3,"+strconv.Itoa(columns["users.username"])+"}") {
+		t.Errorf("username pattern %s does not stop at VARCHAR(%d)", pattern, columns["users.username"])
+	}
+	if validation.MaxWebhookEventLength != columns["webhook_deliveries.event_type"] {
+		t.Errorf("MaxWebhookEventLength is %d, webhook_deliveries.event_type is VARCHAR(%d)",
+			validation.MaxWebhookEventLength, columns["webhook_deliveries.event_type"])
+	}
+}
+
+/* TestTranslate checks binding errors become one validation error per JSON field */
+func TestTranslate(t *testing.T) {
+	validation.RegisterRules()
+
+	tests := []struct {
+		name string
+		body string
+		want map[string]string // field -> rule
+	}{
+		{
+			name: "missing and invalid fields",
+			body: `{"username":"x","email":"not-an-email","password":"123","firstName":"` + strings.Repeat("a", 51) + `"}`,
+			want: map[string]string{"username": "username_format", "email": "email", "password": "min", "firstName": "max"},
+		},
+		{
+			name: "required fields",
+			body: `{}`,
+			want: map[string]string{"username": "required", "email": "required", "password": "required"},
+		},
+		{
+			name: "wrong type",
+			body: `{"username":5}`,
+			want: map[string]string{"username": "type"},
+		},
+		{
+			name: "malformed JSON",
+			body: `{"username":`,
+			want: map[string]string{"request": "format"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var req dto.CreateUserRequest
+			err := binding.JSON.BindBody([]byte(tt.body), &req)
+			if err == nil {
+				t.Fatal("got no binding error")
+			}
+
+			validations := validation.Translate(err)
+			got := make(map[string]string, len(validations))
+			for _, v := range validations {
+				if _, seen := got[v.Field]; seen {
+					t.Errorf("field %s reported twice", v.Field)
+				}
+				got[v.Field] = v.Rule
+				if v.Message == "" || v.Key == "" {
+					t.Errorf("%s: got %+v, want a message and catalog key", v.Field, v)
+				}
+				if v.Field == "password" && v.Value != "" {
+					t.Errorf("password value %q was echoed back", v.Value)
+				}
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+/* varcharSizes returns the VARCHAR size of every table.column created by the script */
+func varcharSizes(sql string) map[string]int {
+	sizes := map[string]int{}
+	tables := regexp.MustCompile(`(?s)CREATE TABLE IF NOT EXISTS (\w+) \((.*?)\n\);`).FindAllStringSubmatch(sql, -1)
+	for _, table := range tables {
+		for _, column := range regexp.MustCompile(`(?m)^\s*(\w+) VARCHAR\((\d+)\)`).FindAllStringSubmatch(table[2], -1) {
+			sizes[table[1]+"."+column[1]], _ = strconv.Atoi(column[2])
+		}
+	}
+	return sizes
+}
+
+/* bindingMax returns the max= of the field with the given JSON name, or 0 */
+func bindingMax(v interface{}, jsonName string) int {
+	typ := reflect.TypeOf(v)
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		if strings.Split(field.Tag.Get("json"), ",")[0] != jsonName {
+			continue
+		}
+		for _, rule := range strings.Split(field.Tag.Get("binding"), ",") {
+			if strings.HasPrefix(rule, "max=") {
+				max, _ := strconv.Atoi(strings.TrimPrefix(rule, "max="))
+				return max
+			}
+		}
+	}
+	return 0
+}
@@ -0,0 +1,145 @@
+package examples
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"baseApi/models"
+	"baseApi/webhooks"
+)
+
+const testWebhookSecret = "whsec_test_secret_0123456789"
+
+/* newTestDelivery returns a delivery of a user.created event */
+func newTestDelivery() *models.WebhookDelivery {
+	return &models.WebhookDelivery{
+		ID:         42,
+		EndpointID: 1,
+		EventID:    "3f1c9a52-7a4e-4d0b-9f57-2d2b0b8e5c11",
+		EventType:  "user.created",
+		Payload:    []byte(`{"specversion":"1.0","id":"3f1c9a52-7a4e-4d0b-9f57-2d2b0b8e5c11","type":"com.baseapi.user.created","data":{"id":7}}`),
+		Attempts:   2,
+	}
+}
+
+/* TestWebhookIsSignedAndVerifiable delivers to a receiver that verifies the signature like a partner would */
+func TestWebhookIsSignedAndVerifiable(t *testing.T) {
+	delivery := newTestDelivery()
+
+	var verifyErr error
+	var headers http.Header
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		headers = r.Header.Clone()
+		verifyErr = webhooks.Verify(testWebhookSecret, r.Header.Get(webhooks.HeaderSignature), body, webhooks.DefaultTolerance, time.Now())
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer receiver.Close()
+
+	result := webhooks.NewSender(time.Second).Send(context.Background(), receiver.URL, testWebhookSecret, delivery)
+	if !result.OK() || result.StatusCode != http.StatusNoContent {
+		t.Fatalf("got status %d (%v), want 204", result.StatusCode, result.Err)
+	}
+	if verifyErr != nil {
+		t.Errorf("receiver could not verify the signature: %v", verifyErr)
+	}
+
+	want := map[string]string{
+		"Content-Type":           "application/cloudevents+json",
+		webhooks.HeaderEventID:   delivery.EventID,
+		webhooks.HeaderEventType: "user.created",
+		webhooks.HeaderDelivery:  "42",
+		webhooks.HeaderAttempt:   "3",
+	}
+	for header, value := range want {
+		if got := headers.Get(header); got != value {
+			t.Errorf("header %s = %q, want %q", header, got, value)
+		}
+	}
+}
+
+/* TestWebhookFailuresAreReported checks non-2xx answers, redirects and unreachable receivers fail the attempt */
+func TestWebhookFailuresAreReported(t *testing.T) {
+	sender := webhooks.NewSender(200 * time.Millisecond)
+
+	cases := map[string]http.HandlerFunc{
+		"server error": func(w http.ResponseWriter, r *http.Request) {
+			http.Error(w, "database is down", http.StatusInternalServerError)
+		},
+		"redirect": func(w http.ResponseWriter, r *http.Request) {
+			http.Redirect(w, r, "https://elsewhere.example.com/hook", http.StatusMovedPermanently)
+		},
+		"timeout": func(w http.ResponseWriter, r *http.Request) {
+			time.Sleep(500 * time.Millisecond)
+		},
+	}
+	for name, handler := range cases {
+		t.Run(name, func(t *testing.T) {
+			receiver := httptest.NewServer(handler)
+			defer receiver.Close()
+
+			result := sender.Send(context.Background(), receiver.URL, testWebhookSecret, newTestDelivery())
+			if result.OK() {
+				t.Fatalf("got a successful result for %s", name)
+			}
+			if name == "server error" && (result.StatusCode != 500 || !strings.Contains(result.Body, "database is down")) {
+				t.Errorf("got status %d body %q, want the receiver's answer logged", result.StatusCode, result.Body)
+			}
+		})
+	}
+
+	receiver := httptest.NewServer(http.NotFoundHandler())
+	receiver.Close()
+	if result := sender.Send(context.Background(), receiver.URL, testWebhookSecret, newTestDelivery()); result.OK() || result.StatusCode != 0 {
+		t.Errorf("got status %d (%v) for an unreachable receiver", result.StatusCode, result.Err)
+	}
+}
+
+/* TestWebhookSignatureRejectsTampering checks a modified body, wrong secret or stale timestamp fail verification */
+func TestWebhookSignatureRejectsTampering(t *testing.T) {
+	body := []byte(`{"id":"evt-1"}`)
+	now := time.Now()
+	header := webhooks.Sign(testWebhookSecret, now, body)
+
+	if err := webhooks.Verify(testWebhookSecret, header, body, webhooks.DefaultTolerance, now); err != nil {
+		t.Fatalf("valid signature rejected: %v", err)
+	}
+	if err := webhooks.Verify(testWebhookSecret, header, []byte(`{"id":"evt-2"}`), webhooks.DefaultTolerance, now); !errors.Is(err, webhooks.ErrSignatureMismatch) {
+		t.Errorf("tampered body: got %v, want ErrSignatureMismatch", err)
+	}
+	if err := webhooks.Verify("whsec_other_secret_000000", header, body, webhooks.DefaultTolerance, now); !errors.Is(err, webhooks.ErrSignatureMismatch) {
+		t.Errorf("wrong secret: got %v, want ErrSignatureMismatch", err)
+	}
+	if err := webhooks.Verify(testWebhookSecret, header, body, webhooks.DefaultTolerance, now.Add(10*time.Minute)); !errors.Is(err, webhooks.ErrSignatureExpired) {
+		t.Errorf("stale timestamp: got %v, want ErrSignatureExpired", err)
+	}
+	if err := webhooks.Verify(testWebhookSecret, "v1=abc", body, webhooks.DefaultTolerance, now); !errors.Is(err, webhooks.ErrInvalidSignatureHeader) {
+		t.Errorf("missing timestamp: got %v, want ErrInvalidSignatureHeader", err)
+	}
+}
+
+/* TestWebhookEventFiltersAndBackoff checks endpoint event filters and the retry schedule */
+func TestWebhookEventFiltersAndBackoff(t *testing.T) {
+	if !webhooks.Subscribed([]string{"user.deleted", "user.*"}, "user.created") {
+		t.Error("user.* should match user.created")
+	}
+	if webhooks.Subscribed([]string{"user.deleted"}, "user.created") {
+		t.Error("user.deleted should not match user.created")
+	}
+
+	want := []time.Duration{time.Minute, 2 * time.Minute, 4 * time.Minute, 8 * time.Minute}
+	for i, delay := range want {
+		if got := webhooks.Backoff(i + 1); got != delay {
+			t.Errorf("Backoff(%d) = %s, want %s", i+1, got, delay)
+		}
+	}
+	if got := webhooks.Backoff(20); got != 6*time.Hour {
+		t.Errorf("Backoff(20) = %s, want the 6h cap", got)
+	}
+}
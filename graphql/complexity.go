@@ -0,0 +1,528 @@
+package graphql
+
+import (
+	"encoding/json"
+	"errors"
+	"strconv"
+	"strings"
+)
+
+const (
+	// defaultListSize is the cost multiplier of users without a limit, its default page size
+	defaultListSize = 10
+	// maxCost saturates cost arithmetic so deeply repeated fragments can't overflow it
+	maxCost = 1 << 30
+)
+
+var errSyntax = errors.New("graphql: syntax error")
+
+/*
+complexity estimates the cost of an operation before it runs: every field costs 1,
+and the root list fields count their selections once per user they can return,
+users by its limit and usersByIds by its number of IDs.
+
+The query must already be valid; fragments are expanded once per spread, with
+their cost memoized so nesting them can't make the estimate itself expensive.
+*/
+func complexity(query, operationName string, variables map[string]interface{}) (int, error) {
+	doc, err := parseDocument(query)
+	if err != nil {
+		return 0, err
+	}
+
+	var operation []selection
+	for _, op := range doc.operations {
+		if operationName == "" || op.name == operationName {
+			operation = op.selections
+			break
+		}
+	}
+
+	estimator := &costEstimator{doc: doc, variables: variables, memo: make(map[string]int), visiting: make(map[string]bool)}
+	return estimator.cost(operation, true), nil
+}
+
+/* selection is a field, fragment spread or inline fragment, with only what cost estimation needs */
+type selection struct {
+	field    string
+	args     map[string]argument
+	spread   string
+	children []selection
+}
+
+/* argument is an argument value: a variable, an integer or a list */
+type argument struct {
+	variable string
+	number   int
+	items    int
+}
+
+type operation struct {
+	name       string
+	selections []selection
+}
+
+type document struct {
+	operations []operation
+	fragments  map[string][]selection
+}
+
+type costEstimator struct {
+	doc       *document
+	variables map[string]interface{}
+	memo      map[string]int
+	visiting  map[string]bool
+}
+
+func (e *costEstimator) cost(selections []selection, root bool) int {
+	total := 0
+	for _, sel := range selections {
+		switch {
+		case sel.spread != "":
+			total = saturate(total + e.fragmentCost(sel.spread, root))
+		case sel.field == "":
+			total = saturate(total + e.cost(sel.children, root))
+		default:
+			multiplier := 1
+			if root {
+				multiplier = e.listSize(sel)
+			}
+			total = saturate(total + 1 + saturate(multiplier*e.cost(sel.children, false)))
+		}
+	}
+	return total
+}
+
+func (e *costEstimator) fragmentCost(name string, root bool) int {
+	key := name + ":" + strconv.FormatBool(root)
+	if cost, ok := e.memo[key]; ok {
+		return cost
+	}
+	if e.visiting[key] {
+		return 0 // cycles are rejected by validation
+	}
+	e.visiting[key] = true
+	cost := e.cost(e.doc.fragments[name], root)
+	delete(e.visiting, key)
+	e.memo[key] = cost
+	return cost
+}
+
+/* listSize is how many users a root field can return */
+func (e *costEstimator) listSize(sel selection) int {
+	switch sel.field {
+	case "users":
+		size := defaultListSize
+		if limit, ok := e.intArgument(sel.args["limit"]); ok {
+			size = limit
+		}
+		if size < 1 {
+			size = defaultListSize
+		}
+		if size > maxBatchSize {
+			size = maxBatchSize
+		}
+		return size
+	case "usersByIds":
+		arg := sel.args["ids"]
+		if arg.variable != "" {
+			items, _ := e.variables[arg.variable].([]interface{})
+			return len(items)
+		}
+		return arg.items
+	}
+	return 1
+}
+
+func (e *costEstimator) intArgument(arg argument) (int, bool) {
+	if arg.variable == "" {
+		return arg.number, arg.number != 0
+	}
+	switch value := e.variables[arg.variable].(type) {
+	case float64:
+		return int(value), true
+	case int:
+		return value, true
+	case int32:
+		return int(value), true
+	case json.Number:
+		n, err := value.Int64()
+		return int(n), err == nil
+	}
+	return 0, false
+}
+
+func saturate(cost int) int {
+	if cost > maxCost || cost < 0 {
+		return maxCost
+	}
+	return cost
+}
+
+/* parser reads the parts of an executable document that affect its cost */
+type parser struct {
+	lexer lexer
+	token token
+}
+
+func parseDocument(query string) (*document, error) {
+	p := &parser{lexer: lexer{input: query}}
+	doc := &document{fragments: make(map[string][]selection)}
+	if err := p.next(); err != nil {
+		return nil, err
+	}
+
+	for p.token.kind != tokenEOF {
+		switch {
+		case p.is("{"):
+			selections, err := p.selectionSet()
+			if err != nil {
+				return nil, err
+			}
+			doc.operations = append(doc.operations, operation{selections: selections})
+		case p.token.kind == tokenName && p.token.value == "fragment":
+			name, selections, err := p.fragment()
+			if err != nil {
+				return nil, err
+			}
+			doc.fragments[name] = selections
+		case p.token.kind == tokenName:
+			op, err := p.operation()
+			if err != nil {
+				return nil, err
+			}
+			doc.operations = append(doc.operations, op)
+		default:
+			return nil, errSyntax
+		}
+	}
+	return doc, nil
+}
+
+/* operation reads "query|mutation|subscription [Name] [($vars)] [@directives] { ... }" */
+func (p *parser) operation() (operation, error) {
+	var op operation
+	if err := p.next(); err != nil {
+		return op, err
+	}
+	if p.token.kind == tokenName {
+		op.name = p.token.value
+		if err := p.next(); err != nil {
+			return op, err
+		}
+	}
+	if p.is("(") {
+		if err := p.skipBalanced("(", ")"); err != nil {
+			return op, err
+		}
+	}
+	if err := p.directives(); err != nil {
+		return op, err
+	}
+	selections, err := p.selectionSet()
+	op.selections = selections
+	return op, err
+}
+
+/* fragment reads "fragment Name on Type [@directives] { ... }" */
+func (p *parser) fragment() (string, []selection, error) {
+	if err := p.next(); err != nil {
+		return "", nil, err
+	}
+	name := p.token.value
+	for i := 0; i < 3; i++ { // name, "on", type condition
+		if p.token.kind != tokenName {
+			return "", nil, errSyntax
+		}
+		if err := p.next(); err != nil {
+			return "", nil, err
+		}
+	}
+	if err := p.directives(); err != nil {
+		return "", nil, err
+	}
+	selections, err := p.selectionSet()
+	return name, selections, err
+}
+
+func (p *parser) selectionSet() ([]selection, error) {
+	if err := p.expect("{"); err != nil {
+		return nil, err
+	}
+
+	var selections []selection
+	for !p.is("}") {
+		sel, err := p.selection()
+		if err != nil {
+			return nil, err
+		}
+		selections = append(selections, sel)
+	}
+	return selections, p.next()
+}
+
+func (p *parser) selection() (selection, error) {
+	var sel selection
+	if p.is("...") {
+		if err := p.next(); err != nil {
+			return sel, err
+		}
+		if p.token.kind == tokenName && p.token.value != "on" {
+			sel.spread = p.token.value
+			if err := p.next(); err != nil {
+				return sel, err
+			}
+			return sel, p.directives()
+		}
+		if p.token.kind == tokenName { // "on Type"
+			if err := p.next(); err != nil {
+				return sel, err
+			}
+			if err := p.next(); err != nil {
+				return sel, err
+			}
+		}
+		if err := p.directives(); err != nil {
+			return sel, err
+		}
+		children, err := p.selectionSet()
+		sel.children = children
+		return sel, err
+	}
+
+	if p.token.kind != tokenName {
+		return sel, errSyntax
+	}
+	sel.field = p.token.value
+	if err := p.next(); err != nil {
+		return sel, err
+	}
+	if p.is(":") { // the alias was read, then the field name
+		if err := p.next(); err != nil {
+			return sel, err
+		}
+		if p.token.kind != tokenName {
+			return sel, errSyntax
+		}
+		sel.field = p.token.value
+		if err := p.next(); err != nil {
+			return sel, err
+		}
+	}
+
+	if p.is("(") {
+		args, err := p.arguments()
+		if err != nil {
+			return sel, err
+		}
+		sel.args = args
+	}
+	if err := p.directives(); err != nil {
+		return sel, err
+	}
+	if p.is("{") {
+		children, err := p.selectionSet()
+		if err != nil {
+			return sel, err
+		}
+		sel.children = children
+	}
+	return sel, nil
+}
+
+func (p *parser) arguments() (map[string]argument, error) {
+	args := make(map[string]argument)
+	if err := p.next(); err != nil {
+		return nil, err
+	}
+	for !p.is(")") {
+		if p.token.kind != tokenName {
+			return nil, errSyntax
+		}
+		name := p.token.value
+		if err := p.next(); err != nil {
+			return nil, err
+		}
+		if err := p.expect(":"); err != nil {
+			return nil, err
+		}
+		value, err := p.value()
+		if err != nil {
+			return nil, err
+		}
+		args[name] = value
+	}
+	return args, p.next()
+}
+
+/* value reads any value, keeping variables, integers and list lengths */
+func (p *parser) value() (argument, error) {
+	var arg argument
+	switch {
+	case p.is("$"):
+		if err := p.next(); err != nil {
+			return arg, err
+		}
+		arg.variable = p.token.value
+	case p.is("["):
+		if err := p.next(); err != nil {
+			return arg, err
+		}
+		for !p.is("]") {
+			if _, err := p.value(); err != nil {
+				return arg, err
+			}
+			arg.items++
+		}
+	case p.is("{"):
+		return arg, p.skipBalanced("{", "}")
+	case p.token.kind == tokenNumber:
+		arg.number, _ = strconv.Atoi(p.token.value)
+	case p.token.kind == tokenName, p.token.kind == tokenString:
+	default:
+		return arg, errSyntax
+	}
+	return arg, p.next()
+}
+
+func (p *parser) directives() error {
+	for p.is("@") {
+		if err := p.next(); err != nil {
+			return err
+		}
+		if err := p.next(); err != nil { // directive name
+			return err
+		}
+		if p.is("(") {
+			if _, err := p.arguments(); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+/* skipBalanced skips from an open punctuator to its matching close */
+func (p *parser) skipBalanced(open, close string) error {
+	depth := 0
+	for {
+		switch {
+		case p.token.kind == tokenEOF:
+			return errSyntax
+		case p.is(open):
+			depth++
+		case p.is(close):
+			depth--
+		}
+		if err := p.next(); err != nil {
+			return err
+		}
+		if depth == 0 {
+			return nil
+		}
+	}
+}
+
+func (p *parser) is(punctuator string) bool {
+	return p.token.kind == tokenPunctuator && p.token.value == punctuator
+}
+
+func (p *parser) expect(punctuator string) error {
+	if !p.is(punctuator) {
+		return errSyntax
+	}
+	return p.next()
+}
+
+func (p *parser) next() error {
+	token, err := p.lexer.next()
+	p.token = token
+	return err
+}
+
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenPunctuator
+	tokenName
+	tokenNumber
+	tokenString
+)
+
+type token struct {
+	kind  tokenKind
+	value string
+}
+
+/* lexer splits a GraphQL document into tokens, skipping whitespace, commas and comments */
+type lexer struct {
+	input string
+	pos   int
+}
+
+func (l *lexer) next() (token, error) {
+	for l.pos < len(l.input) {
+		switch c := l.input[l.pos]; {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == ',':
+			l.pos++
+		case c == '#':
+			for l.pos < len(l.input) && l.input[l.pos] != '\n' && l.input[l.pos] != '\r' {
+				l.pos++
+			}
+		case strings.HasPrefix(l.input[l.pos:], "\uFEFF"):
+			l.pos += len("\uFEFF")
+		default:
+			return l.token()
+		}
+	}
+	return token{kind: tokenEOF}, nil
+}
+
+func (l *lexer) token() (token, error) {
+	start := l.pos
+	c := l.input[l.pos]
+	switch {
+	case strings.HasPrefix(l.input[l.pos:], "..."):
+		l.pos += 3
+		return token{kind: tokenPunctuator, value: "..."}, nil
+	case strings.IndexByte("!$&()=:@[]{}|", c) >= 0:
+		l.pos++
+		return token{kind: tokenPunctuator, value: string(c)}, nil
+	case c == '_' || isLetter(c):
+		for l.pos < len(l.input) && (l.input[l.pos] == '_' || isLetter(l.input[l.pos]) || isDigit(l.input[l.pos])) {
+			l.pos++
+		}
+		return token{kind: tokenName, value: l.input[start:l.pos]}, nil
+	case c == '-' || isDigit(c):
+		l.pos++
+		for l.pos < len(l.input) && (isDigit(l.input[l.pos]) || strings.IndexByte(".eE+-", l.input[l.pos]) >= 0) {
+			l.pos++
+		}
+		return token{kind: tokenNumber, value: l.input[start:l.pos]}, nil
+	case strings.HasPrefix(l.input[l.pos:], `"""`):
+		end := strings.Index(strings.ReplaceAll(l.input[l.pos+3:], `\"""`, "____"), `"""`)
+		if end < 0 {
+			return token{}, errSyntax
+		}
+		l.pos += 3 + end + 3
+		return token{kind: tokenString}, nil
+	case c == '"':
+		for l.pos++; l.pos < len(l.input); l.pos++ {
+			switch l.input[l.pos] {
+			case '\\':
+				l.pos++
+			case '"':
+				l.pos++
+				return token{kind: tokenString}, nil
+			case '\n', '\r':
+				return token{}, errSyntax
+			}
+		}
+		return token{}, errSyntax
+	}
+	return token{}, errSyntax
+}
+
+func isLetter(c byte) bool { return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') }
+func isDigit(c byte) bool  { return c >= '0' && c <= '9' }
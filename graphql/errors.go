@@ -0,0 +1,103 @@
+package graphql
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"baseApi/apperror"
+	"baseApi/i18n"
+	"baseApi/logger"
+	"baseApi/monitoring"
+
+	qerrors "github.com/graph-gophers/graphql-go/errors"
+)
+
+type requestKey struct{}
+
+/* requestInfo is what resolvers know about the HTTP request */
+type requestInfo struct {
+	locale    string
+	requestID string
+}
+
+/* WithRequest returns a context carrying the negotiated locale and the request ID for error messages */
+func WithRequest(ctx context.Context, locale, requestID string) context.Context {
+	return context.WithValue(ctx, requestKey{}, requestInfo{locale: locale, requestID: requestID})
+}
+
+func request(ctx context.Context) requestInfo {
+	info, ok := ctx.Value(requestKey{}).(requestInfo)
+	if !ok {
+		info.locale = i18n.DefaultLocale
+	}
+	return info
+}
+
+/* resolverError is an error in the "errors" list, with the application error code in its extensions */
+type resolverError struct {
+	message    string
+	extensions map[string]interface{}
+}
+
+func (e *resolverError) Error() string                      { return e.message }
+func (e *resolverError) Extensions() map[string]interface{} { return e.extensions }
+
+/* toQueryError returns the error as graphql-go reports it; subscription resolvers must return it to keep the extensions */
+func (e *resolverError) toQueryError() *qerrors.QueryError {
+	return &qerrors.QueryError{Message: e.message, Extensions: e.extensions, ResolverError: e}
+}
+
+/*
+queryError converts a service error for the GraphQL response, localized like the
+HTTP errors.
+
+extensions.code is the APIResponse error code and extensions.validations the
+field failures; internal causes are logged, never sent to the client.
+*/
+func queryError(ctx context.Context, err error) error {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return err
+	}
+	return newResolverError(ctx, err)
+}
+
+func newResolverError(ctx context.Context, err error) *resolverError {
+	appErr := apperror.From(err)
+	if appErr.Kind == apperror.KindInternal {
+		logger.Error("GraphQL request failed:", err)
+	}
+	info := request(ctx)
+	appErr = appErr.Localize(info.locale)
+
+	extensions := map[string]interface{}{"code": appErr.Code}
+	if len(appErr.Validations) > 0 {
+		extensions["validations"] = appErr.Validations
+	}
+	if info.requestID != "" {
+		extensions["requestId"] = info.requestID
+	}
+	return &resolverError{message: appErr.Message, extensions: extensions}
+}
+
+/* panicHandler reports resolver panics to Sentry and answers with a generic internal error */
+type panicHandler struct{}
+
+func (panicHandler) MakePanicError(ctx context.Context, value interface{}) *qerrors.QueryError {
+	err, ok := value.(error)
+	if !ok {
+		err = fmt.Errorf("%v", value)
+	}
+	monitoring.CaptureError(err, map[string]interface{}{
+		"panic":      true,
+		"graphql":    true,
+		"request_id": request(ctx).requestID,
+	})
+
+	return newResolverError(ctx, fmt.Errorf("panic in resolver: %w", err)).toQueryError()
+}
+
+/* panicLogger stops graphql-go from printing panics itself; panicHandler logs them */
+type panicLogger struct{}
+
+func (panicLogger) LogPanic(context.Context, interface{}) {}
@@ -0,0 +1,100 @@
+package graphql
+
+import (
+	"context"
+	"strconv"
+
+	"baseApi/apperror"
+	"baseApi/dto"
+
+	"github.com/graph-gophers/dataloader"
+)
+
+// maxBatchSize matches the largest page of GET /v1/users
+const maxBatchSize = 100
+
+type loaderKey struct{}
+
+/* UserFetcher loads users by ID; users that don't exist are missing from the result */
+type UserFetcher func(ids []uint) (map[uint]*dto.UserResponse, error)
+
+/*
+UserLoader batches the user lookups of one request: the IDs asked for while
+resolvers run in parallel are fetched together, and each user once.
+
+It is created per request so its cache never outlives the request.
+*/
+type UserLoader struct {
+	loader *dataloader.Loader
+}
+
+/* NewUserLoader creates a loader fetching its batches with fetch */
+func NewUserLoader(fetch UserFetcher) *UserLoader {
+	batch := func(ctx context.Context, keys dataloader.Keys) []*dataloader.Result {
+		ids := make([]uint, len(keys))
+		for i, key := range keys {
+			ids[i] = key.Raw().(uint)
+		}
+
+		results := make([]*dataloader.Result, len(keys))
+		users, err := fetch(ids)
+		for i, id := range ids {
+			switch user, ok := users[id]; {
+			case err != nil:
+				results[i] = &dataloader.Result{Error: err}
+			case ok:
+				results[i] = &dataloader.Result{Data: user}
+			default:
+				results[i] = &dataloader.Result{Error: apperror.NotFound("User")}
+			}
+		}
+		return results
+	}
+	return &UserLoader{loader: dataloader.NewBatchedLoader(batch, dataloader.WithBatchCapacity(maxBatchSize))}
+}
+
+/* WithUserLoader returns a context whose requests load users through loader */
+func WithUserLoader(ctx context.Context, loader *UserLoader) context.Context {
+	return context.WithValue(ctx, loaderKey{}, loader)
+}
+
+func userLoader(ctx context.Context) *UserLoader {
+	loader, _ := ctx.Value(loaderKey{}).(*UserLoader)
+	return loader
+}
+
+/* Load returns a user by ID, or a NotFound error */
+func (l *UserLoader) Load(ctx context.Context, id uint) (*dto.UserResponse, error) {
+	value, err := l.loader.Load(ctx, userKey(id))()
+	if err != nil {
+		return nil, err
+	}
+	return value.(*dto.UserResponse), nil
+}
+
+/* LoadMany returns users by ID in the same order, nil where there is none */
+func (l *UserLoader) LoadMany(ctx context.Context, ids []uint) ([]*dto.UserResponse, error) {
+	keys := make(dataloader.Keys, len(ids))
+	for i, id := range ids {
+		keys[i] = userKey(id)
+	}
+
+	values, errs := l.loader.LoadMany(ctx, keys)()
+	users := make([]*dto.UserResponse, len(ids))
+	for i, value := range values {
+		if i < len(errs) && errs[i] != nil {
+			if apperror.IsKind(errs[i], apperror.KindNotFound) {
+				continue
+			}
+			return nil, errs[i]
+		}
+		users[i], _ = value.(*dto.UserResponse)
+	}
+	return users, nil
+}
+
+/* userKey is a dataloader key for a user ID */
+type userKey uint
+
+func (k userKey) String() string   { return strconv.FormatUint(uint64(k), 10) }
+func (k userKey) Raw() interface{} { return uint(k) }
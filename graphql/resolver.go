@@ -0,0 +1,281 @@
+package graphql
+
+import (
+	"context"
+	"strconv"
+
+	"baseApi/apperror"
+	"baseApi/dto"
+	"baseApi/services"
+	"baseApi/validation"
+
+	"github.com/gin-gonic/gin/binding"
+	graphql "github.com/graph-gophers/graphql-go"
+)
+
+/* Resolver resolves the root fields of schema.graphql through the same services as the HTTP API */
+type Resolver struct {
+	userService   *services.UserService
+	streamService *services.UserStreamService
+	streamEnabled bool
+}
+
+/* User returns a user by ID through the request's loader */
+func (r *Resolver) User(ctx context.Context, args struct{ ID graphql.ID }) (*userResolver, error) {
+	id, err := parseID(args.ID)
+	if err != nil {
+		return nil, queryError(ctx, err)
+	}
+
+	user, err := userLoader(ctx).Load(ctx, id)
+	if apperror.IsKind(err, apperror.KindNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, queryError(ctx, err)
+	}
+	return &userResolver{user: user}, nil
+}
+
+/* UsersByIds returns users by ID in one batch with the request's other lookups */
+func (r *Resolver) UsersByIds(ctx context.Context, args struct{ IDs []graphql.ID }) ([]*userResolver, error) {
+	if len(args.IDs) > maxBatchSize {
+		return nil, queryError(ctx, apperror.Validation([]dto.ValidationError{{
+			Field:   "ids",
+			Rule:    "max",
+			Message: "ids must be at most " + strconv.Itoa(maxBatchSize),
+			Key:     "validation.max",
+			Params:  map[string]string{"field": "ids", "param": strconv.Itoa(maxBatchSize)},
+		}}))
+	}
+
+	ids := make([]uint, len(args.IDs))
+	for i, value := range args.IDs {
+		id, err := parseID(value)
+		if err != nil {
+			return nil, queryError(ctx, err)
+		}
+		ids[i] = id
+	}
+
+	users, err := userLoader(ctx).LoadMany(ctx, ids)
+	if err != nil {
+		return nil, queryError(ctx, err)
+	}
+	resolvers := make([]*userResolver, len(users))
+	for i, user := range users {
+		if user != nil {
+			resolvers[i] = &userResolver{user: user}
+		}
+	}
+	return resolvers, nil
+}
+
+/* UserByUsername returns a user by username */
+func (r *Resolver) UserByUsername(ctx context.Context, args struct{ Username string }) (*userResolver, error) {
+	user, err := r.userService.GetUserByUsername(args.Username)
+	if apperror.IsKind(err, apperror.KindNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, queryError(ctx, err)
+	}
+	return &userResolver{user: user}, nil
+}
+
+type usersArgs struct {
+	Query    *string
+	Page     *int32
+	Limit    *int32
+	SortBy   *string
+	SortDesc *bool
+	IsActive *bool
+}
+
+/* Users returns a page of users with the filters and defaults of GET /v1/users */
+func (r *Resolver) Users(ctx context.Context, args usersArgs) (*userPageResolver, error) {
+	search := dto.UserSearchRequest{IsActive: args.IsActive}
+	if args.Query != nil {
+		search.Query = *args.Query
+	}
+	if args.Page != nil {
+		search.Page = int(*args.Page)
+	}
+	if args.Limit != nil {
+		search.Limit = int(*args.Limit)
+	}
+	if args.SortBy != nil {
+		search.SortBy = *args.SortBy
+	}
+	if args.SortDesc != nil {
+		search.SortDesc = *args.SortDesc
+	}
+	if err := validate(&search); err != nil {
+		return nil, queryError(ctx, err)
+	}
+
+	list, err := r.userService.GetAllUsers(search)
+	if err != nil {
+		return nil, queryError(ctx, err)
+	}
+	return &userPageResolver{list: list}, nil
+}
+
+type createUserArgs struct {
+	Input struct {
+		Username  string
+		Email     string
+		Password  string
+		FirstName *string
+		LastName  *string
+	}
+}
+
+/* CreateUser creates a user with the rules of POST /v1/users */
+func (r *Resolver) CreateUser(ctx context.Context, args createUserArgs) (*userResolver, error) {
+	create := dto.CreateUserRequest{
+		Username:  args.Input.Username,
+		Email:     args.Input.Email,
+		Password:  args.Input.Password,
+		FirstName: stringValue(args.Input.FirstName),
+		LastName:  stringValue(args.Input.LastName),
+	}
+	if err := validate(&create); err != nil {
+		return nil, queryError(ctx, err)
+	}
+
+	user, err := r.userService.CreateUser(create)
+	if err != nil {
+		return nil, queryError(ctx, err)
+	}
+	return &userResolver{user: user}, nil
+}
+
+type updateUserArgs struct {
+	ID    graphql.ID
+	Input struct {
+		Username  *string
+		Email     *string
+		FirstName *string
+		LastName  *string
+		IsActive  *bool
+	}
+	IfMatch *int32
+}
+
+/* UpdateUser changes the given fields with the rules of PUT /v1/users/:id */
+func (r *Resolver) UpdateUser(ctx context.Context, args updateUserArgs) (*userResolver, error) {
+	id, err := parseID(args.ID)
+	if err != nil {
+		return nil, queryError(ctx, err)
+	}
+	update := dto.UpdateUserRequest{
+		Username:  stringValue(args.Input.Username),
+		Email:     stringValue(args.Input.Email),
+		FirstName: stringValue(args.Input.FirstName),
+		LastName:  stringValue(args.Input.LastName),
+		IsActive:  args.Input.IsActive,
+	}
+	if err := validate(&update); err != nil {
+		return nil, queryError(ctx, err)
+	}
+
+	user, err := r.userService.UpdateUser(id, update, ifMatch(args.IfMatch)...)
+	if err != nil {
+		return nil, queryError(ctx, err)
+	}
+	return &userResolver{user: user}, nil
+}
+
+/* DeleteUser soft deletes a user */
+func (r *Resolver) DeleteUser(ctx context.Context, args struct {
+	ID      graphql.ID
+	IfMatch *int32
+}) (graphql.ID, error) {
+	id, err := parseID(args.ID)
+	if err != nil {
+		return "", queryError(ctx, err)
+	}
+
+	if err := r.userService.DeleteUser(id, ifMatch(args.IfMatch)...); err != nil {
+		return "", queryError(ctx, err)
+	}
+	return args.ID, nil
+}
+
+type userResolver struct {
+	user *dto.UserResponse
+}
+
+func (r *userResolver) ID() graphql.ID          { return userID(r.user.ID) }
+func (r *userResolver) Username() string        { return r.user.Username }
+func (r *userResolver) Email() string           { return r.user.Email }
+func (r *userResolver) FirstName() string       { return r.user.FirstName }
+func (r *userResolver) LastName() string        { return r.user.LastName }
+func (r *userResolver) IsActive() bool          { return r.user.IsActive }
+func (r *userResolver) Version() int32          { return int32(r.user.Version) }
+func (r *userResolver) CreatedAt() graphql.Time { return graphql.Time{Time: r.user.CreatedAt} }
+func (r *userResolver) UpdatedAt() graphql.Time { return graphql.Time{Time: r.user.UpdatedAt} }
+
+type userPageResolver struct {
+	list *dto.UserListResponse
+}
+
+func (r *userPageResolver) Users() []*userResolver {
+	users := make([]*userResolver, len(r.list.Users))
+	for i := range r.list.Users {
+		users[i] = &userResolver{user: &r.list.Users[i]}
+	}
+	return users
+}
+
+func (r *userPageResolver) Pagination() *paginationResolver {
+	return &paginationResolver{meta: r.list.Pagination}
+}
+
+type paginationResolver struct {
+	meta dto.PaginationMeta
+}
+
+func (r *paginationResolver) CurrentPage() int32 { return int32(r.meta.CurrentPage) }
+func (r *paginationResolver) PerPage() int32     { return int32(r.meta.PerPage) }
+func (r *paginationResolver) TotalPages() int32  { return int32(r.meta.TotalPages) }
+func (r *paginationResolver) TotalItems() int32  { return int32(r.meta.TotalItems) }
+func (r *paginationResolver) HasNextPage() bool  { return r.meta.HasNextPage }
+func (r *paginationResolver) HasPrevPage() bool  { return r.meta.HasPrevPage }
+
+/* parseID parses a user ID like the :id path parameter */
+func parseID(id graphql.ID) (uint, error) {
+	value, err := strconv.ParseUint(string(id), 10, 32)
+	if err != nil || value == 0 {
+		return 0, apperror.BadRequest("Invalid user ID format").WithKey("error.invalid_user_id", nil)
+	}
+	return uint(value), nil
+}
+
+func userID(id uint) graphql.ID {
+	return graphql.ID(strconv.FormatUint(uint64(id), 10))
+}
+
+/* ifMatch is the If-Match version list of the service calls */
+func ifMatch(version *int32) []uint {
+	if version == nil {
+		return nil
+	}
+	return []uint{uint(*version)}
+}
+
+func stringValue(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
+
+/* validate applies the DTO's binding rules, as ShouldBindJSON does for HTTP requests */
+func validate(req interface{}) error {
+	if err := binding.Validator.ValidateStruct(req); err != nil {
+		return apperror.Validation(validation.Translate(err))
+	}
+	return nil
+}
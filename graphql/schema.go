@@ -0,0 +1,121 @@
+package graphql
+
+import (
+	"context"
+	_ "embed"
+	"strconv"
+
+	"baseApi/apperror"
+	"baseApi/config"
+	"baseApi/services"
+	"baseApi/validation"
+
+	graphql "github.com/graph-gophers/graphql-go"
+	qerrors "github.com/graph-gophers/graphql-go/errors"
+)
+
+//go:embed schema.graphql
+var schemaSDL string
+
+/* Request is a GraphQL request as POSTed to /graphql and sent in graphql-transport-ws subscribe messages */
+type Request struct {
+	Query         string                 `json:"query"`
+	OperationName string                 `json:"operationName"`
+	Variables     map[string]interface{} `json:"variables"`
+}
+
+/*
+Schema serves schema.graphql with the configured depth and complexity limits.
+
+Each request gets its own UserLoader unless the context already has one, so
+user lookups are batched within a request and never cached across requests.
+*/
+type Schema struct {
+	schema        *graphql.Schema
+	userService   *services.UserService
+	maxComplexity int
+}
+
+/* NewSchema parses the schema and binds it to the user services */
+func NewSchema(cfg *config.Config) (*Schema, error) {
+	validation.RegisterRules()
+	userService := services.NewUserService()
+	resolver := &Resolver{
+		userService:   userService,
+		streamService: services.NewUserStreamService(cfg.UserStreamReplayLimit),
+		streamEnabled: cfg.UserStreamEnabled,
+	}
+
+	schema, err := graphql.ParseSchema(schemaSDL, resolver,
+		graphql.UseStringDescriptions(),
+		graphql.MaxDepth(cfg.GraphQLMaxDepth),
+		graphql.PanicHandler(panicHandler{}),
+		graphql.Logger(panicLogger{}),
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &Schema{schema: schema, userService: userService, maxComplexity: cfg.GraphQLMaxComplexity}, nil
+}
+
+/* Exec runs a query or mutation */
+func (s *Schema) Exec(ctx context.Context, req Request) *graphql.Response {
+	if errs := s.check(ctx, req); len(errs) > 0 {
+		return &graphql.Response{Errors: errs}
+	}
+	return s.schema.Exec(s.withLoader(ctx), req.Query, req.OperationName, req.Variables)
+}
+
+/* Subscribe runs a subscription, sending a *graphql.Response per event until ctx ends or the stream does */
+func (s *Schema) Subscribe(ctx context.Context, req Request) <-chan interface{} {
+	if errs := s.check(ctx, req); len(errs) > 0 {
+		return closedResponse(&graphql.Response{Errors: errs})
+	}
+	responses, err := s.schema.Subscribe(s.withLoader(ctx), req.Query, req.OperationName, req.Variables)
+	if err != nil {
+		return closedResponse(&graphql.Response{Errors: []*qerrors.QueryError{qerrors.Errorf("%s", err)}})
+	}
+	return responses
+}
+
+/* check validates the request and rejects it when its estimated cost is over the limit */
+func (s *Schema) check(ctx context.Context, req Request) []*qerrors.QueryError {
+	if errs := s.schema.ValidateWithVariables(req.Query, req.Variables); len(errs) > 0 {
+		return errs
+	}
+	if s.maxComplexity <= 0 {
+		return nil
+	}
+
+	var err *apperror.Error
+	cost, costErr := complexity(req.Query, req.OperationName, req.Variables)
+	switch {
+	case costErr != nil:
+		err = apperror.BadRequest("Query complexity could not be estimated").WithKey("error.query_complexity_unknown", nil)
+	case cost > s.maxComplexity:
+		err = apperror.BadRequest("Query is too complex").WithKey("error.query_too_complex", map[string]string{
+			"cost":  strconv.Itoa(cost),
+			"limit": strconv.Itoa(s.maxComplexity),
+		})
+	default:
+		return nil
+	}
+
+	queryErr := newResolverError(ctx, err).toQueryError()
+	queryErr.Rule = "MaxComplexityExceeded"
+	return []*qerrors.QueryError{queryErr}
+}
+
+func (s *Schema) withLoader(ctx context.Context) context.Context {
+	if userLoader(ctx) != nil {
+		return ctx
+	}
+	return WithUserLoader(ctx, NewUserLoader(s.userService.GetUsersByIDs))
+}
+
+func closedResponse(response *graphql.Response) <-chan interface{} {
+	responses := make(chan interface{}, 1)
+	responses <- response
+	close(responses)
+	return responses
+}
@@ -0,0 +1,132 @@
+package graphql
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+
+	"baseApi/apperror"
+	"baseApi/dto"
+	"baseApi/messaging"
+	"baseApi/services"
+	"baseApi/streaming"
+
+	graphql "github.com/graph-gophers/graphql-go"
+)
+
+// maxLastEventIDLength bounds lastEventId like Last-Event-ID; CloudEvent ids are UUIDs
+const maxLastEventIDLength = 64
+
+// subscriptionFields maps stream filter parameters to userChanged arguments
+var subscriptionFields = map[string]string{"events": "events", "userId": "userIds"}
+
+type userChangedArgs struct {
+	Events      *[]string
+	UserIds     *[]graphql.ID
+	LastEventId *string
+}
+
+/*
+UserChanged streams user changes from the process-wide hub, the events of the SSE
+and WebSocket streams, replaying the ones after lastEventId first.
+
+The subscription ends when the client completes it or falls too far behind.
+Errors are returned as QueryErrors, the only kind graphql-go subscriptions keep
+extensions for.
+*/
+func (r *Resolver) UserChanged(ctx context.Context, args userChangedArgs) (<-chan *userEventResolver, error) {
+	if !r.streamEnabled {
+		return nil, newResolverError(ctx, services.ErrUserStreamDisabled).toQueryError()
+	}
+
+	var events, userIDs []string
+	if args.Events != nil {
+		events = *args.Events
+	}
+	if args.UserIds != nil {
+		for _, id := range *args.UserIds {
+			userIDs = append(userIDs, string(id))
+		}
+	}
+	filter, err := streaming.ParseFilter(strings.Join(events, ","), strings.Join(userIDs, ","))
+	var filterErr *streaming.FilterError
+	if errors.As(err, &filterErr) {
+		field := subscriptionFields[filterErr.Field]
+		return nil, newResolverError(ctx, apperror.Validation([]dto.ValidationError{{
+			Field:   field,
+			Rule:    "invalid",
+			Message: filterErr.Error(),
+			Value:   filterErr.Value,
+			Key:     "validation.invalid",
+			Params:  map[string]string{"field": field},
+		}})).toQueryError()
+	}
+
+	lastEventID := stringValue(args.LastEventId)
+	if len(lastEventID) > maxLastEventIDLength {
+		return nil, newResolverError(ctx, apperror.BadRequest("Invalid Last-Event-ID").WithKey("error.invalid_last_event_id", nil)).toQueryError()
+	}
+
+	changes := make(chan *userEventResolver)
+	go func() {
+		defer close(changes)
+		send := func(e streaming.Event) error {
+			select {
+			case changes <- newUserEvent(e):
+				return nil
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+		r.streamService.Serve(ctx, streaming.GetHub(), filter, lastEventID, 0, send, nil)
+	}()
+	return changes, nil
+}
+
+type userEventResolver struct {
+	id          string
+	eventType   string
+	userID      *graphql.ID
+	user        *userResolver
+	time        *graphql.Time
+	resetReason *string
+}
+
+func (r *userEventResolver) ID() string           { return r.id }
+func (r *userEventResolver) Type() string         { return r.eventType }
+func (r *userEventResolver) UserId() *graphql.ID  { return r.userID }
+func (r *userEventResolver) User() *userResolver  { return r.user }
+func (r *userEventResolver) Time() *graphql.Time  { return r.time }
+func (r *userEventResolver) ResetReason() *string { return r.resetReason }
+
+/* newUserEvent converts a stream event; the CloudEvent data is the user DTO */
+func newUserEvent(e streaming.Event) *userEventResolver {
+	event := &userEventResolver{id: e.ID, eventType: e.Event}
+	if e.Event == streaming.EventReset {
+		var data map[string]string
+		json.Unmarshal(e.Data, &data)
+		reason := data["reason"]
+		event.resetReason = &reason
+		return event
+	}
+
+	id := userID(e.UserID)
+	event.userID = &id
+	var cloudEvent messaging.CloudEvent
+	if json.Unmarshal(e.Data, &cloudEvent) != nil {
+		return event
+	}
+	event.time = &graphql.Time{Time: cloudEvent.Time}
+
+	// Deleted events carry only the id
+	if e.Event == messaging.UserEventRoutingKey(services.UserEventDeleted) {
+		event.user = &userResolver{user: &dto.UserResponse{ID: e.UserID}}
+		return event
+	}
+	var user dto.UserResponse
+	if json.Unmarshal(cloudEvent.Data, &user) == nil {
+		event.user = &userResolver{user: &user}
+	}
+	return event
+}
@@ -0,0 +1,133 @@
+package grpc
+
+import (
+	"context"
+	"errors"
+
+	"baseApi/apperror"
+	"baseApi/dto"
+	"baseApi/i18n"
+	"baseApi/logger"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/anypb"
+)
+
+// errorDomain identifies this API in google.rpc.ErrorInfo details
+const errorDomain = "baseApi"
+
+// codesByKind maps application error kinds to gRPC status codes
+var codesByKind = map[apperror.Kind]codes.Code{
+	apperror.KindBadRequest:           codes.InvalidArgument,
+	apperror.KindValidation:           codes.InvalidArgument,
+	apperror.KindUnprocessable:        codes.InvalidArgument,
+	apperror.KindUnsupportedMedia:     codes.InvalidArgument,
+	apperror.KindUnauthorized:         codes.Unauthenticated,
+	apperror.KindForbidden:            codes.PermissionDenied,
+	apperror.KindNotFound:             codes.NotFound,
+	apperror.KindAlreadyExists:        codes.AlreadyExists,
+	apperror.KindConflict:             codes.Aborted,
+	apperror.KindPreconditionFailed:   codes.FailedPrecondition,
+	apperror.KindPreconditionRequired: codes.FailedPrecondition,
+	apperror.KindInternal:             codes.Internal,
+}
+
+/*
+ErrorStatus converts a service error into a gRPC status, localized like the HTTP errors.
+
+The application error code travels in a google.rpc.ErrorInfo detail (reason) and
+validation failures in a google.rpc.BadRequest detail, one violation per field.
+Internal causes are logged, never sent to the client.
+*/
+func ErrorStatus(err error, locale string) *status.Status {
+	switch {
+	case errors.Is(err, context.Canceled):
+		return status.New(codes.Canceled, err.Error())
+	case errors.Is(err, context.DeadlineExceeded):
+		return status.New(codes.DeadlineExceeded, err.Error())
+	}
+
+	appErr := apperror.From(err)
+	if appErr.Kind == apperror.KindInternal {
+		logger.Error("gRPC request failed:", err)
+	}
+	appErr = appErr.Localize(locale)
+
+	code, ok := codesByKind[appErr.Kind]
+	if !ok {
+		code = codes.Unknown
+	}
+	st := status.New(code, appErr.Message)
+
+	details := []proto.Message{&errdetails.ErrorInfo{Reason: appErr.Code, Domain: errorDomain}}
+	if len(appErr.Validations) > 0 {
+		badRequest := &errdetails.BadRequest{}
+		for _, v := range appErr.Validations {
+			badRequest.FieldViolations = append(badRequest.FieldViolations, &errdetails.BadRequest_FieldViolation{
+				Field:       v.Field,
+				Description: v.Message,
+			})
+		}
+		details = append(details, badRequest)
+	}
+
+	// Status.WithDetails takes legacy messages, so pack the details the same way ourselves
+	pb := st.Proto()
+	for _, detail := range details {
+		packed, err := anypb.New(detail)
+		if err != nil {
+			return st
+		}
+		pb.Details = append(pb.Details, packed)
+	}
+	return status.FromProto(pb)
+}
+
+/*
+statusError is ErrorStatus as an error, in the locale negotiated from the call's metadata.
+
+fields renames validation failures from the DTO's JSON names to the message's field names.
+*/
+func statusError(ctx context.Context, err error, fields map[string]string) error {
+	return ErrorStatus(renameFields(err, fields), locale(ctx)).Err()
+}
+
+/* renameFields returns err with its validation failures reported under the given field names */
+func renameFields(err error, fields map[string]string) error {
+	var appErr *apperror.Error
+	if !errors.As(err, &appErr) || len(appErr.Validations) == 0 {
+		return err
+	}
+
+	clone := *appErr
+	clone.Validations = make([]dto.ValidationError, len(appErr.Validations))
+	for i, v := range appErr.Validations {
+		if name, ok := fields[v.Field]; ok {
+			params := map[string]string{"field": name}
+			for key, value := range v.Params {
+				if key != "field" {
+					params[key] = value
+				}
+			}
+			v.Field, v.Params = name, params
+			if v.Key != "" {
+				v.Message = i18n.T(i18n.DefaultLocale, v.Key, params)
+			}
+		}
+		clone.Validations[i] = v
+	}
+	return &clone
+}
+
+/* locale negotiates the response locale from the accept-language metadata, like LocaleMiddleware */
+func locale(ctx context.Context) string {
+	md, _ := metadata.FromIncomingContext(ctx)
+	for _, value := range md.Get("accept-language") {
+		return i18n.Negotiate(value)
+	}
+	return i18n.DefaultLocale
+}
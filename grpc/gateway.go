@@ -0,0 +1,199 @@
+package grpc
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/textproto"
+	"sort"
+	"strings"
+
+	"baseApi/config"
+	"baseApi/dto"
+	grpcv2 "baseApi/grpc/v2"
+	"baseApi/i18n"
+
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"github.com/improbable-eng/grpc-web/go/grpcweb"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	grpc "google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+)
+
+// gatewaySuccess is the APIResponse status and message of each v2 RPC, as the /v1 handlers answer
+var gatewaySuccess = map[string]struct {
+	status int
+	key    string
+}{
+	grpcv2.UserService_GetUser_FullMethodName:    {dto.StatusOK, "success.user_retrieved"},
+	grpcv2.UserService_CreateUser_FullMethodName: {dto.StatusCreated, "success.user_created"},
+	grpcv2.UserService_UpdateUser_FullMethodName: {dto.StatusOK, "success.user_updated"},
+	grpcv2.UserService_DeleteUser_FullMethodName: {dto.StatusOK, "success.user_deleted"},
+	grpcv2.UserService_ListUsers_FullMethodName:  {dto.StatusOK, "success.users_retrieved"},
+}
+
+// gatewayErrorCodes is the APIResponse error code for gateway failures without an ErrorInfo (bad JSON, unknown route, gRPC server down)
+var gatewayErrorCodes = map[int]string{
+	http.StatusBadRequest:         dto.ErrorCodeBadRequest,
+	http.StatusUnauthorized:       dto.ErrorCodeUnauthorized,
+	http.StatusForbidden:          dto.ErrorCodeForbidden,
+	http.StatusNotFound:           dto.ErrorCodeNotFound,
+	http.StatusConflict:           dto.ErrorCodeConflict,
+	http.StatusServiceUnavailable: dto.ErrorCodeExternalService,
+}
+
+/*
+Gateway serves the v2 UserService on the HTTP port.
+
+JSON requests follow the google.api.http routes of grpc/v2/user_service.proto and are
+forwarded to the gRPC server on GRPC_PORT, so REST and gRPC clients reach the same
+server. gRPC-Web and h2c gRPC calls are served in-process by a server with the same
+services.
+*/
+type Gateway struct {
+	rest        *runtime.ServeMux
+	web         *grpcweb.WrappedGrpcServer
+	services    []string
+	apiResponse bool
+}
+
+/* NewGateway creates the gateway; the gRPC server on GRPC_PORT is dialed lazily */
+func NewGateway(cfg *config.Config) (*Gateway, error) {
+	opts := []runtime.ServeMuxOption{runtime.WithIncomingHeaderMatcher(gatewayHeaderMatcher)}
+	if cfg.GRPCGatewayAPIResponse {
+		opts = append(opts,
+			runtime.WithErrorHandler(apiResponseError),
+			runtime.WithForwardResponseOption(recordMethod),
+		)
+	}
+	rest := runtime.NewServeMux(opts...)
+
+	dialOpts := []grpc.DialOption{grpc.WithTransportCredentials(insecure.NewCredentials())}
+	if err := grpcv2.RegisterUserServiceHandlerFromEndpoint(context.Background(), rest, "localhost:"+cfg.GRPCPort, dialOpts); err != nil {
+		return nil, err
+	}
+
+	server := NewServer(cfg)
+	services := make([]string, 0)
+	for name := range server.GetServiceInfo() {
+		// Reflection is a bidirectional stream, which gRPC-Web can't carry; grpcurl uses GRPC_PORT
+		if !strings.HasPrefix(name, "grpc.reflection.") {
+			services = append(services, name)
+		}
+	}
+	sort.Strings(services)
+
+	return &Gateway{
+		rest:        rest,
+		web:         grpcweb.WrapServer(server, grpcweb.WithOriginFunc(func(string) bool { return true })),
+		services:    services,
+		apiResponse: cfg.GRPCGatewayAPIResponse,
+	}, nil
+}
+
+/* Services returns the fully qualified gRPC services served by ServeGRPC */
+func (g *Gateway) Services() []string {
+	return g.services
+}
+
+/* ServeGRPC serves gRPC-Web requests and native gRPC over h2c */
+func (g *Gateway) ServeGRPC(w http.ResponseWriter, r *http.Request) {
+	g.web.ServeHTTP(w, r)
+}
+
+/* ServeHTTP serves the JSON routes of the v2 API */
+func (g *Gateway) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !g.apiResponse {
+		g.rest.ServeHTTP(w, r)
+		return
+	}
+
+	// Successful responses are buffered to be wrapped; errors are written by apiResponseError
+	buffered := &envelopeWriter{header: make(http.Header), status: http.StatusOK}
+	g.rest.ServeHTTP(buffered, r)
+	for key, values := range buffered.header {
+		if key != "Content-Length" {
+			w.Header()[key] = values
+		}
+	}
+
+	success, ok := gatewaySuccess[buffered.method]
+	if !ok || buffered.status >= http.StatusMultipleChoices {
+		w.WriteHeader(buffered.status)
+		w.Write(buffered.body.Bytes())
+		return
+	}
+
+	locale := i18n.Negotiate(r.Header.Get("Accept-Language"))
+	response := dto.SuccessResponse(success.status, i18n.T(locale, success.key, nil), json.RawMessage(buffered.body.Bytes()))
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(success.status)
+	json.NewEncoder(w).Encode(response)
+}
+
+/* gatewayHeaderMatcher forwards the locale and request ID as gRPC metadata, besides the default headers */
+func gatewayHeaderMatcher(key string) (string, bool) {
+	switch textproto.CanonicalMIMEHeaderKey(key) {
+	case "Accept-Language", "X-Request-Id":
+		return textproto.CanonicalMIMEHeaderKey(key), true
+	}
+	return runtime.DefaultHeaderMatcher(key)
+}
+
+/* envelopeWriter buffers a gateway response so it can be wrapped in an APIResponse */
+type envelopeWriter struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+	method string
+}
+
+func (w *envelopeWriter) Header() http.Header         { return w.header }
+func (w *envelopeWriter) Write(b []byte) (int, error) { return w.body.Write(b) }
+func (w *envelopeWriter) WriteHeader(status int)      { w.status = status }
+
+/* recordMethod notes which RPC produced a successful response, to pick its APIResponse message */
+func recordMethod(ctx context.Context, w http.ResponseWriter, _ proto.Message) error {
+	if buffered, ok := w.(*envelopeWriter); ok {
+		buffered.method, _ = runtime.RPCMethod(ctx)
+	}
+	return nil
+}
+
+/*
+apiResponseError writes a gRPC status as the APIResponse error used by /v1.
+
+The ErrorInfo reason is the error code and BadRequest violations become
+validations; the message was already localized by the gRPC server.
+*/
+func apiResponseError(_ context.Context, _ *runtime.ServeMux, _ runtime.Marshaler, w http.ResponseWriter, r *http.Request, err error) {
+	st := status.Convert(err)
+	httpStatus := runtime.HTTPStatusFromCode(st.Code())
+
+	code, ok := gatewayErrorCodes[httpStatus]
+	if !ok {
+		code = dto.ErrorCodeInternalServer
+	}
+	var validations []dto.ValidationError
+	for _, detail := range st.Details() {
+		switch d := detail.(type) {
+		case *errdetails.ErrorInfo:
+			code = d.Reason
+		case *errdetails.BadRequest:
+			for _, violation := range d.FieldViolations {
+				validations = append(validations, dto.ValidationError{Field: violation.Field, Message: violation.Description})
+			}
+		}
+	}
+
+	response := dto.ErrorResponseWithDetails(httpStatus, code, st.Message(), "")
+	response.Error.Validations = validations
+	response.Error.RequestID = r.Header.Get("X-Request-ID")
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(httpStatus)
+	json.NewEncoder(w).Encode(response)
+}
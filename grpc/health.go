@@ -0,0 +1,142 @@
+package grpc
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"baseApi/cache"
+	"baseApi/database"
+	"baseApi/logger"
+	"baseApi/messaging"
+
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+// Dependencies reported by name beside the gRPC services, e.g. grpc_health_probe -service=redis
+const (
+	healthDatabase = "database"
+	healthRedis    = "redis"
+	healthBroker   = "broker"
+)
+
+const (
+	// defaultHealthInterval is used when GRPC_HEALTH_INTERVAL is not positive
+	defaultHealthInterval = 10 * time.Second
+	// healthTimeout bounds each dependency check
+	healthTimeout = 2 * time.Second
+)
+
+var (
+	// healthServer is shared by every gRPC server of the process, so GRPC_PORT and the gateway agree
+	healthServer = health.NewServer()
+	healthOnce   sync.Once
+)
+
+// healthChecks ping the dependencies; a nil error is SERVING
+var healthChecks = map[string]func(context.Context) error{
+	healthDatabase: pingDatabase,
+	healthRedis:    pingRedis,
+	healthBroker:   pingBroker,
+}
+
+/*
+startHealthChecks keeps the grpc.health.v1 statuses current, checking the
+dependencies now and then every interval for as long as the process runs.
+
+The overall status ("") and the user services follow the database only: Redis is
+a cache and events wait in the outbox while the broker is down, so those degrade
+the API without taking it out of rotation. They are reported under their own names.
+*/
+func startHealthChecks(interval time.Duration, services []string) {
+	healthOnce.Do(func() {
+		if interval <= 0 {
+			interval = defaultHealthInterval
+		}
+		go func() {
+			ticker := time.NewTicker(interval)
+			defer ticker.Stop()
+			for {
+				checkHealth(services)
+				<-ticker.C
+			}
+		}()
+	})
+}
+
+/* checkHealth runs every dependency check and updates the health statuses */
+func checkHealth(services []string) {
+	var databaseErr error
+	for name, check := range healthChecks {
+		ctx, cancel := context.WithTimeout(context.Background(), healthTimeout)
+		err := check(ctx)
+		cancel()
+
+		setHealth(name, err)
+		if name == healthDatabase {
+			databaseErr = err
+		}
+	}
+
+	setHealth("", databaseErr)
+	for _, service := range services {
+		setHealth(service, databaseErr)
+	}
+}
+
+/* setHealth sets a service's status, logging when it changes */
+func setHealth(service string, err error) {
+	status := healthpb.HealthCheckResponse_SERVING
+	if err != nil {
+		status = healthpb.HealthCheckResponse_NOT_SERVING
+	}
+
+	current, _ := healthServer.Check(context.Background(), &healthpb.HealthCheckRequest{Service: service})
+	if current.GetStatus() != status {
+		name := service
+		if name == "" {
+			name = "server"
+		}
+		if err != nil {
+			logger.Warn("gRPC health: "+name+" is not serving:", err)
+		} else if current != nil {
+			logger.Info("gRPC health: " + name + " is serving again")
+		}
+	}
+	healthServer.SetServingStatus(service, status)
+}
+
+/* pingDatabase checks the PostgreSQL connection */
+func pingDatabase(ctx context.Context) error {
+	if database.DB == nil {
+		return errors.New("database is not initialized")
+	}
+	sqlDB, err := database.DB.DB()
+	if err != nil {
+		return err
+	}
+	return sqlDB.PingContext(ctx)
+}
+
+/* pingRedis checks the shared Redis client */
+func pingRedis(ctx context.Context) error {
+	client := cache.GetRedisClient()
+	if client == nil {
+		return errors.New("redis is not initialized")
+	}
+	return client.Ping(ctx).Err()
+}
+
+/* pingBroker checks the message broker, when its backend can tell */
+func pingBroker(ctx context.Context) error {
+	broker := messaging.GetBroker()
+	if broker == nil {
+		return errors.New("message broker is not initialized")
+	}
+	if checker, ok := broker.(messaging.HealthChecker); ok {
+		return checker.Ping(ctx)
+	}
+	return nil
+}
@@ -0,0 +1,319 @@
+package grpc
+
+import (
+	"context"
+	"crypto/subtle"
+	"fmt"
+	"strings"
+	"time"
+
+	"baseApi/apperror"
+	"baseApi/config"
+	"baseApi/logger"
+	"baseApi/middleware"
+	"baseApi/monitoring"
+
+	"github.com/getsentry/sentry-go"
+	"github.com/sirupsen/logrus"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	grpc "google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+// requestIDMetadata carries the request ID in both directions, as X-Request-ID does over HTTP
+const requestIDMetadata = "x-request-id"
+
+type requestIDKey struct{}
+
+/*
+interceptors returns the server options mirroring the Gin middleware stack.
+
+Order matters: the request ID is assigned first so every later step can log it,
+and recovery sits inside logging and Sentry so a panic is reported as an
+INTERNAL call rather than lost.
+*/
+func interceptors(cfg *config.Config) []grpc.ServerOption {
+	auth := authInterceptor{token: cfg.GRPCAuthToken}
+	return []grpc.ServerOption{
+		grpc.ChainUnaryInterceptor(unaryRequestID, unaryLogging, unarySentry, unaryRecovery, auth.unary),
+		grpc.ChainStreamInterceptor(streamRequestID, streamLogging, streamSentry, streamRecovery, auth.stream),
+	}
+}
+
+/* RequestID returns the ID assigned to the call by the request ID interceptor */
+func RequestID(ctx context.Context) string {
+	requestID, _ := ctx.Value(requestIDKey{}).(string)
+	return requestID
+}
+
+/* withRequestID reuses the caller's x-request-id or generates one, and echoes it in the response header */
+func withRequestID(ctx context.Context) context.Context {
+	md, _ := metadata.FromIncomingContext(ctx)
+	var requestID string
+	if values := md.Get(requestIDMetadata); len(values) > 0 && len(values[0]) <= 128 {
+		requestID = values[0]
+	}
+	if requestID == "" {
+		requestID = middleware.NewRequestID()
+	}
+
+	grpc.SetHeader(ctx, metadata.Pairs(requestIDMetadata, requestID))
+	return context.WithValue(ctx, requestIDKey{}, requestID)
+}
+
+/* withRequestInfo adds the request ID to an error status, as error responses carry requestId over HTTP */
+func withRequestInfo(ctx context.Context, err error) error {
+	if err == nil {
+		return nil
+	}
+	st, ok := status.FromError(err)
+	if !ok {
+		return err
+	}
+	withInfo, detailErr := st.WithDetails(&errdetails.RequestInfo{RequestId: RequestID(ctx)})
+	if detailErr != nil {
+		return err
+	}
+	return withInfo.Err()
+}
+
+func unaryRequestID(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	ctx = withRequestID(ctx)
+	resp, err := handler(ctx, req)
+	return resp, withRequestInfo(ctx, err)
+}
+
+func streamRequestID(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	ctx := withRequestID(ss.Context())
+	return withRequestInfo(ctx, handler(srv, &contextStream{ServerStream: ss, ctx: ctx}))
+}
+
+/* contextStream is a ServerStream with a derived context */
+type contextStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *contextStream) Context() context.Context { return s.ctx }
+
+/* unaryLogging logs each call like LoggingMiddleware, with credentials and sensitive bodies redacted */
+func unaryLogging(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	startTime := time.Now()
+	resp, err := handler(ctx, req)
+
+	logFields := callFields(ctx, info.FullMethod, startTime, err)
+	if message, ok := req.(proto.Message); ok {
+		if body := protojson.Format(message); body != "{}" && body != "" {
+			if len(body) > 1000 { // Limit body size in logs
+				body = body[:1000] + "... [TRUNCATED]"
+			}
+			if middleware.ContainsSensitiveData(body) {
+				body = "[CONTAINS SENSITIVE DATA]"
+			}
+			logFields["request_body"] = body
+		}
+	}
+	logCall(logFields, err)
+	return resp, err
+}
+
+/* streamLogging logs a stream when it ends; messages are not logged */
+func streamLogging(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	startTime := time.Now()
+	err := handler(srv, ss)
+
+	logFields := callFields(ss.Context(), info.FullMethod, startTime, err)
+	logFields["stream"] = true
+	logCall(logFields, err)
+	return err
+}
+
+/* callFields are the log fields of a finished call */
+func callFields(ctx context.Context, method string, startTime time.Time, err error) logrus.Fields {
+	md, _ := metadata.FromIncomingContext(ctx)
+	return logrus.Fields{
+		"timestamp":   startTime.Format("2006-01-02 15:04:05"),
+		"request_id":  RequestID(ctx),
+		"method":      method,
+		"grpc_code":   status.Code(err).String(),
+		"duration_ms": time.Since(startTime).Milliseconds(),
+		"client_ip":   peerAddress(ctx),
+		"user_agent":  strings.Join(md.Get("user-agent"), " "),
+		"metadata":    redactMetadata(md),
+	}
+}
+
+func logCall(logFields logrus.Fields, err error) {
+	if status.Code(err) != codes.OK {
+		logger.WithFields(logFields).Error("gRPC Request Error")
+	} else {
+		logger.WithFields(logFields).Info("gRPC Request")
+	}
+}
+
+/* redactMetadata flattens metadata for logs and Sentry, hiding credentials like the HTTP logs do */
+func redactMetadata(md metadata.MD) map[string]string {
+	redacted := make(map[string]string, len(md))
+	for name, values := range md {
+		if middleware.IsSensitiveHeader(name) {
+			redacted[name] = "[REDACTED]"
+		} else {
+			redacted[name] = strings.Join(values, ", ")
+		}
+	}
+	return redacted
+}
+
+/* peerAddress is the caller's address, or "" for in-process calls */
+func peerAddress(ctx context.Context) string {
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		return p.Addr.String()
+	}
+	return ""
+}
+
+func unarySentry(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	finish := startSentryTransaction(ctx, info.FullMethod)
+	resp, err := handler(ctx, req)
+	finish(err)
+	return resp, err
+}
+
+func streamSentry(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	finish := startSentryTransaction(ss.Context(), info.FullMethod)
+	err := handler(srv, ss)
+	finish(err)
+	return err
+}
+
+/*
+startSentryTransaction starts a grpc.server transaction like SentryMiddleware; the
+returned func finishes it with the call's status.
+
+Client errors are captured as warnings and server errors as errors, as the HTTP
+middleware does for 4xx and 5xx responses.
+*/
+func startSentryTransaction(ctx context.Context, method string) func(error) {
+	transaction := monitoring.StartTransaction(method, "grpc.server")
+	md, _ := metadata.FromIncomingContext(ctx)
+	if transaction != nil {
+		sentry.ConfigureScope(func(scope *sentry.Scope) {
+			scope.SetContext("request", map[string]interface{}{
+				"method":     method,
+				"metadata":   redactMetadata(md),
+				"user_agent": strings.Join(md.Get("user-agent"), " "),
+				"client_ip":  peerAddress(ctx),
+			})
+			scope.SetTag("endpoint", method)
+			scope.SetTag("method", "grpc")
+		})
+	}
+
+	monitoring.AddBreadcrumb("gRPC Request", "grpc", map[string]interface{}{"method": method})
+
+	startTime := time.Now()
+	return func(err error) {
+		code := status.Code(err)
+		if code != codes.OK {
+			level, message := sentry.LevelWarning, "gRPC Error"
+			if serverError(code) {
+				level, message = sentry.LevelError, "gRPC Server Error"
+			}
+			monitoring.CaptureMessage(message, level, map[string]interface{}{
+				"grpc_code":   code.String(),
+				"method":      method,
+				"request_id":  RequestID(ctx),
+				"duration_ms": time.Since(startTime).Milliseconds(),
+			})
+		}
+		// Sentry span statuses follow the gRPC codes, offset by SpanStatusUndefined
+		monitoring.FinishTransaction(transaction, sentry.SpanStatusOK+sentry.SpanStatus(code))
+	}
+}
+
+/* serverError reports whether a code is the server's fault rather than the caller's */
+func serverError(code codes.Code) bool {
+	switch code {
+	case codes.Unknown, codes.Internal, codes.Unavailable, codes.DataLoss, codes.Unimplemented:
+		return true
+	}
+	return false
+}
+
+func unaryRecovery(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp interface{}, err error) {
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			err = recoverPanic(ctx, info.FullMethod, recovered)
+		}
+	}()
+	return handler(ctx, req)
+}
+
+func streamRecovery(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) (err error) {
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			err = recoverPanic(ss.Context(), info.FullMethod, recovered)
+		}
+	}()
+	return handler(srv, ss)
+}
+
+/* recoverPanic sends a panic to Sentry and turns it into an INTERNAL status, like RecoveryWithSentry */
+func recoverPanic(ctx context.Context, method string, recovered interface{}) error {
+	err, ok := recovered.(error)
+	if !ok {
+		err = fmt.Errorf("%v", recovered)
+	}
+	monitoring.CaptureError(err, map[string]interface{}{
+		"panic":      true,
+		"method":     method,
+		"request_id": RequestID(ctx),
+	})
+	return statusError(ctx, fmt.Errorf("panic in %s: %w", method, err), nil)
+}
+
+/* authInterceptor requires the configured bearer token in "authorization" metadata, like AdminAuth */
+type authInterceptor struct {
+	token string
+}
+
+func (a authInterceptor) unary(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	if err := a.authorize(ctx, info.FullMethod); err != nil {
+		return nil, err
+	}
+	return handler(ctx, req)
+}
+
+func (a authInterceptor) stream(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	if err := a.authorize(ss.Context(), info.FullMethod); err != nil {
+		return err
+	}
+	return handler(srv, ss)
+}
+
+/* authorize checks the bearer token; without a configured token every call is allowed */
+func (a authInterceptor) authorize(ctx context.Context, method string) error {
+	if a.token == "" || publicMethod(method) {
+		return nil
+	}
+
+	md, _ := metadata.FromIncomingContext(ctx)
+	var provided string
+	if values := md.Get("authorization"); len(values) > 0 {
+		provided = strings.TrimPrefix(values[0], "Bearer ")
+	}
+	if subtle.ConstantTimeCompare([]byte(provided), []byte(a.token)) != 1 {
+		return statusError(ctx, apperror.Unauthorized("Authentication required"), nil)
+	}
+	return nil
+}
+
+/* publicMethod reports calls allowed without a token: health probes and reflection */
+func publicMethod(method string) bool {
+	return strings.HasPrefix(method, "/grpc.health.v1.Health/") || strings.HasPrefix(method, "/grpc.reflection.")
+}
@@ -0,0 +1,73 @@
+package grpc
+
+import (
+	"context"
+	"fmt"
+	"net"
+
+	"baseApi/config"
+	grpcv2 "baseApi/grpc/v2"
+	"baseApi/logger"
+
+	grpc "google.golang.org/grpc"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/reflection"
+)
+
+/*
+NewServer creates a gRPC server with every service registered; v1 stays served for existing clients.
+
+Calls go through the interceptor chain (request ID, logging, Sentry, recovery, auth),
+so GRPC_PORT and the gateway's gRPC-Web server behave alike. grpc.health.v1 reports
+the dependencies' status and, with GRPC_REFLECTION_ENABLED, reflection lets grpcurl
+list and call the services without the protos.
+*/
+func NewServer(cfg *config.Config) *grpc.Server {
+	server := grpc.NewServer(interceptors(cfg)...)
+	RegisterUserServiceServer(server, NewUserServer())
+	grpcv2.RegisterUserServiceServer(server, NewUserServerV2(cfg))
+
+	healthpb.RegisterHealthServer(server, healthServer)
+	startHealthChecks(cfg.GRPCHealthInterval, []string{UserService_ServiceDesc.ServiceName, grpcv2.UserService_ServiceDesc.ServiceName})
+	if cfg.GRPCReflectionEnabled {
+		reflection.Register(server)
+	}
+	return server
+}
+
+/*
+StartGRPCServer listens on GRPC_PORT and serves in the background.
+
+Listen errors (port in use) are returned so the caller can carry on without gRPC;
+stop the returned server with StopGRPCServer on shutdown.
+*/
+func StartGRPCServer(cfg *config.Config) (*grpc.Server, error) {
+	listener, err := net.Listen("tcp", fmt.Sprintf(":%s", cfg.GRPCPort))
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen on gRPC port %s: %w", cfg.GRPCPort, err)
+	}
+
+	server := NewServer(cfg)
+	go func() {
+		if err := server.Serve(listener); err != nil {
+			logger.Error("gRPC server stopped:", err)
+		}
+	}()
+	return server, nil
+}
+
+/* StopGRPCServer waits for in-flight RPCs to finish, cancelling those still running when ctx ends */
+func StopGRPCServer(ctx context.Context, server *grpc.Server) {
+	stopped := make(chan struct{})
+	go func() {
+		server.GracefulStop()
+		close(stopped)
+	}()
+
+	select {
+	case <-stopped:
+	case <-ctx.Done():
+		logger.Warn("gRPC server did not drain in time, closing remaining streams")
+		server.Stop()
+	}
+}
@@ -0,0 +1,145 @@
+package grpc
+
+import (
+	"context"
+	"time"
+
+	"baseApi/apperror"
+	"baseApi/dto"
+	"baseApi/i18n"
+	"baseApi/services"
+	"baseApi/validation"
+
+	"github.com/gin-gonic/gin/binding"
+)
+
+// v1Fields maps DTO fields to v1 message fields where they differ
+var v1Fields = map[string]string{"username": "name"}
+
+/* UserServer implements UserService on top of services.UserService, with the same rules as the HTTP API */
+type UserServer struct {
+	UnimplementedUserServiceServer
+	userService *services.UserService
+}
+
+/* NewUserServer creates the gRPC user service */
+func NewUserServer() *UserServer {
+	validation.RegisterRules()
+	return &UserServer{userService: services.NewUserService()}
+}
+
+/* GetUser returns a user by ID */
+func (s *UserServer) GetUser(ctx context.Context, req *GetUserRequest) (*GetUserResponse, error) {
+	if err := validateID(req.GetId()); err != nil {
+		return nil, statusError(ctx, err, v1Fields)
+	}
+
+	user, err := s.userService.GetUserByID(uint(req.GetId()))
+	if err != nil {
+		return nil, statusError(ctx, err, v1Fields)
+	}
+	return &GetUserResponse{User: toUser(user), Message: i18n.T(locale(ctx), "success.user_retrieved", nil)}, nil
+}
+
+/* CreateUser creates a user; name is the username */
+func (s *UserServer) CreateUser(ctx context.Context, req *CreateUserRequest) (*CreateUserResponse, error) {
+	create := dto.CreateUserRequest{
+		Username: req.GetName(),
+		Email:    req.GetEmail(),
+		Password: req.GetPassword(),
+	}
+	if err := validate(&create); err != nil {
+		return nil, statusError(ctx, err, v1Fields)
+	}
+
+	user, err := s.userService.CreateUser(create)
+	if err != nil {
+		return nil, statusError(ctx, err, v1Fields)
+	}
+	return &CreateUserResponse{User: toUser(user), Message: i18n.T(locale(ctx), "success.user_created", nil)}, nil
+}
+
+/* UpdateUser changes the non-empty fields of a user */
+func (s *UserServer) UpdateUser(ctx context.Context, req *UpdateUserRequest) (*UpdateUserResponse, error) {
+	if err := validateID(req.GetId()); err != nil {
+		return nil, statusError(ctx, err, v1Fields)
+	}
+	update := dto.UpdateUserRequest{
+		Username: req.GetName(),
+		Email:    req.GetEmail(),
+	}
+	if err := validate(&update); err != nil {
+		return nil, statusError(ctx, err, v1Fields)
+	}
+
+	user, err := s.userService.UpdateUser(uint(req.GetId()), update)
+	if err != nil {
+		return nil, statusError(ctx, err, v1Fields)
+	}
+	return &UpdateUserResponse{User: toUser(user), Message: i18n.T(locale(ctx), "success.user_updated", nil)}, nil
+}
+
+/* DeleteUser soft deletes a user */
+func (s *UserServer) DeleteUser(ctx context.Context, req *DeleteUserRequest) (*DeleteUserResponse, error) {
+	if err := validateID(req.GetId()); err != nil {
+		return nil, statusError(ctx, err, v1Fields)
+	}
+
+	if err := s.userService.DeleteUser(uint(req.GetId())); err != nil {
+		return nil, statusError(ctx, err, v1Fields)
+	}
+	return &DeleteUserResponse{Message: i18n.T(locale(ctx), "success.user_deleted", nil)}, nil
+}
+
+/* ListUsers returns a page of users; page and limit default like GET /users */
+func (s *UserServer) ListUsers(ctx context.Context, req *ListUsersRequest) (*ListUsersResponse, error) {
+	search := dto.UserSearchRequest{
+		Page:  int(req.GetPage()),
+		Limit: int(req.GetLimit()),
+	}
+	if err := validate(&search); err != nil {
+		return nil, statusError(ctx, err, v1Fields)
+	}
+
+	list, err := s.userService.GetAllUsers(search)
+	if err != nil {
+		return nil, statusError(ctx, err, v1Fields)
+	}
+
+	users := make([]*User, len(list.Users))
+	for i := range list.Users {
+		users[i] = toUser(&list.Users[i])
+	}
+	return &ListUsersResponse{
+		Users:   users,
+		Total:   int32(list.Pagination.TotalItems),
+		Message: i18n.T(locale(ctx), "success.users_retrieved", nil),
+	}, nil
+}
+
+/* toUser converts a user DTO to its v1 message; timestamps are RFC 3339 */
+func toUser(user *dto.UserResponse) *User {
+	return &User{
+		Id:        uint32(user.ID),
+		Name:      user.Username,
+		Email:     user.Email,
+		CreatedAt: user.CreatedAt.Format(time.RFC3339),
+		UpdatedAt: user.UpdatedAt.Format(time.RFC3339),
+	}
+}
+
+/* validateID rejects the zero ID, which proto3 sends when the field is missing */
+func validateID(id uint32) error {
+	if id != 0 {
+		return nil
+	}
+	return apperror.BadRequest("Invalid user ID format").WithKey("error.invalid_user_id", nil)
+}
+
+/* validate applies the DTO's binding rules, as ShouldBindJSON does for HTTP requests */
+func validate(req interface{}) error {
+	if err := binding.Validator.ValidateStruct(req); err != nil {
+		return apperror.Validation(validation.Translate(err))
+	}
+	return nil
+}
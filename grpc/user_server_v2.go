@@ -0,0 +1,237 @@
+package grpc
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+
+	"baseApi/apperror"
+	"baseApi/config"
+	"baseApi/dto"
+	grpcv2 "baseApi/grpc/v2"
+	"baseApi/services"
+	"baseApi/validation"
+
+	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// v2Fields maps DTO fields to v2 message fields where they differ
+var v2Fields = map[string]string{
+	"firstName": "first_name",
+	"lastName":  "last_name",
+	"isActive":  "is_active",
+	"limit":     "page_size",
+	"cursor":    "page_token",
+	"sortBy":    "sort_by",
+}
+
+// v2SortFields maps UserSortField values to the sort fields of the HTTP API
+var v2SortFields = map[grpcv2.UserSortField]string{
+	grpcv2.UserSortField_USER_SORT_FIELD_UNSPECIFIED: "createdAt",
+	grpcv2.UserSortField_USER_SORT_FIELD_USERNAME:    "username",
+	grpcv2.UserSortField_USER_SORT_FIELD_EMAIL:       "email",
+	grpcv2.UserSortField_USER_SORT_FIELD_FIRST_NAME:  "firstName",
+	grpcv2.UserSortField_USER_SORT_FIELD_LAST_NAME:   "lastName",
+	grpcv2.UserSortField_USER_SORT_FIELD_IS_ACTIVE:   "isActive",
+	grpcv2.UserSortField_USER_SORT_FIELD_CREATED_AT:  "createdAt",
+	grpcv2.UserSortField_USER_SORT_FIELD_UPDATED_AT:  "updatedAt",
+}
+
+/* UserServerV2 implements the v2 UserService on top of services.UserService */
+type UserServerV2 struct {
+	grpcv2.UnimplementedUserServiceServer
+	userService   *services.UserService
+	streamService *services.UserStreamService
+	streamEnabled bool
+}
+
+/* NewUserServerV2 creates the v2 gRPC user service; WatchUsers follows the user stream settings */
+func NewUserServerV2(cfg *config.Config) *UserServerV2 {
+	validation.RegisterRules()
+	return &UserServerV2{
+		userService:   services.NewUserService(),
+		streamService: services.NewUserStreamService(cfg.UserStreamReplayLimit),
+		streamEnabled: cfg.UserStreamEnabled,
+	}
+}
+
+/* GetUser returns a user by ID */
+func (s *UserServerV2) GetUser(ctx context.Context, req *grpcv2.GetUserRequest) (*grpcv2.User, error) {
+	if err := validateID(req.GetId()); err != nil {
+		return nil, statusError(ctx, err, v2Fields)
+	}
+
+	user, err := s.userService.GetUserByID(uint(req.GetId()))
+	if err != nil {
+		return nil, statusError(ctx, err, v2Fields)
+	}
+	return toUserV2(user), nil
+}
+
+/* CreateUser creates an active user */
+func (s *UserServerV2) CreateUser(ctx context.Context, req *grpcv2.CreateUserRequest) (*grpcv2.User, error) {
+	create := dto.CreateUserRequest{
+		Username:  req.GetUsername(),
+		Email:     req.GetEmail(),
+		Password:  req.GetPassword(),
+		FirstName: req.GetFirstName(),
+		LastName:  req.GetLastName(),
+	}
+	if err := validate(&create); err != nil {
+		return nil, statusError(ctx, err, v2Fields)
+	}
+
+	user, err := s.userService.CreateUser(create)
+	if err != nil {
+		return nil, statusError(ctx, err, v2Fields)
+	}
+	return toUserV2(user), nil
+}
+
+/*
+UpdateUser applies the fields named in update_mask.
+
+The masked fields become a JSON Merge Patch, so updates go through PatchUser and
+get its validation, versioning and events; unlike the v1 update, a mask can clear
+first_name or set is_active to false.
+*/
+func (s *UserServerV2) UpdateUser(ctx context.Context, req *grpcv2.UpdateUserRequest) (*grpcv2.User, error) {
+	if err := validateID(req.GetUser().GetId()); err != nil {
+		return nil, statusError(ctx, err, v2Fields)
+	}
+	patch, err := maskPatch(req)
+	if err != nil {
+		return nil, statusError(ctx, err, v2Fields)
+	}
+
+	user, err := s.userService.PatchUser(uint(req.GetUser().GetId()), dto.ContentTypeMergePatch, patch, expectedVersion(req.ExpectedVersion)...)
+	if err != nil {
+		return nil, statusError(ctx, err, v2Fields)
+	}
+	return toUserV2(user), nil
+}
+
+/* DeleteUser soft deletes a user */
+func (s *UserServerV2) DeleteUser(ctx context.Context, req *grpcv2.DeleteUserRequest) (*emptypb.Empty, error) {
+	if err := validateID(req.GetId()); err != nil {
+		return nil, statusError(ctx, err, v2Fields)
+	}
+
+	if err := s.userService.DeleteUser(uint(req.GetId()), expectedVersion(req.ExpectedVersion)...); err != nil {
+		return nil, statusError(ctx, err, v2Fields)
+	}
+	return &emptypb.Empty{}, nil
+}
+
+/* ListUsers returns a cursor page of users */
+func (s *UserServerV2) ListUsers(ctx context.Context, req *grpcv2.ListUsersRequest) (*grpcv2.ListUsersResponse, error) {
+	sortBy, ok := v2SortFields[req.GetSortBy()]
+	if !ok {
+		return nil, statusError(ctx, invalidField("sort_by", req.GetSortBy().String()), nil)
+	}
+	search := dto.UserCursorRequest{
+		Query:    req.GetQuery(),
+		Limit:    int(req.GetPageSize()),
+		Cursor:   req.GetPageToken(),
+		SortBy:   sortBy,
+		SortDesc: req.GetDescending(),
+		IsActive: req.IsActive,
+	}
+	if err := validate(&search); err != nil {
+		return nil, statusError(ctx, err, v2Fields)
+	}
+
+	page, err := s.userService.ListUsers(search)
+	if err != nil {
+		return nil, statusError(ctx, err, v2Fields)
+	}
+
+	users := make([]*grpcv2.User, len(page.Users))
+	for i := range page.Users {
+		users[i] = toUserV2(&page.Users[i])
+	}
+	return &grpcv2.ListUsersResponse{
+		Users:         users,
+		NextPageToken: page.NextCursor,
+		TotalSize:     page.TotalItems,
+	}, nil
+}
+
+/* toUserV2 converts a user DTO to its v2 message */
+func toUserV2(user *dto.UserResponse) *grpcv2.User {
+	return &grpcv2.User{
+		Id:        uint32(user.ID),
+		Username:  user.Username,
+		Email:     user.Email,
+		FirstName: user.FirstName,
+		LastName:  user.LastName,
+		IsActive:  user.IsActive,
+		Version:   uint32(user.Version),
+		CreatedAt: timestamppb.New(user.CreatedAt),
+		UpdatedAt: timestamppb.New(user.UpdatedAt),
+	}
+}
+
+/*
+maskPatch builds the JSON Merge Patch for an update.
+
+Without a mask the non-empty fields of user are updated, as AIP-134 suggests; a
+mask is needed to clear a name or deactivate a user.
+*/
+func maskPatch(req *grpcv2.UpdateUserRequest) ([]byte, error) {
+	user := req.GetUser()
+	values := map[string]interface{}{
+		"username":   user.GetUsername(),
+		"email":      user.GetEmail(),
+		"first_name": user.GetFirstName(),
+		"last_name":  user.GetLastName(),
+		"is_active":  user.GetIsActive(),
+	}
+	jsonNames := map[string]string{
+		"username":   "username",
+		"email":      "email",
+		"first_name": "firstName",
+		"last_name":  "lastName",
+		"is_active":  "isActive",
+	}
+
+	patch := make(map[string]interface{})
+	paths := req.GetUpdateMask().GetPaths()
+	if len(paths) == 0 {
+		for path, value := range values {
+			if value != "" && value != false {
+				patch[jsonNames[path]] = value
+			}
+		}
+	}
+	for _, path := range paths {
+		value, ok := values[path]
+		if !ok {
+			return nil, invalidField("update_mask", strings.Join(paths, ","))
+		}
+		patch[jsonNames[path]] = value
+	}
+
+	return json.Marshal(patch)
+}
+
+/* expectedVersion turns an optional expected_version into the service's If-Match versions */
+func expectedVersion(version *uint32) []uint {
+	if version == nil {
+		return nil
+	}
+	return []uint{uint(*version)}
+}
+
+/* invalidField reports an invalid request field as a validation failure */
+func invalidField(field, value string) error {
+	return apperror.Validation([]dto.ValidationError{{
+		Field:   field,
+		Rule:    "invalid",
+		Message: field + " is invalid",
+		Value:   value,
+		Key:     "validation.invalid",
+		Params:  map[string]string{"field": field},
+	}})
+}
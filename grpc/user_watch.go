@@ -0,0 +1,97 @@
+package grpc
+
+import (
+	"encoding/json"
+	"errors"
+	"strconv"
+	"strings"
+
+	"baseApi/apperror"
+	"baseApi/dto"
+	grpcv2 "baseApi/grpc/v2"
+	"baseApi/messaging"
+	"baseApi/services"
+	"baseApi/streaming"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// maxLastEventIDLength bounds last_event_id like Last-Event-ID; CloudEvent ids are UUIDs
+const maxLastEventIDLength = 64
+
+// watchFields maps stream filter parameters to WatchUsersRequest fields
+var watchFields = map[string]string{"events": "events", "userId": "user_ids"}
+
+/*
+WatchUsers streams user changes from the process-wide hub, the same events the
+SSE and WebSocket streams push: the CloudEvents published by the outbox relay.
+
+With last_event_id the missed events are replayed from the outbox first; a reset
+event tells the client to reload when they can't be. The stream ends when the
+client cancels; it fails with Aborted when the client falls too far behind and
+Unavailable when the server shuts down, and is resumed with the last id received.
+*/
+func (s *UserServerV2) WatchUsers(req *grpcv2.WatchUsersRequest, stream grpcv2.UserService_WatchUsersServer) error {
+	ctx := stream.Context()
+	if !s.streamEnabled {
+		return statusError(ctx, services.ErrUserStreamDisabled, nil)
+	}
+
+	userIDs := make([]string, len(req.GetUserIds()))
+	for i, id := range req.GetUserIds() {
+		userIDs[i] = strconv.FormatUint(uint64(id), 10)
+	}
+	filter, err := streaming.ParseFilter(strings.Join(req.GetEvents(), ","), strings.Join(userIDs, ","))
+	var filterErr *streaming.FilterError
+	if errors.As(err, &filterErr) {
+		return statusError(ctx, invalidField(watchFields[filterErr.Field], filterErr.Value), nil)
+	}
+	if err != nil {
+		return statusError(ctx, err, nil)
+	}
+	if len(req.GetLastEventId()) > maxLastEventIDLength {
+		return statusError(ctx, apperror.BadRequest("Invalid Last-Event-ID").WithKey("error.invalid_last_event_id", nil), nil)
+	}
+
+	send := func(e streaming.Event) error {
+		return stream.Send(toUserEvent(e))
+	}
+	err = s.streamService.Serve(ctx, streaming.GetHub(), filter, req.GetLastEventId(), 0, send, nil)
+	switch {
+	case errors.Is(err, services.ErrUserStreamLagged):
+		return statusError(ctx, err, nil) // Aborted: resume with the last event id
+	case errors.Is(err, services.ErrUserStreamClosed):
+		return status.Error(codes.Unavailable, err.Error())
+	}
+	return err // nil, or the failed Send's status
+}
+
+/* toUserEvent converts a stream event to its v2 message; the CloudEvent data is the user DTO */
+func toUserEvent(e streaming.Event) *grpcv2.UserEvent {
+	event := &grpcv2.UserEvent{Id: e.ID, Type: e.Event, UserId: uint32(e.UserID)}
+	if e.Event == streaming.EventReset {
+		var data map[string]string
+		json.Unmarshal(e.Data, &data)
+		event.ResetReason = data["reason"]
+		return event
+	}
+
+	var cloudEvent messaging.CloudEvent
+	if json.Unmarshal(e.Data, &cloudEvent) != nil {
+		return event
+	}
+	event.Time = timestamppb.New(cloudEvent.Time)
+
+	// Deleted events carry only the id
+	if e.Event == messaging.UserEventRoutingKey(services.UserEventDeleted) {
+		event.User = &grpcv2.User{Id: event.UserId}
+		return event
+	}
+	var user dto.UserResponse
+	if json.Unmarshal(cloudEvent.Data, &user) == nil {
+		event.User = toUserV2(&user)
+	}
+	return event
+}
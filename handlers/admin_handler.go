@@ -0,0 +1,131 @@
+package handlers
+
+import (
+	"errors"
+	"strconv"
+
+	"baseApi/apperror"
+	"baseApi/dto"
+	"baseApi/i18n"
+	"baseApi/logger"
+	"baseApi/messaging"
+	"baseApi/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	defaultDeadLetterLimit = 10
+	maxDeadLetterLimit     = 100
+)
+
+type AdminHandler struct{}
+
+/* NewAdminHandler creates a new admin handler */
+func NewAdminHandler() *AdminHandler {
+	return &AdminHandler{}
+}
+
+/* GetDeadLetters shows the size of a queue's dead-letter queue and peeks at its messages */
+func (h *AdminHandler) GetDeadLetters(c *gin.Context) {
+	info, err := deadLetters().InspectDeadLetters(c.Param("queue"), deadLetterLimit(c))
+	if err != nil {
+		c.Error(brokerError(err))
+		return
+	}
+
+	response := dto.SuccessResponse(
+		dto.StatusOK,
+		i18n.T(middleware.Locale(c), "success.dead_letters_retrieved", nil),
+		info,
+	)
+	c.JSON(response.StatusCode, response)
+}
+
+/* ReplayDeadLetters republishes dead-lettered messages to their original routing keys */
+func (h *AdminHandler) ReplayDeadLetters(c *gin.Context) {
+	queue := c.Param("queue")
+	replayed, err := deadLetters().ReplayDeadLetters(queue, deadLetterLimit(c))
+	if err != nil && replayed == 0 {
+		c.Error(brokerError(err))
+		return
+	}
+	if err != nil {
+		logger.Error("Dead-letter replay stopped early:", err)
+	}
+
+	logger.Info("Replayed dead letters from", queue+":", replayed)
+	response := dto.SuccessResponse(
+		dto.StatusOK,
+		i18n.T(middleware.Locale(c), "success.dead_letters_replayed", map[string]string{"count": strconv.Itoa(replayed)}),
+		gin.H{"queue": queue, "replayed": replayed},
+	)
+	c.JSON(response.StatusCode, response)
+}
+
+/* PurgeDeadLetters deletes every dead-lettered message of a queue */
+func (h *AdminHandler) PurgeDeadLetters(c *gin.Context) {
+	queue := c.Param("queue")
+	purged, err := deadLetters().PurgeDeadLetters(queue)
+	if err != nil {
+		c.Error(brokerError(err))
+		return
+	}
+
+	logger.Info("Purged dead letters from", queue+":", purged)
+	response := dto.SuccessResponse(
+		dto.StatusOK,
+		i18n.T(middleware.Locale(c), "success.dead_letters_purged", map[string]string{"count": strconv.Itoa(purged)}),
+		gin.H{"queue": queue, "purged": purged},
+	)
+	c.JSON(response.StatusCode, response)
+}
+
+/* deadLetters returns the configured broker's dead-letter admin; a missing broker reports ErrNotConnected */
+func deadLetters() messaging.DeadLetterAdmin {
+	if admin, ok := messaging.GetBroker().(messaging.DeadLetterAdmin); ok {
+		return admin
+	}
+	return unavailableDeadLetters{}
+}
+
+/* unavailableDeadLetters answers every dead-letter operation with ErrNotConnected */
+type unavailableDeadLetters struct{}
+
+func (unavailableDeadLetters) InspectDeadLetters(string, int) (*messaging.DeadLetterQueueInfo, error) {
+	return nil, messaging.ErrNotConnected
+}
+
+func (unavailableDeadLetters) ReplayDeadLetters(string, int) (int, error) {
+	return 0, messaging.ErrNotConnected
+}
+
+func (unavailableDeadLetters) PurgeDeadLetters(string) (int, error) {
+	return 0, messaging.ErrNotConnected
+}
+
+/* deadLetterLimit parses ?limit=, defaulting to 10 and capped at 100 */
+func deadLetterLimit(c *gin.Context) int {
+	limit, err := strconv.Atoi(c.Query("limit"))
+	if err != nil || limit <= 0 {
+		return defaultDeadLetterLimit
+	}
+	if limit > maxDeadLetterLimit {
+		return maxDeadLetterLimit
+	}
+	return limit
+}
+
+/* brokerError maps messaging errors to application errors */
+func brokerError(err error) error {
+	switch {
+	case errors.Is(err, messaging.ErrQueueNotFound):
+		return apperror.NotFound("DeadLetterQueue")
+	case errors.Is(err, messaging.ErrNotConnected):
+		return apperror.New(apperror.KindInternal, dto.StatusServiceUnavailable, dto.ErrorCodeExternalService, "Message broker unavailable").
+			WithKey("error.broker_unavailable", nil).
+			WithCause(err)
+	default:
+		return apperror.Internal(dto.ErrorCodeExternalService, "Message broker operation failed", err)
+	}
+}
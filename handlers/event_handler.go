@@ -0,0 +1,39 @@
+package handlers
+
+import (
+	"baseApi/apperror"
+	"baseApi/dto"
+	"baseApi/i18n"
+	"baseApi/messaging"
+	"baseApi/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+type EventHandler struct{}
+
+/* NewEventHandler creates a new event schema handler */
+func NewEventHandler() *EventHandler {
+	return &EventHandler{}
+}
+
+/* GetSchemaCatalog lists the versioned JSON Schemas of published events */
+func (h *EventHandler) GetSchemaCatalog(c *gin.Context) {
+	response := dto.SuccessResponse(
+		dto.StatusOK,
+		i18n.T(middleware.Locale(c), "success.event_schemas_retrieved", nil),
+		messaging.SchemaCatalog(),
+	)
+	c.JSON(response.StatusCode, response)
+}
+
+/* GetSchema serves one event data schema; its URL is the CloudEvents "dataschema" */
+func (h *EventHandler) GetSchema(c *gin.Context) {
+	schema, ok := messaging.Schema(c.Param("event"), c.Param("version"))
+	if !ok {
+		c.Error(apperror.NotFound("EventSchema"))
+		return
+	}
+
+	c.Data(dto.StatusOK, dto.ContentTypeSchema, schema)
+}
@@ -0,0 +1,45 @@
+package handlers
+
+import (
+	"baseApi/config"
+	usergrpc "baseApi/grpc"
+	"baseApi/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+type GatewayHandler struct {
+	gateway *usergrpc.Gateway
+}
+
+/* NewGatewayHandler creates the handler serving the v2 gRPC API over HTTP */
+func NewGatewayHandler(cfg *config.Config) (*GatewayHandler, error) {
+	gateway, err := usergrpc.NewGateway(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return &GatewayHandler{gateway: gateway}, nil
+}
+
+/* Services returns the gRPC services reachable through ServeGRPC */
+func (h *GatewayHandler) Services() []string {
+	return h.gateway.Services()
+}
+
+/*
+ServeREST serves the JSON routes generated from the google.api.http annotations.
+
+The negotiated locale and request ID replace the client's headers, so gRPC
+messages match the locale of /v1 and logs share one request ID.
+*/
+func (h *GatewayHandler) ServeREST(c *gin.Context) {
+	c.Request.Header.Set("Accept-Language", middleware.Locale(c))
+	c.Request.Header.Set(middleware.RequestIDHeader, c.GetString("request_id"))
+	h.gateway.ServeHTTP(c.Writer, c.Request)
+}
+
+/* ServeGRPC serves gRPC-Web calls and native gRPC calls over h2c, with the request ID as x-request-id metadata */
+func (h *GatewayHandler) ServeGRPC(c *gin.Context) {
+	c.Request.Header.Set(middleware.RequestIDHeader, c.GetString("request_id"))
+	h.gateway.ServeGRPC(c.Writer, c.Request)
+}
@@ -0,0 +1,279 @@
+package handlers
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"baseApi/apperror"
+	"baseApi/config"
+	usergraphql "baseApi/graphql"
+	"baseApi/middleware"
+	"baseApi/validation"
+
+	"github.com/gin-gonic/gin"
+	graphql "github.com/graph-gophers/graphql-go"
+	"golang.org/x/net/websocket"
+)
+
+const (
+	// graphQLWSProtocol is the WebSocket subprotocol of the graphql-ws client library
+	graphQLWSProtocol = "graphql-transport-ws"
+	// connectionInitTimeout closes sockets that don't send connection_init in time
+	connectionInitTimeout = 10 * time.Second
+)
+
+// graphql-transport-ws close codes
+const (
+	closeInvalidMessage     = 4400
+	closeUnauthorized       = 4401
+	closeInitTimeout        = 4408
+	closeSubscriberExists   = 4409
+	closeTooManyInitRequest = 4429
+)
+
+type GraphQLHandler struct {
+	schema    *usergraphql.Schema
+	heartbeat time.Duration
+}
+
+/* NewGraphQLHandler creates the handler serving graphql/schema.graphql */
+func NewGraphQLHandler(cfg *config.Config) (*GraphQLHandler, error) {
+	schema, err := usergraphql.NewSchema(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return &GraphQLHandler{schema: schema, heartbeat: cfg.UserStreamHeartbeat}, nil
+}
+
+/* graphQLRequest is the POST /graphql body */
+type graphQLRequest struct {
+	Query         string                 `json:"query" binding:"required"`
+	OperationName string                 `json:"operationName"`
+	Variables     map[string]interface{} `json:"variables"`
+}
+
+/*
+Query runs a query or mutation: {"query", "operationName", "variables"} in,
+{"data", "errors"} out.
+
+Failed fields are reported in "errors" with a 200 as GraphQL clients expect;
+extensions.code carries the APIResponse error code. Only a malformed body is
+answered with an APIResponse error.
+*/
+func (h *GraphQLHandler) Query(c *gin.Context) {
+	var req graphQLRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(apperror.Validation(validation.Translate(err)))
+		return
+	}
+
+	span := middleware.StartSpanFromContext(c, "graphql.exec", req.OperationName)
+	ctx := usergraphql.WithRequest(c.Request.Context(), middleware.Locale(c), c.GetString("request_id"))
+	response := h.schema.Exec(ctx, usergraphql.Request(req))
+	if span != nil {
+		span.Finish()
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+/*
+Subscribe serves subscriptions, and queries and mutations too, over WebSocket
+with the graphql-transport-ws protocol of the graphql-ws client library.
+
+The client sends connection_init, then a subscribe message per operation; each
+result is a next message and the end of the stream a complete message. The
+server pings every USER_STREAM_HEARTBEAT.
+*/
+func (h *GraphQLHandler) Subscribe(c *gin.Context) {
+	ctx := usergraphql.WithRequest(c.Request.Context(), middleware.Locale(c), c.GetString("request_id"))
+	server := websocket.Server{
+		// The token (not cookies) authorizes the connection, so any Origin is fine
+		Handshake: func(config *websocket.Config, _ *http.Request) error {
+			for _, protocol := range config.Protocol {
+				if protocol == graphQLWSProtocol {
+					config.Protocol = []string{graphQLWSProtocol}
+					return nil
+				}
+			}
+			return websocket.ErrBadWebSocketProtocol
+		},
+		Handler: func(ws *websocket.Conn) {
+			defer ws.Close()
+			conn := &graphQLWSConn{ws: ws, schema: h.schema, operations: make(map[string]*graphQLWSOperation)}
+			conn.serve(ctx, h.heartbeat)
+		},
+	}
+	server.ServeHTTP(c.Writer, c.Request)
+}
+
+/* graphQLWSMessage is a graphql-transport-ws message */
+type graphQLWSMessage struct {
+	ID      string          `json:"id,omitempty"`
+	Type    string          `json:"type"`
+	Payload json.RawMessage `json:"payload,omitempty"`
+}
+
+/* graphQLWSConn is one graphql-transport-ws connection and its running operations */
+type graphQLWSConn struct {
+	ws     *websocket.Conn
+	schema *usergraphql.Schema
+
+	writeMu sync.Mutex // operations write concurrently
+
+	mu         sync.Mutex
+	operations map[string]*graphQLWSOperation
+}
+
+type graphQLWSOperation struct {
+	cancel context.CancelFunc
+}
+
+func (conn *graphQLWSConn) serve(ctx context.Context, heartbeat time.Duration) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	conn.ws.SetReadDeadline(time.Now().Add(connectionInitTimeout))
+	acknowledged := false
+	for {
+		var data []byte
+		if err := websocket.Message.Receive(conn.ws, &data); err != nil {
+			var netErr net.Error
+			if errors.As(err, &netErr) && netErr.Timeout() && !acknowledged {
+				conn.close(closeInitTimeout, "Connection initialisation timeout")
+			}
+			return
+		}
+		var msg graphQLWSMessage
+		if err := json.Unmarshal(data, &msg); err != nil {
+			conn.close(closeInvalidMessage, "Invalid message received")
+			return
+		}
+
+		switch msg.Type {
+		case "connection_init":
+			if acknowledged {
+				conn.close(closeTooManyInitRequest, "Too many initialisation requests")
+				return
+			}
+			acknowledged = true
+			conn.ws.SetReadDeadline(time.Time{})
+			conn.send(graphQLWSMessage{Type: "connection_ack"})
+			if heartbeat > 0 {
+				go conn.ping(ctx, heartbeat)
+			}
+		case "ping":
+			conn.send(graphQLWSMessage{Type: "pong"})
+		case "pong":
+		case "subscribe":
+			if !acknowledged {
+				conn.close(closeUnauthorized, "Unauthorized")
+				return
+			}
+			var req usergraphql.Request
+			if msg.ID == "" || json.Unmarshal(msg.Payload, &req) != nil {
+				conn.close(closeInvalidMessage, "Invalid message received")
+				return
+			}
+			if !conn.start(ctx, msg.ID, req) {
+				conn.close(closeSubscriberExists, "Subscriber for "+msg.ID+" already exists")
+				return
+			}
+		case "complete":
+			conn.stop(msg.ID)
+		default:
+			conn.close(closeInvalidMessage, "Invalid message received")
+			return
+		}
+	}
+}
+
+/* start runs an operation until it ends or the client completes it; false if the id is in use */
+func (conn *graphQLWSConn) start(ctx context.Context, id string, req usergraphql.Request) bool {
+	conn.mu.Lock()
+	defer conn.mu.Unlock()
+	if _, exists := conn.operations[id]; exists {
+		return false
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	operation := &graphQLWSOperation{cancel: cancel}
+	conn.operations[id] = operation
+
+	go func() {
+		defer conn.finish(id, operation)
+		for response := range conn.schema.Subscribe(ctx, req) {
+			result := response.(*graphql.Response)
+			// Errors without data mean the operation never ran
+			if result.Data == nil && len(result.Errors) > 0 {
+				payload, _ := json.Marshal(result.Errors)
+				conn.send(graphQLWSMessage{ID: id, Type: "error", Payload: payload})
+				return
+			}
+			payload, _ := json.Marshal(result)
+			conn.send(graphQLWSMessage{ID: id, Type: "next", Payload: payload})
+		}
+		// Operations the client completed or dropped end without a message
+		if ctx.Err() == nil {
+			conn.send(graphQLWSMessage{ID: id, Type: "complete"})
+		}
+	}()
+	return true
+}
+
+/* stop cancels an operation the client completed */
+func (conn *graphQLWSConn) stop(id string) {
+	conn.mu.Lock()
+	defer conn.mu.Unlock()
+	if operation, ok := conn.operations[id]; ok {
+		operation.cancel()
+		delete(conn.operations, id)
+	}
+}
+
+/* finish releases an operation that ended, unless its id was already reused */
+func (conn *graphQLWSConn) finish(id string, operation *graphQLWSOperation) {
+	operation.cancel()
+	conn.mu.Lock()
+	defer conn.mu.Unlock()
+	if conn.operations[id] == operation {
+		delete(conn.operations, id)
+	}
+}
+
+func (conn *graphQLWSConn) ping(ctx context.Context, heartbeat time.Duration) {
+	ticker := time.NewTicker(heartbeat)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if conn.send(graphQLWSMessage{Type: "ping"}) != nil {
+				return
+			}
+		}
+	}
+}
+
+func (conn *graphQLWSConn) send(msg graphQLWSMessage) error {
+	conn.writeMu.Lock()
+	defer conn.writeMu.Unlock()
+	conn.ws.SetWriteDeadline(time.Now().Add(streamWriteTimeout))
+	return websocket.JSON.Send(conn.ws, msg)
+}
+
+/* close sends a close frame with a protocol code; golang.org/x/net/websocket only closes with 1000 itself */
+func (conn *graphQLWSConn) close(code uint16, reason string) {
+	conn.writeMu.Lock()
+	defer conn.writeMu.Unlock()
+	payload := binary.BigEndian.AppendUint16(nil, code)
+	conn.ws.SetWriteDeadline(time.Now().Add(streamWriteTimeout))
+	conn.ws.PayloadType = websocket.CloseFrame
+	conn.ws.Write(append(payload, reason...))
+}
@@ -0,0 +1,44 @@
+package handlers
+
+import (
+	"encoding/json"
+	"strings"
+
+	"baseApi/dto"
+	"baseApi/openapi"
+
+	"github.com/gin-gonic/gin"
+	swaggerFiles "github.com/swaggo/files"
+)
+
+type OpenAPIHandler struct {
+	spec []byte
+}
+
+/* NewOpenAPIHandler generates the OpenAPI document of the given routes */
+func NewOpenAPIHandler(routes gin.RoutesInfo, version string) (*OpenAPIHandler, error) {
+	doc, err := openapi.Build(routes, version)
+	if err != nil {
+		return nil, err
+	}
+	spec, err := json.Marshal(doc)
+	if err != nil {
+		return nil, err
+	}
+	return &OpenAPIHandler{spec: spec}, nil
+}
+
+/* GetSpec serves the OpenAPI 3.1 document */
+func (h *OpenAPIHandler) GetSpec(c *gin.Context) {
+	c.Data(dto.StatusOK, dto.ContentTypeJSON, h.spec)
+}
+
+/* SwaggerUI serves the Swagger UI page and its embedded assets */
+func (h *OpenAPIHandler) SwaggerUI(c *gin.Context) {
+	file := strings.TrimPrefix(c.Param("any"), "/")
+	if file == "" || file == "index.html" {
+		c.Data(dto.StatusOK, "text/html; charset=utf-8", openapi.SwaggerUI)
+		return
+	}
+	c.FileFromFS(file, swaggerFiles.HTTP)
+}
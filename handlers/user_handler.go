@@ -1,13 +1,22 @@
 package handlers
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
 	"strconv"
+	"strings"
+	"time"
 
+	"baseApi/apperror"
 	"baseApi/dto"
+	"baseApi/i18n"
 	"baseApi/logger"
 	"baseApi/middleware"
-	"baseApi/monitoring"
 	"baseApi/services"
+	"baseApi/validation"
 
 	"github.com/gin-gonic/gin"
 )
@@ -27,18 +36,7 @@ func NewUserHandler() *UserHandler {
 func (h *UserHandler) CreateUser(c *gin.Context) {
 	var req dto.CreateUserRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		logger.Error("Invalid request body:", err)
-		response := dto.ValidationErrorResponse([]dto.ValidationError{
-			{Field: "request", Message: "Invalid request format", Value: err.Error()},
-		})
-		c.JSON(response.StatusCode, response)
-		return
-	}
-
-	// Additional validation
-	if validationErrors := req.Validate(); len(validationErrors) > 0 {
-		response := dto.ValidationErrorResponse(validationErrors)
-		c.JSON(response.StatusCode, response)
+		c.Error(apperror.Validation(validation.Translate(err)))
 		return
 	}
 
@@ -50,28 +48,18 @@ func (h *UserHandler) CreateUser(c *gin.Context) {
 	}
 
 	if err != nil {
-		// Capture error to Sentry with context
-		monitoring.CaptureError(err, map[string]interface{}{
+		c.Error(err).SetMeta(map[string]interface{}{
 			"operation": "create_user",
 			"username":  req.Username,
 			"email":     req.Email,
 		})
-
-		logger.Error("Failed to create user:", err)
-		response := dto.ErrorResponseWithDetails(
-			dto.StatusInternalServerError,
-			dto.ErrorCodeDatabaseError,
-			"Failed to create user",
-			err.Error(),
-		)
-		c.JSON(response.StatusCode, response)
 		return
 	}
 
 	logger.Info("User created successfully:", user.ID)
 	response := dto.SuccessResponse(
 		dto.StatusCreated,
-		"User created successfully",
+		i18n.T(middleware.Locale(c), "success.user_created", nil),
 		user,
 	)
 	c.JSON(response.StatusCode, response)
@@ -79,50 +67,36 @@ func (h *UserHandler) CreateUser(c *gin.Context) {
 
 /* GetUser handles retrieving a user by ID */
 func (h *UserHandler) GetUser(c *gin.Context) {
-	idStr := c.Param("id")
-	id, err := strconv.ParseUint(idStr, 10, 32)
+	id, err := parseUserID(c)
 	if err != nil {
-		response := dto.BadRequestResponse("Invalid user ID format")
-		c.JSON(response.StatusCode, response)
+		c.Error(err)
 		return
 	}
 
 	// Start Sentry span for service call
 	span := middleware.StartSpanFromContext(c, "user.get_by_id", "Get user by ID")
-	user, err := h.userService.GetUserByID(uint(id))
+	user, err := h.userService.GetUserByID(id)
 	if span != nil {
 		span.Finish()
 	}
 
 	if err != nil {
-		if err.Error() == "user not found" {
-			// Add breadcrumb for not found
-			monitoring.AddBreadcrumb("User not found", "user", map[string]interface{}{
-				"user_id": id,
-			})
-			response := dto.NotFoundResponse("User")
-			c.JSON(response.StatusCode, response)
-			return
-		}
-
-		// Capture error to Sentry
-		monitoring.CaptureError(err, map[string]interface{}{
+		c.Error(err).SetMeta(map[string]interface{}{
 			"operation": "get_user_by_id",
 			"user_id":   id,
 		})
+		return
+	}
 
-		logger.Error("Failed to get user:", err)
-		response := dto.ErrorResponseWithDetails(
-			dto.StatusInternalServerError,
-			dto.ErrorCodeDatabaseError,
-			"Failed to retrieve user",
-			err.Error(),
-		)
-		c.JSON(response.StatusCode, response)
+	if middleware.NotModified(c, userETag(user.Version, middleware.Locale(c)), user.UpdatedAt) {
 		return
 	}
 
-	response := dto.SuccessResponse(dto.StatusOK, "User retrieved successfully", user)
+	response := dto.SuccessResponse(
+		dto.StatusOK,
+		i18n.T(middleware.Locale(c), "success.user_retrieved", nil),
+		user,
+	)
 	c.JSON(response.StatusCode, response)
 }
 
@@ -130,30 +104,25 @@ func (h *UserHandler) GetUser(c *gin.Context) {
 func (h *UserHandler) GetUserByUsername(c *gin.Context) {
 	username := c.Param("username")
 	if username == "" {
-		response := dto.BadRequestResponse("Username parameter is required")
-		c.JSON(response.StatusCode, response)
+		c.Error(apperror.BadRequest("Username parameter is required").WithKey("error.username_required", nil))
 		return
 	}
 
 	user, err := h.userService.GetUserByUsername(username)
 	if err != nil {
-		if err.Error() == "user not found" {
-			response := dto.NotFoundResponse("User")
-			c.JSON(response.StatusCode, response)
-			return
-		}
-		logger.Error("Failed to get user:", err)
-		response := dto.ErrorResponseWithDetails(
-			dto.StatusInternalServerError,
-			dto.ErrorCodeDatabaseError,
-			"Failed to retrieve user",
-			err.Error(),
-		)
-		c.JSON(response.StatusCode, response)
+		c.Error(err)
 		return
 	}
 
-	response := dto.SuccessResponse(dto.StatusOK, "User retrieved successfully", user)
+	if middleware.NotModified(c, userETag(user.Version, middleware.Locale(c)), user.UpdatedAt) {
+		return
+	}
+
+	response := dto.SuccessResponse(
+		dto.StatusOK,
+		i18n.T(middleware.Locale(c), "success.user_retrieved", nil),
+		user,
+	)
 	c.JSON(response.StatusCode, response)
 }
 
@@ -163,8 +132,7 @@ func (h *UserHandler) GetAllUsers(c *gin.Context) {
 
 	// Bind query parameters
 	if err := c.ShouldBindQuery(&searchReq); err != nil {
-		response := dto.BadRequestResponse("Invalid query parameters")
-		c.JSON(response.StatusCode, response)
+		c.Error(apperror.Validation(validation.Translate(err)))
 		return
 	}
 
@@ -179,83 +147,97 @@ func (h *UserHandler) GetAllUsers(c *gin.Context) {
 	}
 
 	if err != nil {
-		// Capture error to Sentry with search context
-		monitoring.CaptureError(err, map[string]interface{}{
-			"operation":   "get_all_users",
+		c.Error(err).SetMeta(map[string]interface{}{
+			"operation":    "get_all_users",
 			"search_query": searchReq.Query,
-			"page":        searchReq.Page,
-			"limit":       searchReq.Limit,
+			"page":         searchReq.Page,
+			"limit":        searchReq.Limit,
 		})
-
-		logger.Error("Failed to get users:", err)
-		response := dto.ErrorResponseWithDetails(
-			dto.StatusInternalServerError,
-			dto.ErrorCodeDatabaseError,
-			"Failed to retrieve users",
-			err.Error(),
-		)
-		c.JSON(response.StatusCode, response)
 		return
 	}
 
 	response := dto.SuccessResponseWithPagination(
 		dto.StatusOK,
-		"Users retrieved successfully",
+		i18n.T(middleware.Locale(c), "success.users_retrieved", nil),
 		userList.Users,
 		&userList.Pagination,
 	)
+
+	// Deletes and page shifts don't move any UpdatedAt, so the list is validated by content hash only
+	if middleware.NotModified(c, contentETag(response), time.Time{}) {
+		return
+	}
+
 	c.JSON(response.StatusCode, response)
 }
 
 /* UpdateUser handles user updates */
 func (h *UserHandler) UpdateUser(c *gin.Context) {
-	idStr := c.Param("id")
-	id, err := strconv.ParseUint(idStr, 10, 32)
+	id, err := parseUserID(c)
 	if err != nil {
-		response := dto.BadRequestResponse("Invalid user ID format")
-		c.JSON(response.StatusCode, response)
+		c.Error(err)
 		return
 	}
 
 	var req dto.UpdateUserRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		logger.Error("Invalid request body:", err)
-		response := dto.ValidationErrorResponse([]dto.ValidationError{
-			{Field: "request", Message: "Invalid request format", Value: err.Error()},
-		})
-		c.JSON(response.StatusCode, response)
+		c.Error(apperror.Validation(validation.Translate(err)))
 		return
 	}
 
-	// Additional validation
-	if validationErrors := req.Validate(); len(validationErrors) > 0 {
-		response := dto.ValidationErrorResponse(validationErrors)
-		c.JSON(response.StatusCode, response)
+	user, err := h.userService.UpdateUser(id, req, parseIfMatch(c)...)
+	if err != nil {
+		c.Error(err)
 		return
 	}
 
-	user, err := h.userService.UpdateUser(uint(id), req)
+	logger.Info("User updated successfully:", user.ID)
+	c.Header("ETag", userETag(user.Version, middleware.Locale(c)))
+	response := dto.SuccessResponse(
+		dto.StatusOK,
+		i18n.T(middleware.Locale(c), "success.user_updated", nil),
+		user,
+	)
+	c.JSON(response.StatusCode, response)
+}
+
+/* PatchUser handles partial user updates with JSON Merge Patch or JSON Patch */
+func (h *UserHandler) PatchUser(c *gin.Context) {
+	id, err := parseUserID(c)
 	if err != nil {
-		if err.Error() == "user not found" {
-			response := dto.NotFoundResponse("User")
-			c.JSON(response.StatusCode, response)
-			return
-		}
-		logger.Error("Failed to update user:", err)
-		response := dto.ErrorResponseWithDetails(
-			dto.StatusInternalServerError,
-			dto.ErrorCodeDatabaseError,
-			"Failed to update user",
-			err.Error(),
-		)
-		c.JSON(response.StatusCode, response)
+		c.Error(err)
 		return
 	}
 
-	logger.Info("User updated successfully:", user.ID)
+	contentType := c.ContentType()
+	if contentType != dto.ContentTypeMergePatch && contentType != dto.ContentTypeJSONPatch {
+		c.Header("Accept-Patch", dto.ContentTypeMergePatch+", "+dto.ContentTypeJSONPatch)
+		c.Error(services.ErrUnsupportedPatchType)
+		return
+	}
+
+	patch, err := io.ReadAll(c.Request.Body)
+	if err != nil || len(patch) == 0 {
+		c.Error(apperror.BadRequest("Patch document is required").WithKey("error.patch_required", nil))
+		return
+	}
+
+	span := middleware.StartSpanFromContext(c, "user.patch", "Patch user")
+	user, err := h.userService.PatchUser(id, contentType, patch, parseIfMatch(c)...)
+	if span != nil {
+		span.Finish()
+	}
+
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	logger.Info("User patched successfully:", user.ID)
+	c.Header("ETag", userETag(user.Version, middleware.Locale(c)))
 	response := dto.SuccessResponse(
 		dto.StatusOK,
-		"User updated successfully",
+		i18n.T(middleware.Locale(c), "success.user_updated", nil),
 		user,
 	)
 	c.JSON(response.StatusCode, response)
@@ -263,37 +245,82 @@ func (h *UserHandler) UpdateUser(c *gin.Context) {
 
 /* DeleteUser handles user deletion */
 func (h *UserHandler) DeleteUser(c *gin.Context) {
-	idStr := c.Param("id")
-	id, err := strconv.ParseUint(idStr, 10, 32)
+	id, err := parseUserID(c)
 	if err != nil {
-		response := dto.BadRequestResponse("Invalid user ID format")
-		c.JSON(response.StatusCode, response)
+		c.Error(err)
 		return
 	}
 
-	err = h.userService.DeleteUser(uint(id))
-	if err != nil {
-		if err.Error() == "user not found" {
-			response := dto.NotFoundResponse("User")
-			c.JSON(response.StatusCode, response)
-			return
-		}
-		logger.Error("Failed to delete user:", err)
-		response := dto.ErrorResponseWithDetails(
-			dto.StatusInternalServerError,
-			dto.ErrorCodeDatabaseError,
-			"Failed to delete user",
-			err.Error(),
-		)
-		c.JSON(response.StatusCode, response)
+	if err := h.userService.DeleteUser(id, parseIfMatch(c)...); err != nil {
+		c.Error(err)
 		return
 	}
 
 	logger.Info("User deleted successfully:", id)
 	response := dto.SuccessResponse(
 		dto.StatusOK,
-		"User deleted successfully",
+		i18n.T(middleware.Locale(c), "success.user_deleted", nil),
 		nil,
 	)
 	c.JSON(response.StatusCode, response)
-}
\ No newline at end of file
+}
+
+/* parseUserID parses the :id path parameter */
+func parseUserID(c *gin.Context) (uint, error) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		return 0, apperror.BadRequest("Invalid user ID format").WithKey("error.invalid_user_id", nil)
+	}
+	return uint(id), nil
+}
+
+/*
+userETag formats a user version as a strong entity tag ("3-en").
+
+The body carries a localized message, so each locale is a different representation
+and gets its own tag; If-Match only looks at the version (see parseIfMatch).
+*/
+func userETag(version uint, locale string) string {
+	return fmt.Sprintf("\"%d-%s\"", version, locale)
+}
+
+/* contentETag derives a weak entity tag from the JSON representation of a response */
+func contentETag(v interface{}) string {
+	body, err := json.Marshal(v)
+	if err != nil {
+		return ""
+	}
+	sum := sha256.Sum256(body)
+	return fmt.Sprintf("W/\"%s\"", hex.EncodeToString(sum[:16]))
+}
+
+/* parseIfMatch returns the user versions listed in If-Match; nil means the write is unconditional */
+func parseIfMatch(c *gin.Context) []uint {
+	header := strings.TrimSpace(c.GetHeader("If-Match"))
+	if header == "" {
+		return nil
+	}
+
+	var versions []uint
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimSpace(tag)
+		if tag == "*" {
+			return nil
+		}
+		// Weak tags never match under the strong comparison If-Match requires
+		if !strings.HasPrefix(tag, "\"") || !strings.HasSuffix(tag, "\"") || len(tag) < 2 {
+			continue
+		}
+		// "3-en" from userETag, or a bare "3"
+		version, _, _ := strings.Cut(tag[1:len(tag)-1], "-")
+		if v, err := strconv.ParseUint(version, 10, 32); err == nil {
+			versions = append(versions, uint(v))
+		}
+	}
+
+	// Versions start at 1, so 0 keeps a header with no usable tags from matching anything
+	if len(versions) == 0 {
+		return []uint{0}
+	}
+	return versions
+}
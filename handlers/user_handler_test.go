@@ -0,0 +1,45 @@
+package handlers
+
+import (
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+/* TestParseIfMatch reads user versions from If-Match headers */
+func TestParseIfMatch(t *testing.T) {
+	tests := []struct {
+		name   string
+		header string
+		want   []uint
+	}{
+		{"no header", "", nil},
+		{"wildcard", "*", nil},
+		{"wildcard in a list", `"3-en", *`, nil},
+		{"localized tag", `"3-en"`, []uint{3}},
+		{"bare version", `"3"`, []uint{3}},
+		{"tag of another locale", `"3-vi"`, []uint{3}},
+		{"several tags", `"3-en", "4-vi"`, []uint{3, 4}},
+		{"weak tag is skipped", `W/"2-en", "3-en"`, []uint{3}},
+		{"only weak tags", `W/"3-en"`, []uint{0}},
+		{"unquoted tag", `3-en`, []uint{0}},
+		{"not a version", `"abc"`, []uint{0}},
+		{"empty tag", `""`, []uint{0}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, _ := gin.CreateTestContext(httptest.NewRecorder())
+			c.Request = httptest.NewRequest("PUT", "/v1/users/7", nil)
+			if tt.header != "" {
+				c.Request.Header.Set("If-Match", tt.header)
+			}
+
+			if got := parseIfMatch(c); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseIfMatch(%q) = %v, want %v", tt.header, got, tt.want)
+			}
+		})
+	}
+}
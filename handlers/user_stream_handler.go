@@ -0,0 +1,155 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"baseApi/apperror"
+	"baseApi/config"
+	"baseApi/dto"
+	"baseApi/services"
+	"baseApi/streaming"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/net/websocket"
+)
+
+const (
+	// sseRetry is the reconnect delay suggested to EventSource clients
+	sseRetry = 3 * time.Second
+	// streamWriteTimeout drops WebSocket clients that stop reading
+	streamWriteTimeout = 10 * time.Second
+	// maxEventIDLength bounds Last-Event-ID; CloudEvent ids are UUIDs
+	maxEventIDLength = 64
+)
+
+type UserStreamHandler struct {
+	streamService *services.UserStreamService
+	hub           *streaming.Hub
+	heartbeat     time.Duration
+}
+
+/* NewUserStreamHandler creates a user stream handler on the process-wide hub */
+func NewUserStreamHandler(cfg *config.Config) *UserStreamHandler {
+	return &UserStreamHandler{
+		streamService: services.NewUserStreamService(cfg.UserStreamReplayLimit),
+		hub:           streaming.GetHub(),
+		heartbeat:     cfg.UserStreamHeartbeat,
+	}
+}
+
+/*
+StreamUsers pushes user changes as Server-Sent Events.
+
+Each event is "id: <CloudEvent id>", "event: user.created|user.updated|user.deleted"
+and the CloudEvent as data. EventSource reconnects send Last-Event-ID and receive
+the events they missed; when those can't be replayed a "reset" event tells the
+client to reload the list.
+*/
+func (h *UserStreamHandler) StreamUsers(c *gin.Context) {
+	filter, lastEventID, err := parseStreamRequest(c, c.GetHeader("Last-Event-ID"))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	flusher, ok := c.Writer.(http.Flusher)
+	if !ok {
+		c.Error(apperror.Internal(dto.ErrorCodeInternalServer, "Streaming unsupported", errors.New("response writer can't flush")))
+		return
+	}
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no") // disable proxy buffering (nginx)
+	c.Status(http.StatusOK)
+	fmt.Fprintf(c.Writer, "retry: %d\n\n", sseRetry.Milliseconds())
+	flusher.Flush()
+
+	send := func(e streaming.Event) error {
+		if e.ID != "" {
+			fmt.Fprintf(c.Writer, "id: %s\n", e.ID)
+		}
+		_, err := fmt.Fprintf(c.Writer, "event: %s\ndata: %s\n\n", e.Event, e.Data)
+		flusher.Flush()
+		return err
+	}
+	ping := func() error {
+		_, err := fmt.Fprint(c.Writer, ": ping\n\n")
+		flusher.Flush()
+		return err
+	}
+
+	h.streamService.Serve(c.Request.Context(), h.hub, filter, lastEventID, h.heartbeat, send, ping)
+}
+
+/*
+StreamUsersWebSocket pushes the same events as JSON text frames
+{"id": ..., "event": ..., "data": <CloudEvent>}.
+
+Clients resume with ?lastEventId=<id of the last event received>.
+*/
+func (h *UserStreamHandler) StreamUsersWebSocket(c *gin.Context) {
+	filter, lastEventID, err := parseStreamRequest(c, c.Query("lastEventId"))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	server := websocket.Server{
+		// The token (not cookies) authorizes the connection, so any Origin is fine
+		Handshake: func(*websocket.Config, *http.Request) error { return nil },
+		Handler: func(ws *websocket.Conn) {
+			defer ws.Close()
+
+			// Reading handles pings and notices when the client goes away
+			ctx, cancel := context.WithCancel(c.Request.Context())
+			defer cancel()
+			go func() {
+				defer cancel()
+				var discard string
+				for websocket.Message.Receive(ws, &discard) == nil {
+				}
+			}()
+
+			send := func(e streaming.Event) error {
+				ws.SetWriteDeadline(time.Now().Add(streamWriteTimeout))
+				return websocket.JSON.Send(ws, e)
+			}
+			ping := func() error {
+				ws.SetWriteDeadline(time.Now().Add(streamWriteTimeout))
+				ws.PayloadType = websocket.PingFrame
+				_, err := ws.Write(nil)
+				return err
+			}
+
+			h.streamService.Serve(ctx, h.hub, filter, lastEventID, h.heartbeat, send, ping)
+		},
+	}
+	server.ServeHTTP(c.Writer, c.Request)
+}
+
+/* parseStreamRequest reads the ?events= / ?userId= filters and the resume point */
+func parseStreamRequest(c *gin.Context, lastEventID string) (streaming.Filter, string, error) {
+	filter, err := streaming.ParseFilter(c.Query("events"), c.Query("userId"))
+	var filterErr *streaming.FilterError
+	if errors.As(err, &filterErr) {
+		return filter, "", apperror.Validation([]dto.ValidationError{{
+			Field:   filterErr.Field,
+			Rule:    "invalid",
+			Message: filterErr.Error(),
+			Value:   filterErr.Value,
+			Key:     "validation.invalid",
+			Params:  map[string]string{"field": filterErr.Field},
+		}})
+	}
+
+	if len(lastEventID) > maxEventIDLength {
+		return filter, "", apperror.BadRequest("Invalid Last-Event-ID").WithKey("error.invalid_last_event_id", nil)
+	}
+	return filter, lastEventID, nil
+}
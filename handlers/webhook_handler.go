@@ -0,0 +1,210 @@
+package handlers
+
+import (
+	"strconv"
+
+	"baseApi/apperror"
+	"baseApi/dto"
+	"baseApi/i18n"
+	"baseApi/logger"
+	"baseApi/middleware"
+	"baseApi/services"
+	"baseApi/validation"
+
+	"github.com/gin-gonic/gin"
+)
+
+type WebhookHandler struct {
+	webhookService *services.WebhookService
+}
+
+/* NewWebhookHandler creates a new webhook handler */
+func NewWebhookHandler() *WebhookHandler {
+	return &WebhookHandler{
+		webhookService: services.NewWebhookService(),
+	}
+}
+
+/* CreateWebhook handles registering a webhook endpoint */
+func (h *WebhookHandler) CreateWebhook(c *gin.Context) {
+	var req dto.CreateWebhookRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(apperror.Validation(validation.Translate(err)))
+		return
+	}
+
+	webhook, err := h.webhookService.CreateWebhook(req)
+	if err != nil {
+		c.Error(err).SetMeta(map[string]interface{}{
+			"operation": "create_webhook",
+			"url":       req.URL,
+		})
+		return
+	}
+
+	logger.Info("Webhook created successfully:", webhook.ID)
+	response := dto.SuccessResponse(
+		dto.StatusCreated,
+		i18n.T(middleware.Locale(c), "success.webhook_created", nil),
+		webhook,
+	)
+	c.JSON(response.StatusCode, response)
+}
+
+/* GetWebhooks handles listing webhook endpoints */
+func (h *WebhookHandler) GetWebhooks(c *gin.Context) {
+	webhooks, err := h.webhookService.ListWebhooks()
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	response := dto.SuccessResponse(
+		dto.StatusOK,
+		i18n.T(middleware.Locale(c), "success.webhooks_retrieved", nil),
+		webhooks,
+	)
+	c.JSON(response.StatusCode, response)
+}
+
+/* GetWebhook handles retrieving a webhook endpoint by ID */
+func (h *WebhookHandler) GetWebhook(c *gin.Context) {
+	id, err := parseWebhookID(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	webhook, err := h.webhookService.GetWebhook(id)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	response := dto.SuccessResponse(
+		dto.StatusOK,
+		i18n.T(middleware.Locale(c), "success.webhook_retrieved", nil),
+		webhook,
+	)
+	c.JSON(response.StatusCode, response)
+}
+
+/* UpdateWebhook handles updating (and re-enabling) a webhook endpoint */
+func (h *WebhookHandler) UpdateWebhook(c *gin.Context) {
+	id, err := parseWebhookID(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	var req dto.UpdateWebhookRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(apperror.Validation(validation.Translate(err)))
+		return
+	}
+
+	webhook, err := h.webhookService.UpdateWebhook(id, req)
+	if err != nil {
+		c.Error(err).SetMeta(map[string]interface{}{
+			"operation":  "update_webhook",
+			"webhook_id": id,
+		})
+		return
+	}
+
+	logger.Info("Webhook updated successfully:", id)
+	response := dto.SuccessResponse(
+		dto.StatusOK,
+		i18n.T(middleware.Locale(c), "success.webhook_updated", nil),
+		webhook,
+	)
+	c.JSON(response.StatusCode, response)
+}
+
+/* DeleteWebhook handles deleting a webhook endpoint */
+func (h *WebhookHandler) DeleteWebhook(c *gin.Context) {
+	id, err := parseWebhookID(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	if err := h.webhookService.DeleteWebhook(id); err != nil {
+		c.Error(err)
+		return
+	}
+
+	logger.Info("Webhook deleted successfully:", id)
+	response := dto.SuccessResponse(
+		dto.StatusOK,
+		i18n.T(middleware.Locale(c), "success.webhook_deleted", nil),
+		nil,
+	)
+	c.JSON(response.StatusCode, response)
+}
+
+/* GetDeliveries handles listing a webhook's delivery log */
+func (h *WebhookHandler) GetDeliveries(c *gin.Context) {
+	id, err := parseWebhookID(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	var searchReq dto.WebhookDeliverySearchRequest
+	if err := c.ShouldBindQuery(&searchReq); err != nil {
+		c.Error(apperror.Validation(validation.Translate(err)))
+		return
+	}
+	searchReq.SetDefaults()
+
+	deliveries, err := h.webhookService.ListDeliveries(id, searchReq)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	response := dto.SuccessResponseWithPagination(
+		dto.StatusOK,
+		i18n.T(middleware.Locale(c), "success.webhook_deliveries_retrieved", nil),
+		deliveries.Deliveries,
+		&deliveries.Pagination,
+	)
+	c.JSON(response.StatusCode, response)
+}
+
+/* GetDelivery handles retrieving one delivery, including its payload */
+func (h *WebhookHandler) GetDelivery(c *gin.Context) {
+	id, err := parseWebhookID(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	deliveryID, err := strconv.ParseUint(c.Param("deliveryId"), 10, 64)
+	if err != nil {
+		c.Error(apperror.BadRequest("Invalid delivery ID format").WithKey("error.invalid_delivery_id", nil))
+		return
+	}
+
+	delivery, err := h.webhookService.GetDelivery(id, deliveryID)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	response := dto.SuccessResponse(
+		dto.StatusOK,
+		i18n.T(middleware.Locale(c), "success.webhook_delivery_retrieved", nil),
+		delivery,
+	)
+	c.JSON(response.StatusCode, response)
+}
+
+/* parseWebhookID parses the :id path parameter */
+func parseWebhookID(c *gin.Context) (uint, error) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		return 0, apperror.BadRequest("Invalid webhook ID format").WithKey("error.invalid_webhook_id", nil)
+	}
+	return uint(id), nil
+}
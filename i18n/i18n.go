@@ -0,0 +1,97 @@
+package i18n
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"golang.org/x/text/language"
+)
+
+const (
+	English    = "en"
+	Vietnamese = "vi"
+
+	// DefaultLocale is used when no supported locale can be negotiated
+	DefaultLocale = English
+)
+
+//go:embed locales/*.json
+var localeFiles embed.FS
+
+var (
+	catalogs = map[string]map[string]string{}
+	matcher  language.Matcher
+)
+
+func init() {
+	supported := []string{English, Vietnamese}
+	tags := make([]language.Tag, 0, len(supported))
+	for _, locale := range supported {
+		data, err := localeFiles.ReadFile("locales/" + locale + ".json")
+		if err != nil {
+			panic(fmt.Sprintf("i18n: missing catalog for %s: %v", locale, err))
+		}
+		catalog := map[string]string{}
+		if err := json.Unmarshal(data, &catalog); err != nil {
+			panic(fmt.Sprintf("i18n: invalid catalog for %s: %v", locale, err))
+		}
+		catalogs[locale] = catalog
+		tags = append(tags, language.Make(locale))
+	}
+	// The first tag is the matcher's fallback, so English must come first
+	matcher = language.NewMatcher(tags)
+}
+
+/* Supported reports whether a locale has a catalog */
+func Supported(locale string) bool {
+	_, ok := catalogs[locale]
+	return ok
+}
+
+/* Negotiate picks the best supported locale for an Accept-Language header */
+func Negotiate(acceptLanguage string) string {
+	if acceptLanguage == "" {
+		return DefaultLocale
+	}
+	tags, _, err := language.ParseAcceptLanguage(acceptLanguage)
+	if err != nil || len(tags) == 0 {
+		return DefaultLocale
+	}
+	_, index, confidence := matcher.Match(tags...)
+	if confidence == language.No {
+		return DefaultLocale
+	}
+	return []string{English, Vietnamese}[index]
+}
+
+/* Lookup returns the message for key in locale, falling back to English */
+func Lookup(locale, key string) (string, bool) {
+	if message, ok := catalogs[locale][key]; ok {
+		return message, true
+	}
+	message, ok := catalogs[DefaultLocale][key]
+	return message, ok
+}
+
+/* T returns the localized message for key with {name} placeholders filled from params */
+func T(locale, key string, params map[string]string) string {
+	message, ok := Lookup(locale, key)
+	if !ok {
+		return key
+	}
+	return format(message, params)
+}
+
+/* format substitutes {name} placeholders */
+func format(message string, params map[string]string) string {
+	if len(params) == 0 {
+		return message
+	}
+	replacements := make([]string, 0, len(params)*2)
+	for name, value := range params {
+		replacements = append(replacements, "{"+name+"}", value)
+	}
+	return strings.NewReplacer(replacements...).Replace(message)
+}
@@ -1,17 +1,27 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"fmt"
-	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
 	"time"
 
 	"baseApi/cache"
 	"baseApi/config"
 	"baseApi/database"
+	"baseApi/dto"
+	"baseApi/grpc"
 	"baseApi/logger"
 	"baseApi/messaging"
 	"baseApi/monitoring"
 	"baseApi/routes"
+	"baseApi/streaming"
+	"baseApi/webhooks"
 )
 
 /* main is the entry point of the application */
@@ -24,6 +34,19 @@ func main() {
 	cfg := config.LoadConfig()
 	logger.Info("Configuration loaded successfully")
 
+	// Initialize Sentry first so startup failures and background workers can report to it
+	sentryEnabled := false
+	if cfg.SentryDSN != "" {
+		if err := monitoring.InitSentry(cfg); err != nil {
+			logger.Error("Failed to initialize Sentry:", err)
+		} else {
+			logger.Info("Sentry initialized successfully")
+			sentryEnabled = true
+		}
+	} else {
+		logger.Info("Sentry DSN not provided, skipping Sentry initialization")
+	}
+
 	// Initialize database
 	database.InitDatabase(cfg)
 	logger.Info("Database initialized successfully")
@@ -32,50 +55,144 @@ func main() {
 	cache.InitRedis(cfg)
 	logger.Info("Redis cache initialized successfully")
 
-	// Initialize RabbitMQ
-	if err := messaging.InitRabbitMQ(cfg); err != nil {
-		logger.Error("Failed to initialize RabbitMQ:", err)
-		logger.Info("Continuing without RabbitMQ...")
-	} else {
-		logger.Info("RabbitMQ initialized successfully")
-		// Ensure RabbitMQ cleanup on shutdown
-		defer func() {
-			if publisher := messaging.GetRabbitMQPublisher(); publisher != nil {
-				publisher.Close()
-			}
+	// CloudEvents envelope metadata for published events
+	messaging.EventSource = cfg.EventSource
+	messaging.SchemaBaseURI = cfg.EventSchemaBaseURI
+
+	// Initialize the message broker (MESSAGE_BROKER); RabbitMQ reconnects in the background if it is down
+	if err := messaging.InitBroker(cfg); err != nil {
+		logger.Error("Failed to initialize message broker:", err)
+		logger.Info("Continuing, events stay in the outbox until the broker is reachable...")
+	}
+
+	// Background workers (outbox relay, webhook delivery) stop with workersCtx and are waited for on shutdown
+	workersCtx, stopWorkers := context.WithCancel(context.Background())
+	defer stopWorkers()
+	var workers sync.WaitGroup
+	runWorker := func(run func(ctx context.Context)) {
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			run(workersCtx)
 		}()
 	}
+	var consumers []*messaging.Consumer
 
-	// Initialize Sentry for error tracking
-	if cfg.SentryDSN != "" {
-		if err := monitoring.InitSentry(cfg); err != nil {
-			logger.Error("Failed to initialize Sentry:", err)
+	// Relay outbox events to the broker; every worker runs one, a DB lock keeps a single leader
+	if cfg.OutboxRelayEnabled {
+		runWorker(messaging.NewOutboxRelay(database.DB, cfg).Run)
+	}
+
+	// Fan user events out to webhook endpoints and deliver them; SKIP LOCKED leases let every worker run both
+	if cfg.WebhooksEnabled {
+		dispatcher := webhooks.NewDispatcher(database.DB).Consumer(cfg.WebhookQueue)
+		if err := dispatcher.Start(workersCtx); err != nil {
+			logger.Error("Failed to start webhook dispatcher:", err)
 		} else {
-			logger.Info("Sentry initialized successfully")
-			// Ensure Sentry flushes before shutdown
-			defer monitoring.FlushSentry(2 * time.Second)
+			consumers = append(consumers, dispatcher)
 		}
-	} else {
-		logger.Info("Sentry DSN not provided, skipping Sentry initialization")
+		runWorker(webhooks.NewWorker(database.DB, cfg).Run)
 	}
 
+	// Every worker reads user events on its own queue and pushes them to its SSE/WebSocket subscribers
+	if cfg.UserStreamEnabled {
+		streaming.SetHub(streaming.NewHub(cfg.UserStreamBuffer))
+		streamConsumer := streaming.GetHub().Consumer(cfg.UserStreamQueuePrefix)
+		if err := streamConsumer.Start(context.Background()); err != nil {
+			logger.Error("Failed to start user stream consumer:", err)
+		} else {
+			consumers = append(consumers, streamConsumer)
+		}
+	}
+
+	// Problem+json type URIs are derived from the ErrorCode catalog
+	dto.ProblemTypeBaseURI = cfg.ProblemTypeBaseURI
+
 	// Setup routes
-	router := routes.SetupRoutes()
+	router := routes.SetupRoutes(cfg)
 	logger.Info("Routes setup completed")
 
 	// Start gRPC server
-	// if err := grpc.StartGRPCServer(cfg); err != nil {
-	// 	logger.Error("Failed to start gRPC server:", err)
-	// 	logger.Info("Continuing without gRPC server...")
-	// } else {
-	// 	logger.Info("gRPC server started on port:", cfg.GRPCPort)
-	// }
-
-	// Start server
-	serverAddr := fmt.Sprintf(":%s", cfg.ServerPort)
-	logger.Info("Server starting on port ", cfg.ServerPort)
-	
-	if err := router.Run(serverAddr); err != nil {
-		log.Fatal("Failed to start server:", err)
+	grpcServer, err := grpc.StartGRPCServer(cfg)
+	if err != nil {
+		logger.Error("Failed to start gRPC server:", err)
+		logger.Info("Continuing without gRPC server...")
+	} else {
+		logger.Info("gRPC server started on port:", cfg.GRPCPort)
+	}
+
+	// Start server; Handler() serves h2c when the gRPC gateway enabled it
+	server := &http.Server{
+		Addr:    fmt.Sprintf(":%s", cfg.ServerPort),
+		Handler: router.Handler(),
+	}
+	serverErr := make(chan error, 1)
+	go func() {
+		logger.Info("Server starting on port ", cfg.ServerPort)
+		serverErr <- server.ListenAndServe()
+	}()
+
+	signalCtx, stopSignals := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stopSignals()
+
+	exitCode := 0
+	select {
+	case <-signalCtx.Done():
+		logger.Info("Shutdown signal received, draining...")
+	case err := <-serverErr:
+		logger.Error("Failed to start server:", err)
+		exitCode = 1
+	}
+	stopSignals() // a second signal kills the process
+
+	// Everything below shares one deadline; what hasn't drained by then is cut off
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.ShutdownTimeout)
+	defer cancel()
+
+	// End SSE/WebSocket/gRPC streams first: they would otherwise keep the servers busy until the deadline
+	streaming.GetHub().Close()
+
+	// Stop accepting requests and wait for in-flight ones
+	if err := server.Shutdown(ctx); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		logger.Error("HTTP server did not drain in time:", err)
+		server.Close()
+	}
+	if grpcServer != nil {
+		grpc.StopGRPCServer(ctx, grpcServer)
+	}
+
+	// Finish the messages being handled; unacked ones are redelivered to another worker
+	for _, consumer := range consumers {
+		if err := consumer.Shutdown(ctx); err != nil {
+			logger.Error("Consumer did not drain in time:", err)
+		}
+	}
+
+	// Stop the outbox relay and webhook worker between batches
+	stopWorkers()
+	workersDone := make(chan struct{})
+	go func() {
+		workers.Wait()
+		close(workersDone)
+	}()
+	select {
+	case <-workersDone:
+	case <-ctx.Done():
+		logger.Error("Background workers did not stop in time")
+	}
+
+	// Nothing publishes any more; events not yet relayed stay in the outbox
+	if broker := messaging.GetBroker(); broker != nil {
+		broker.Close()
+	}
+
+	// Last, so errors reported while draining are sent
+	if sentryEnabled {
+		monitoring.FlushSentry(2 * time.Second)
+	}
+	logger.Info("Shutdown complete")
+
+	if exitCode != 0 {
+		os.Exit(exitCode)
 	}
 }
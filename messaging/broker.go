@@ -0,0 +1,283 @@
+package messaging
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"baseApi/config"
+	"baseApi/logger"
+	"baseApi/monitoring"
+)
+
+// Broker backends selectable with MESSAGE_BROKER
+const (
+	BackendRabbitMQ = "rabbitmq"
+	BackendRedis    = "redis"
+	BackendMemory   = "memory"
+)
+
+// ErrNoHandler is reported for messages whose routing key matches no registered pattern
+var ErrNoHandler = errors.New("no handler registered for routing key")
+
+/* Publisher sends messages to a topic, routed by routing key */
+type Publisher interface {
+	// Publish returns once the backend has durably accepted the message
+	Publish(ctx context.Context, msg *Message) error
+	Close() error
+}
+
+/* Subscriber delivers messages whose routing key matches a router's patterns */
+type Subscriber interface {
+	// Subscribe starts consuming the named queue (consumer group) in the background
+	Subscribe(ctx context.Context, cfg ConsumerConfig, router *Router) (Subscription, error)
+}
+
+/* Subscription is a running subscriber that can be drained */
+type Subscription interface {
+	// Shutdown stops new deliveries and waits for in-flight handlers
+	Shutdown(ctx context.Context) error
+}
+
+/* Broker is a backend that both publishes and subscribes */
+type Broker interface {
+	Publisher
+	Subscriber
+	Name() string
+}
+
+/* HealthChecker is implemented by brokers that can tell whether their backend is reachable */
+type HealthChecker interface {
+	Ping(ctx context.Context) error
+}
+
+var defaultBroker Broker
+
+/* InitBroker initializes the backend selected by MESSAGE_BROKER */
+func InitBroker(cfg *config.Config) error {
+	switch cfg.MessageBroker {
+	case BackendMemory:
+		defaultBroker = NewMemoryBroker()
+	case BackendRedis:
+		broker, err := NewRedisStreamsBroker(cfg)
+		if err != nil {
+			return err
+		}
+		defaultBroker = broker
+	case BackendRabbitMQ, "":
+		// The publisher is installed (and reconnects) even when the first dial fails
+		err := InitRabbitMQ(cfg)
+		defaultBroker = GetRabbitMQPublisher()
+		return err
+	default:
+		return fmt.Errorf("unknown MESSAGE_BROKER %q (want rabbitmq, redis or memory)", cfg.MessageBroker)
+	}
+
+	logger.Info("Message broker initialized:", defaultBroker.Name())
+	return nil
+}
+
+/* GetBroker returns the configured broker, or nil before InitBroker */
+func GetBroker() Broker {
+	return defaultBroker
+}
+
+/* SetBroker replaces the configured broker (tests, single-process setups) */
+func SetBroker(broker Broker) {
+	defaultBroker = broker
+}
+
+/*
+Message is a backend-neutral message, published and delivered.
+
+Delivered messages carry acknowledgement: handlers may Ack or Nack themselves;
+otherwise the subscriber acks when the handler returns nil and nacks when it
+returns an error. Plain errors are not requeued (dead-lettered where the backend
+supports it); wrap transient failures with Requeue. With a RetryPolicy, requeued
+messages are retried after a delay and dead-lettered once MaxRetries is exhausted.
+*/
+type Message struct {
+	ID          string
+	RoutingKey  string
+	ContentType string
+	Headers     map[string]interface{}
+	Body        []byte
+	Timestamp   time.Time
+	Redelivered bool
+
+	ack     func() error
+	nack    func(requeue bool) error
+	settled bool
+}
+
+/* Ack acknowledges the message */
+func (m *Message) Ack() error {
+	if m.settled || m.ack == nil {
+		return nil
+	}
+	m.settled = true
+	return m.ack()
+}
+
+/* Nack rejects the message, optionally requeueing it */
+func (m *Message) Nack(requeue bool) error {
+	if m.settled || m.nack == nil {
+		return nil
+	}
+	m.settled = true
+	return m.nack(requeue)
+}
+
+/* Attempt returns how many times the message has been retried */
+func (m *Message) Attempt() int {
+	return headerInt(m.Headers, HeaderAttempt)
+}
+
+/* HandlerFunc processes one delivery; see Message for ack semantics */
+type HandlerFunc func(ctx context.Context, msg *Message) error
+
+/* requeueError marks a handler error as transient */
+type requeueError struct {
+	err error
+}
+
+func (e *requeueError) Error() string { return e.err.Error() }
+func (e *requeueError) Unwrap() error { return e.err }
+
+/* Requeue wraps a handler error so the message is retried */
+func Requeue(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &requeueError{err: err}
+}
+
+/* IsRequeue reports whether a handler error asked for a retry */
+func IsRequeue(err error) bool {
+	var transient *requeueError
+	return errors.As(err, &transient)
+}
+
+type route struct {
+	pattern string
+	handler HandlerFunc
+}
+
+/* Router maps topic patterns to handlers; the first matching pattern wins */
+type Router struct {
+	routes []route
+}
+
+/* Handle registers a handler for a topic pattern ("user.*", "user.#") */
+func (r *Router) Handle(pattern string, handler HandlerFunc) *Router {
+	r.routes = append(r.routes, route{pattern: pattern, handler: handler})
+	return r
+}
+
+/* Patterns returns the registered patterns in registration order */
+func (r *Router) Patterns() []string {
+	patterns := make([]string, 0, len(r.routes))
+	for _, rt := range r.routes {
+		patterns = append(patterns, rt.pattern)
+	}
+	return patterns
+}
+
+/* Matches reports whether any pattern matches the routing key */
+func (r *Router) Matches(routingKey string) bool {
+	return r.match(routingKey) != nil
+}
+
+/* match returns the handler of the first pattern matching the routing key */
+func (r *Router) match(routingKey string) HandlerFunc {
+	for _, rt := range r.routes {
+		if TopicMatch(rt.pattern, routingKey) {
+			return rt.handler
+		}
+	}
+	return nil
+}
+
+/* Dispatch runs the handler of the first matching pattern, turning a panic into an error */
+func (r *Router) Dispatch(ctx context.Context, msg *Message) (err error) {
+	handler := r.match(msg.RoutingKey)
+	if handler == nil {
+		return ErrNoHandler
+	}
+
+	defer func() {
+		if rec := recover(); rec != nil {
+			err = fmt.Errorf("handler panic: %v", rec)
+		}
+	}()
+	return handler(ctx, msg)
+}
+
+/* failureAction is what a subscriber does with a message whose handler failed */
+type failureAction int
+
+const (
+	actionReject     failureAction = iota // discard (RabbitMQ: nack, so a queue-level DLX still applies)
+	actionRequeue                         // redeliver as soon as possible
+	actionRetry                           // redeliver after the policy's delay for the next attempt
+	actionDeadLetter                      // move to the queue's dead letters
+)
+
+/* onFailure decides a failed message's fate from the error and the queue's retry policy */
+func onFailure(err error, retry *RetryPolicy, attempt int) failureAction {
+	if retry == nil {
+		if IsRequeue(err) {
+			return actionRequeue
+		}
+		return actionReject
+	}
+	if IsRequeue(err) && attempt < retry.withDefaults().MaxRetries {
+		return actionRetry
+	}
+	return actionDeadLetter
+}
+
+/* deadReason describes why a message was dead-lettered */
+func deadReason(err error) string {
+	if IsRequeue(err) {
+		return "retries_exhausted"
+	}
+	return "rejected"
+}
+
+/* reportHandlerError logs a handling failure and sends it to Sentry */
+func reportHandlerError(backend, queue string, msg *Message, err error) {
+	monitoring.CaptureError(err, map[string]interface{}{
+		"component":   backend + "_consumer",
+		"queue":       queue,
+		"routing_key": msg.RoutingKey,
+		"message_id":  msg.ID,
+		"redelivered": msg.Redelivered,
+	})
+}
+
+/* TopicMatch reports whether a routing key matches an AMQP topic pattern (* = one word, # = zero or more) */
+func TopicMatch(pattern, key string) bool {
+	return matchWords(strings.Split(pattern, "."), strings.Split(key, "."))
+}
+
+func matchWords(pattern, key []string) bool {
+	if len(pattern) == 0 {
+		return len(key) == 0
+	}
+	switch pattern[0] {
+	case "#":
+		for i := 0; i <= len(key); i++ {
+			if matchWords(pattern[1:], key[i:]) {
+				return true
+			}
+		}
+		return false
+	case "*":
+		return len(key) > 0 && matchWords(pattern[1:], key[1:])
+	default:
+		return len(key) > 0 && pattern[0] == key[0] && matchWords(pattern[1:], key[1:])
+	}
+}
@@ -0,0 +1,70 @@
+package messaging
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+const (
+	// CloudEventsSpecVersion is the CloudEvents specification version of every envelope
+	CloudEventsSpecVersion = "1.0"
+	// CloudEventsContentType is the AMQP content type of structured-mode CloudEvents
+	CloudEventsContentType = "application/cloudevents+json"
+	// EventTypePrefix namespaces CloudEvents types (com.baseapi.user.created)
+	EventTypePrefix = "com.baseapi."
+)
+
+// EventSource is the CloudEvents "source" of events published by this service
+var EventSource = "/baseapi"
+
+/* CloudEvent is a CloudEvents 1.0 envelope in structured JSON format */
+type CloudEvent struct {
+	SpecVersion     string          `json:"specversion"`
+	ID              string          `json:"id"`
+	Source          string          `json:"source"`
+	Type            string          `json:"type"`
+	Subject         string          `json:"subject,omitempty"`
+	Time            time.Time       `json:"time"`
+	DataContentType string          `json:"datacontenttype"`
+	DataSchema      string          `json:"dataschema"`
+	Data            json.RawMessage `json:"data"`
+}
+
+/* NewCloudEvent validates data against the event's schema and wraps it in an envelope */
+func NewCloudEvent(eventName, version, subject string, data interface{}) (*CloudEvent, error) {
+	body, err := json.Marshal(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal %s data: %w", eventName, err)
+	}
+	if err := ValidateEventData(eventName, version, body); err != nil {
+		return nil, err
+	}
+
+	return &CloudEvent{
+		SpecVersion:     CloudEventsSpecVersion,
+		ID:              newEventID(),
+		Source:          EventSource,
+		Type:            EventTypePrefix + eventName,
+		Subject:         subject,
+		Time:            time.Now().UTC(),
+		DataContentType: "application/json",
+		DataSchema:      SchemaURI(eventName, version),
+		Data:            body,
+	}, nil
+}
+
+/* newEventID generates a random UUIDv4 event ID */
+func newEventID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return fmt.Sprintf("%d", time.Now().UnixNano())
+	}
+	b[6] = (b[6] & 0x0f) | 0x40
+	b[8] = (b[8] & 0x3f) | 0x80
+
+	h := hex.EncodeToString(b)
+	return h[0:8] + "-" + h[8:12] + "-" + h[12:16] + "-" + h[16:20] + "-" + h[20:32]
+}
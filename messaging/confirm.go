@@ -0,0 +1,245 @@
+package messaging
+
+import (
+	"errors"
+	"expvar"
+	"fmt"
+	"sync"
+	"time"
+
+	"baseApi/monitoring"
+
+	"github.com/streadway/amqp"
+)
+
+var (
+	// ErrNacked is returned when the broker refuses a message (negative confirm)
+	ErrNacked = errors.New("RabbitMQ nacked the message")
+	// ErrUnroutable is returned when a message matched no queue (RabbitMQ mandatory return, memory bus)
+	ErrUnroutable = errors.New("message was returned as unroutable")
+	// ErrConfirmTimeout is returned when no confirm arrives in time
+	ErrConfirmTimeout = errors.New("timed out waiting for RabbitMQ publisher confirm")
+)
+
+// publishStats counts publisher outcomes; exposed through expvar (/debug/vars)
+var publishStats = expvar.NewMap("rabbitmq_publisher")
+
+/* PublishOption customizes a single publish */
+type PublishOption func(*publishOptions)
+
+type publishOptions struct {
+	waitConfirm bool
+	timeout     time.Duration
+}
+
+/* WithConfirm waits for the broker's confirm, using the configured confirm timeout */
+func WithConfirm() PublishOption {
+	return func(o *publishOptions) {
+		o.waitConfirm = true
+	}
+}
+
+/* WithConfirmTimeout waits for the broker's confirm up to timeout */
+func WithConfirmTimeout(timeout time.Duration) PublishOption {
+	return func(o *publishOptions) {
+		o.waitConfirm = true
+		o.timeout = timeout
+	}
+}
+
+/* pendingConfirm tracks one published message until its confirm arrives */
+type pendingConfirm struct {
+	routingKey string
+	messageID  string
+	returned   *amqp.Return
+	done       chan error // nil when nobody waits for the outcome
+}
+
+/*
+confirmChannel is a channel in confirm mode that matches confirms to messages.
+
+Delivery tags count publishes per channel starting at 1; the pool lends a channel
+to one publisher at a time, so the next tag is known before publishing. The broker
+sends basic.return before the basic.ack of the same message, so returns are
+drained before each confirm is resolved and matched by message ID.
+*/
+type confirmChannel struct {
+	*amqp.Channel
+
+	mu        sync.Mutex
+	nextTag   uint64
+	pending   map[uint64]*pendingConfirm
+	byMessage map[string]uint64
+}
+
+/* newConfirmChannel puts ch in confirm mode and starts resolving its confirms and returns */
+func newConfirmChannel(ch *amqp.Channel) (*confirmChannel, error) {
+	if err := ch.Confirm(false); err != nil {
+		return nil, fmt.Errorf("failed to enable publisher confirms: %w", err)
+	}
+
+	cc := &confirmChannel{
+		Channel:   ch,
+		pending:   make(map[uint64]*pendingConfirm),
+		byMessage: make(map[string]uint64),
+	}
+	confirms := ch.NotifyPublish(make(chan amqp.Confirmation, 256))
+	returns := ch.NotifyReturn(make(chan amqp.Return, 256))
+	go cc.listen(confirms, returns)
+	return cc, nil
+}
+
+/* publish sends a mandatory message and returns a channel receiving its outcome when wait is set */
+func (cc *confirmChannel) publish(exchange, routingKey string, msg amqp.Publishing, wait bool) (<-chan error, error) {
+	if msg.MessageId == "" {
+		msg.MessageId = newEventID()
+	}
+
+	p := &pendingConfirm{routingKey: routingKey, messageID: msg.MessageId}
+	if wait {
+		p.done = make(chan error, 1)
+	}
+
+	cc.mu.Lock()
+	tag := cc.nextTag + 1
+	cc.pending[tag] = p
+	cc.byMessage[msg.MessageId] = tag
+	cc.mu.Unlock()
+
+	err := cc.Channel.Publish(
+		exchange,   // exchange
+		routingKey, // routing key
+		true,       // mandatory: unroutable messages come back via NotifyReturn
+		false,      // immediate
+		msg,
+	)
+
+	cc.mu.Lock()
+	if err != nil {
+		delete(cc.pending, tag)
+		delete(cc.byMessage, msg.MessageId)
+	} else {
+		cc.nextTag = tag
+	}
+	cc.mu.Unlock()
+
+	if err != nil {
+		return nil, err
+	}
+	publishStats.Add("published", 1)
+	return p.done, nil
+}
+
+/* listen resolves confirms until the channel closes, then fails whatever is still pending */
+func (cc *confirmChannel) listen(confirms <-chan amqp.Confirmation, returns <-chan amqp.Return) {
+	for {
+		select {
+		case ret, ok := <-returns:
+			if !ok {
+				// A nil channel blocks forever, so a closed returns channel can't spin the loop
+				returns = nil
+				continue
+			}
+			cc.recordReturn(ret)
+		case confirm, ok := <-confirms:
+			if !ok {
+				cc.failPending()
+				return
+			}
+			cc.drainReturns(returns)
+			cc.resolve(confirm)
+		}
+	}
+}
+
+/* drainReturns records returns that arrived ahead of the confirm being resolved */
+func (cc *confirmChannel) drainReturns(returns <-chan amqp.Return) {
+	for {
+		select {
+		case ret, ok := <-returns:
+			if !ok {
+				return
+			}
+			cc.recordReturn(ret)
+		default:
+			return
+		}
+	}
+}
+
+/* recordReturn marks the returned message so its confirm resolves as unroutable */
+func (cc *confirmChannel) recordReturn(ret amqp.Return) {
+	cc.mu.Lock()
+	defer cc.mu.Unlock()
+
+	if tag, ok := cc.byMessage[ret.MessageId]; ok {
+		if p := cc.pending[tag]; p != nil {
+			p.returned = &ret
+		}
+	}
+}
+
+/* resolve settles the message a confirm refers to */
+func (cc *confirmChannel) resolve(confirm amqp.Confirmation) {
+	cc.mu.Lock()
+	p := cc.pending[confirm.DeliveryTag]
+	delete(cc.pending, confirm.DeliveryTag)
+	if p != nil {
+		delete(cc.byMessage, p.messageID)
+	}
+	cc.mu.Unlock()
+
+	if p == nil {
+		return
+	}
+
+	var err error
+	switch {
+	case p.returned != nil:
+		publishStats.Add("returned", 1)
+		err = fmt.Errorf("%w: %s (%d %s)", ErrUnroutable, p.routingKey, p.returned.ReplyCode, p.returned.ReplyText)
+	case !confirm.Ack:
+		publishStats.Add("nacked", 1)
+		err = fmt.Errorf("%w: %s", ErrNacked, p.routingKey)
+	default:
+		publishStats.Add("confirmed", 1)
+	}
+
+	if err != nil {
+		monitoring.CaptureError(err, map[string]interface{}{
+			"component":   "rabbitmq_publisher",
+			"routing_key": p.routingKey,
+			"message_id":  p.messageID,
+		})
+	}
+	if p.done != nil {
+		p.done <- err
+	}
+}
+
+/* failPending fails messages whose confirm will never arrive because the channel closed */
+func (cc *confirmChannel) failPending() {
+	cc.mu.Lock()
+	pending := cc.pending
+	cc.pending = make(map[uint64]*pendingConfirm)
+	cc.byMessage = make(map[string]uint64)
+	cc.mu.Unlock()
+
+	for _, p := range pending {
+		publishStats.Add("unconfirmed", 1)
+		if p.done != nil {
+			p.done <- fmt.Errorf("channel closed before confirm: %w", ErrNotConnected)
+		}
+	}
+}
+
+/* awaitConfirm waits for a publish outcome up to timeout */
+func awaitConfirm(done <-chan error, timeout time.Duration) error {
+	select {
+	case err := <-done:
+		return err
+	case <-time.After(timeout):
+		publishStats.Add("confirm_timeouts", 1)
+		return ErrConfirmTimeout
+	}
+}
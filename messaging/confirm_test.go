@@ -0,0 +1,85 @@
+package messaging
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/streadway/amqp"
+)
+
+/* newTestConfirmChannel returns a confirm channel with one waiting publish per message ID, tagged from 1 */
+func newTestConfirmChannel(messageIDs ...string) (*confirmChannel, []chan error) {
+	cc := &confirmChannel{
+		pending:   make(map[uint64]*pendingConfirm),
+		byMessage: make(map[string]uint64),
+	}
+	var done []chan error
+	for i, id := range messageIDs {
+		tag := uint64(i + 1)
+		p := &pendingConfirm{routingKey: "user.created", messageID: id, done: make(chan error, 1)}
+		cc.pending[tag] = p
+		cc.byMessage[id] = tag
+		cc.nextTag = tag
+		done = append(done, p.done)
+	}
+	return cc, done
+}
+
+/* TestConfirmResolution checks acks, nacks and returns settle the right publish */
+func TestConfirmResolution(t *testing.T) {
+	cc, done := newTestConfirmChannel("acked", "nacked", "returned")
+	confirms := make(chan amqp.Confirmation, 3)
+	returns := make(chan amqp.Return, 1)
+
+	// The broker sends basic.return ahead of the ack of the same message
+	returns <- amqp.Return{MessageId: "returned", ReplyCode: 312, ReplyText: "NO_ROUTE"}
+	confirms <- amqp.Confirmation{DeliveryTag: 1, Ack: true}
+	confirms <- amqp.Confirmation{DeliveryTag: 2, Ack: false}
+	confirms <- amqp.Confirmation{DeliveryTag: 3, Ack: true}
+	close(confirms)
+	cc.listen(confirms, returns)
+
+	if err := awaitConfirm(done[0], time.Second); err != nil {
+		t.Errorf("ack: got %v, want nil", err)
+	}
+	if err := awaitConfirm(done[1], time.Second); !errors.Is(err, ErrNacked) {
+		t.Errorf("nack: got %v, want ErrNacked", err)
+	}
+	if err := awaitConfirm(done[2], time.Second); !errors.Is(err, ErrUnroutable) {
+		t.Errorf("return: got %v, want ErrUnroutable", err)
+	}
+	if len(cc.pending) != 0 || len(cc.byMessage) != 0 {
+		t.Errorf("got %d pending, %d by message ID, want none", len(cc.pending), len(cc.byMessage))
+	}
+}
+
+/* TestConfirmChannelClosed checks publishes still waiting fail once the channel closes */
+func TestConfirmChannelClosed(t *testing.T) {
+	cc, done := newTestConfirmChannel("acked", "lost")
+	confirms := make(chan amqp.Confirmation, 1)
+	returns := make(chan amqp.Return)
+
+	stopped := make(chan struct{})
+	go func() {
+		cc.listen(confirms, returns)
+		close(stopped)
+	}()
+
+	// Returns close first; the listener must keep resolving confirms without spinning
+	close(returns)
+	confirms <- amqp.Confirmation{DeliveryTag: 1, Ack: true}
+	if err := awaitConfirm(done[0], time.Second); err != nil {
+		t.Errorf("ack: got %v, want nil", err)
+	}
+	close(confirms)
+
+	select {
+	case <-stopped:
+	case <-time.After(time.Second):
+		t.Fatal("listener did not stop after the channel closed")
+	}
+	if err := awaitConfirm(done[1], time.Second); !errors.Is(err, ErrNotConnected) {
+		t.Errorf("lost: got %v, want ErrNotConnected", err)
+	}
+}
@@ -0,0 +1,285 @@
+package messaging
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"baseApi/logger"
+
+	"github.com/streadway/amqp"
+)
+
+const minReconnectBackoff = 500 * time.Millisecond
+
+/* connect dials the broker, declares the exchanges and builds a fresh channel pool */
+func (r *RabbitMQPublisher) connect() error {
+	conn, err := amqp.Dial(r.url)
+	if err != nil {
+		return fmt.Errorf("failed to connect to RabbitMQ: %w", err)
+	}
+
+	ch, err := conn.Channel()
+	if err != nil {
+		conn.Close()
+		return fmt.Errorf("failed to open channel: %w", err)
+	}
+
+	// Declare topic exchange
+	err = ch.ExchangeDeclare(
+		r.exchange, // name
+		"topic",    // type
+		true,       // durable
+		false,      // auto-deleted
+		false,      // internal
+		false,      // no-wait
+		nil,        // arguments
+	)
+	if err != nil {
+		ch.Close()
+		conn.Close()
+		return fmt.Errorf("failed to declare exchange: %w", err)
+	}
+
+	// Retry and dead-letter exchanges used by consumer retry topologies
+	if err := declareRetryExchanges(ch, r.exchange); err != nil {
+		ch.Close()
+		conn.Close()
+		return err
+	}
+
+	// The declaring channel becomes the first pooled channel
+	pool, err := newChannelPool(conn, ch, r.poolSize)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+
+	r.mu.Lock()
+	select {
+	case <-r.closed:
+		r.mu.Unlock()
+		pool.close()
+		conn.Close()
+		return ErrPublisherClosed
+	default:
+	}
+	r.connection = conn
+	r.pool = pool
+	r.mu.Unlock()
+
+	go r.watch(conn)
+	return nil
+}
+
+/* watch waits for the connection to close and starts reconnecting unless the publisher was closed */
+func (r *RabbitMQPublisher) watch(conn *amqp.Connection) {
+	closeErr := <-conn.NotifyClose(make(chan *amqp.Error, 1))
+
+	select {
+	case <-r.closed:
+		return
+	default:
+	}
+
+	logger.Error("RabbitMQ connection lost:", closeErr)
+
+	r.mu.Lock()
+	if r.connection == conn {
+		if r.pool != nil {
+			r.pool.close()
+		}
+		r.connection = nil
+		r.pool = nil
+	}
+	r.mu.Unlock()
+
+	r.reconnect()
+}
+
+/* reconnect retries connect with exponential backoff until it succeeds or the publisher is closed */
+func (r *RabbitMQPublisher) reconnect() {
+	backoff := minReconnectBackoff
+	for attempt := 1; ; attempt++ {
+		select {
+		case <-r.closed:
+			return
+		case <-time.After(backoff):
+		}
+
+		err := r.connect()
+		if err == nil {
+			logger.Info(fmt.Sprintf("RabbitMQ reconnected after %d attempts", attempt))
+			r.mu.Lock()
+			close(r.reconnected)
+			r.reconnected = make(chan struct{})
+			r.mu.Unlock()
+			r.flushBuffer()
+			return
+		}
+		if err == ErrPublisherClosed {
+			return
+		}
+
+		logger.Warn(fmt.Sprintf("RabbitMQ reconnect attempt %d failed, retrying in %s:", attempt, backoff), err)
+		backoff *= 2
+		if backoff > r.maxBackoff {
+			backoff = r.maxBackoff
+		}
+	}
+}
+
+/* flushBuffer publishes messages buffered during an outage, in order, holding new publishes until it is done */
+func (r *RabbitMQPublisher) flushBuffer() {
+	if r.buffer == nil {
+		return
+	}
+
+	r.flushMu.Lock()
+	defer r.flushMu.Unlock()
+
+	flushed := 0
+	for {
+		pending := r.head
+		if pending == nil {
+			select {
+			case next := <-r.buffer:
+				pending = &next
+			default:
+				if flushed > 0 {
+					logger.Info(fmt.Sprintf("Published %d messages buffered during RabbitMQ outage", flushed))
+				}
+				return
+			}
+		}
+
+		if err := r.publish(pending.routingKey, pending.msg, publishOptions{}); err != nil {
+			// Connection dropped again; keep the message first in line and let the next reconnect resume
+			r.head = pending
+			logger.Warn("RabbitMQ flush interrupted, keeping buffered message with routing key", pending.routingKey+":", err)
+			return
+		}
+		r.head = nil
+		flushed++
+	}
+}
+
+/* channelPool hands out confirm-mode channels of one connection to one publisher at a time */
+type channelPool struct {
+	conn     *amqp.Connection
+	channels chan *confirmChannel
+	done     chan struct{}
+	mu       sync.Mutex // orders release against close
+}
+
+/* newChannelPool fills a pool with size channels, starting with first */
+func newChannelPool(conn *amqp.Connection, first *amqp.Channel, size int) (*channelPool, error) {
+	if size <= 0 {
+		size = 1
+	}
+	p := &channelPool{
+		conn:     conn,
+		channels: make(chan *confirmChannel, size),
+		done:     make(chan struct{}),
+	}
+
+	cc, err := newConfirmChannel(first)
+	if err != nil {
+		first.Close()
+		return nil, err
+	}
+	p.channels <- cc
+
+	for i := 1; i < size; i++ {
+		cc, err := p.open()
+		if err != nil {
+			p.close()
+			return nil, err
+		}
+		p.channels <- cc
+	}
+	return p, nil
+}
+
+/* open creates one confirm-mode channel on the pool's connection */
+func (p *channelPool) open() (*confirmChannel, error) {
+	ch, err := p.conn.Channel()
+	if err != nil {
+		return nil, fmt.Errorf("failed to open pooled channel: %w", err)
+	}
+	cc, err := newConfirmChannel(ch)
+	if err != nil {
+		ch.Close()
+		return nil, err
+	}
+	return cc, nil
+}
+
+/* acquire borrows a channel, waiting up to timeout for one to be released */
+func (p *channelPool) acquire(timeout time.Duration) (*confirmChannel, error) {
+	select {
+	case cc := <-p.channels:
+		return cc, nil
+	case <-p.done:
+		return nil, ErrNotConnected
+	case <-time.After(timeout):
+		return nil, fmt.Errorf("timed out waiting for a RabbitMQ channel")
+	}
+}
+
+/* release returns a healthy channel to the pool, or closes it if the pool was closed */
+func (p *channelPool) release(cc *confirmChannel) {
+	// Checked under the lock close takes, so a channel can't slip in after close drained the pool
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	select {
+	case <-p.done:
+		cc.Close()
+		return
+	default:
+	}
+
+	select {
+	case p.channels <- cc:
+	default:
+		// Never more channels than slots, but don't block holding the lock
+		cc.Close()
+	}
+}
+
+/* discard drops a broken channel and replaces it if the connection is still open */
+func (p *channelPool) discard(cc *confirmChannel) {
+	cc.Close()
+	select {
+	case <-p.done:
+		return
+	default:
+	}
+
+	replacement, err := p.open()
+	if err != nil {
+		// The connection watcher rebuilds the pool after reconnecting
+		return
+	}
+	p.release(replacement)
+}
+
+/* close closes every idle channel; borrowed channels are closed on release */
+func (p *channelPool) close() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	select {
+	case <-p.done:
+		return
+	default:
+		close(p.done)
+	}
+	for {
+		select {
+		case cc := <-p.channels:
+			cc.Close()
+		default:
+			return
+		}
+	}
+}
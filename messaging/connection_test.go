@@ -0,0 +1,70 @@
+package messaging
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/streadway/amqp"
+)
+
+/* newBufferingPublisher returns a disconnected publisher that buffers up to size messages */
+func newBufferingPublisher(size int) *RabbitMQPublisher {
+	return &RabbitMQPublisher{
+		outageMode:  OutageModeBuffer,
+		buffer:      make(chan pendingPublish, size),
+		reconnected: make(chan struct{}),
+		closed:      make(chan struct{}),
+	}
+}
+
+/* bufferedKeys drains the publisher's head and buffer, returning their routing keys in order */
+func bufferedKeys(r *RabbitMQPublisher) []string {
+	var keys []string
+	if r.head != nil {
+		keys = append(keys, r.head.routingKey)
+	}
+	for len(r.buffer) > 0 {
+		keys = append(keys, (<-r.buffer).routingKey)
+	}
+	return keys
+}
+
+/* TestOutageBufferKeepsOrder checks a flush cut short keeps its message first in line */
+func TestOutageBufferKeepsOrder(t *testing.T) {
+	r := newBufferingPublisher(10)
+	for _, key := range []string{"user.created", "user.updated"} {
+		if err := r.publishOrBuffer(key, amqp.Publishing{}, publishOptions{}); err != nil {
+			t.Fatalf("publishOrBuffer %s: %v", key, err)
+		}
+	}
+
+	// The connection drops again during the flush
+	r.flushBuffer()
+	if r.head == nil || r.head.routingKey != "user.created" {
+		t.Fatalf("got head %+v, want user.created kept first", r.head)
+	}
+
+	if err := r.publishOrBuffer("user.deleted", amqp.Publishing{}, publishOptions{}); err != nil {
+		t.Fatalf("publishOrBuffer: %v", err)
+	}
+	if got := fmt.Sprint(bufferedKeys(r)); got != "[user.created user.updated user.deleted]" {
+		t.Errorf("got %s, want the publish order", got)
+	}
+}
+
+/* TestOutageBufferFull checks publishes fail once the buffer is full */
+func TestOutageBufferFull(t *testing.T) {
+	r := newBufferingPublisher(1)
+	if err := r.publishOrBuffer("user.created", amqp.Publishing{}, publishOptions{}); err != nil {
+		t.Fatalf("first publish: %v", err)
+	}
+	if err := r.publishOrBuffer("user.updated", amqp.Publishing{}, publishOptions{}); err != ErrBufferFull {
+		t.Errorf("got %v, want ErrBufferFull", err)
+	}
+
+	// Without buffering an outage is reported straight away
+	r.buffer = nil
+	if err := r.publishOrBuffer("user.updated", amqp.Publishing{}, publishOptions{}); err != ErrNotConnected {
+		t.Errorf("got %v, want ErrNotConnected", err)
+	}
+}
@@ -0,0 +1,107 @@
+package messaging
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"baseApi/logger"
+
+	"github.com/streadway/amqp"
+)
+
+const (
+	defaultPrefetch       = 10
+	defaultWorkers        = 4
+	maxResubscribeBackoff = 30 * time.Second
+)
+
+/* ConsumerConfig describes the queue a consumer reads and how many messages it handles at once */
+type ConsumerConfig struct {
+	Queue       string     // queue name (Redis Streams: consumer group); declared durable unless AutoDelete is set
+	Bindings    []string   // extra routing-key patterns to bind besides the handler patterns
+	AutoDelete  bool       // delete the queue when the last consumer goes away (Redis Streams: the group, on Shutdown)
+	QueueArgs   amqp.Table // optional RabbitMQ x-arguments (x-dead-letter-exchange, x-message-ttl, ...)
+	Prefetch    int        // unacknowledged deliveries held at once (default 10)
+	Workers     int        // concurrent handler goroutines (default 4)
+	ConsumerTag string     // defaults to the queue name
+
+	// Retry enables delayed retries and dead letters for the queue (see RetryPolicy).
+	// RabbitMQ queues declared before enabling it must be deleted first: their arguments change.
+	Retry *RetryPolicy
+}
+
+/* withDefaults fills unset consumer settings */
+func (c ConsumerConfig) withDefaults() ConsumerConfig {
+	if c.Prefetch <= 0 {
+		c.Prefetch = defaultPrefetch
+	}
+	if c.Workers <= 0 {
+		c.Workers = defaultWorkers
+	}
+	if c.ConsumerTag == "" {
+		c.ConsumerTag = c.Queue
+	}
+	return c
+}
+
+/* bindingKeys returns the handler patterns plus extra bindings, without duplicates */
+func (c ConsumerConfig) bindingKeys(router *Router) []string {
+	seen := make(map[string]bool)
+	var keys []string
+	for _, pattern := range append(append([]string{}, c.Bindings...), router.Patterns()...) {
+		if !seen[pattern] {
+			seen[pattern] = true
+			keys = append(keys, pattern)
+		}
+	}
+	return keys
+}
+
+/* Consumer reads one queue of the configured broker and dispatches by routing-key pattern */
+type Consumer struct {
+	cfg          ConsumerConfig
+	router       Router
+	subscription Subscription
+}
+
+/* NewConsumer creates a consumer; register handlers with Handle before calling Start */
+func NewConsumer(cfg ConsumerConfig) *Consumer {
+	return &Consumer{cfg: cfg.withDefaults()}
+}
+
+/* Handle registers a handler for a topic pattern ("user.*", "user.#"); the first match wins */
+func (c *Consumer) Handle(pattern string, handler HandlerFunc) *Consumer {
+	c.router.Handle(pattern, handler)
+	return c
+}
+
+/* Start subscribes to the queue on the configured broker (see InitBroker) */
+func (c *Consumer) Start(ctx context.Context) error {
+	return c.StartOn(ctx, GetBroker())
+}
+
+/* StartOn subscribes to the queue on a specific broker */
+func (c *Consumer) StartOn(ctx context.Context, broker Subscriber) error {
+	if broker == nil {
+		return fmt.Errorf("message broker not initialized")
+	}
+
+	subscription, err := broker.Subscribe(ctx, c.cfg, &c.router)
+	if err != nil {
+		return err
+	}
+	c.subscription = subscription
+
+	logger.Info(fmt.Sprintf("Consumer '%s' started on queue '%s' with %d workers, prefetch %d",
+		c.cfg.ConsumerTag, c.cfg.Queue, c.cfg.Workers, c.cfg.Prefetch))
+	return nil
+}
+
+/* Shutdown stops new deliveries and waits for in-flight handlers */
+func (c *Consumer) Shutdown(ctx context.Context) error {
+	if c.subscription == nil {
+		return nil
+	}
+	return c.subscription.Shutdown(ctx)
+}
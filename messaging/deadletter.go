@@ -0,0 +1,216 @@
+package messaging
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/streadway/amqp"
+)
+
+// ErrQueueNotFound is returned when a work queue has no dead-letter queue
+var ErrQueueNotFound = errors.New("dead-letter queue not found")
+
+/* DeadLetter is a dead-lettered message as shown by the admin API */
+type DeadLetter struct {
+	MessageID   string      `json:"messageId,omitempty"`
+	RoutingKey  string      `json:"routingKey"`
+	Attempts    int         `json:"attempts"`
+	LastError   string      `json:"lastError,omitempty"`
+	Reason      string      `json:"reason,omitempty"`
+	ContentType string      `json:"contentType,omitempty"`
+	Timestamp   *time.Time  `json:"timestamp,omitempty"`
+	Body        interface{} `json:"body"`
+}
+
+/* DeadLetterQueueInfo summarizes a work queue's dead-letter queue */
+type DeadLetterQueueInfo struct {
+	Queue           string       `json:"queue"`
+	DeadLetterQueue string       `json:"deadLetterQueue"`
+	Count           int          `json:"count"`
+	Messages        []DeadLetter `json:"messages"`
+}
+
+/* DeadLetterAdmin is implemented by brokers whose dead letters can be inspected, replayed and purged */
+type DeadLetterAdmin interface {
+	InspectDeadLetters(queue string, limit int) (*DeadLetterQueueInfo, error)
+	ReplayDeadLetters(queue string, limit int) (int, error)
+	PurgeDeadLetters(queue string) (int, error)
+}
+
+/*
+InspectDeadLetters peeks at up to limit dead-lettered messages of a work queue.
+
+Messages are fetched without ack and returned to the queue when the channel
+closes, so inspecting never loses them (they are marked redelivered).
+*/
+func (r *RabbitMQPublisher) InspectDeadLetters(queue string, limit int) (*DeadLetterQueueInfo, error) {
+	ch, err := r.deadLetterChannel()
+	if err != nil {
+		return nil, err
+	}
+	defer ch.Close()
+
+	dead := DeadLetterQueue(queue)
+	state, err := ch.QueueInspect(dead)
+	if err != nil {
+		return nil, queueError(err)
+	}
+
+	info := &DeadLetterQueueInfo{
+		Queue:           queue,
+		DeadLetterQueue: dead,
+		Count:           state.Messages,
+		Messages:        []DeadLetter{},
+	}
+	for i := 0; i < limit; i++ {
+		d, ok, err := ch.Get(dead, false)
+		if err != nil {
+			return nil, queueError(err)
+		}
+		if !ok {
+			break
+		}
+		info.Messages = append(info.Messages, toDeadLetter(d))
+	}
+	return info, nil
+}
+
+/*
+ReplayDeadLetters republishes up to limit dead-lettered messages with a fresh attempt count.
+
+They go through the default exchange straight to the work queue, like retries,
+so other queues bound to the same routing key don't receive them again.
+*/
+func (r *RabbitMQPublisher) ReplayDeadLetters(queue string, limit int) (int, error) {
+	ch, err := r.deadLetterChannel()
+	if err != nil {
+		return 0, err
+	}
+	defer ch.Close()
+
+	dead := DeadLetterQueue(queue)
+	if _, err := ch.QueueInspect(dead); err != nil {
+		return 0, queueError(err)
+	}
+
+	replayed := 0
+	for replayed < limit {
+		d, ok, err := ch.Get(dead, false)
+		if err != nil {
+			return replayed, queueError(err)
+		}
+		if !ok {
+			break
+		}
+
+		headers := replayHeaders(d.Headers)
+		headers[HeaderOriginalRoutingKey] = originalRoutingKey(d)
+		err = r.publishTo("", queue, republishing(d, headers),
+			publishOptions{waitConfirm: true, timeout: r.confirmTimeout})
+		if err != nil {
+			d.Nack(false, true)
+			return replayed, err
+		}
+		if err := d.Ack(false); err != nil {
+			return replayed, err
+		}
+		replayed++
+	}
+	return replayed, nil
+}
+
+/* PurgeDeadLetters deletes every dead-lettered message of a work queue */
+func (r *RabbitMQPublisher) PurgeDeadLetters(queue string) (int, error) {
+	ch, err := r.deadLetterChannel()
+	if err != nil {
+		return 0, err
+	}
+	defer ch.Close()
+
+	dead := DeadLetterQueue(queue)
+	if _, err := ch.QueueInspect(dead); err != nil {
+		return 0, queueError(err)
+	}
+	purged, err := ch.QueuePurge(dead, false)
+	if err != nil {
+		return 0, queueError(err)
+	}
+	return purged, nil
+}
+
+/* deadLetterChannel opens a short-lived channel for admin operations */
+func (r *RabbitMQPublisher) deadLetterChannel() (*amqp.Channel, error) {
+	if r == nil {
+		return nil, ErrNotConnected
+	}
+	return r.Channel()
+}
+
+/* queueError maps a missing queue (404 channel exception) to ErrQueueNotFound */
+func queueError(err error) error {
+	var amqpErr *amqp.Error
+	if errors.As(err, &amqpErr) && amqpErr.Code == amqp.NotFound {
+		return ErrQueueNotFound
+	}
+	return fmt.Errorf("dead-letter queue operation failed: %w", err)
+}
+
+/* originalRoutingKey recovers the topic routing key a dead-lettered message was first published with */
+func originalRoutingKey(d amqp.Delivery) string {
+	if key := headerString(d.Headers, HeaderOriginalRoutingKey); key != "" {
+		return key
+	}
+	// Nacked straight to the DLX: the broker records the original keys in x-death
+	if deaths, ok := d.Headers["x-death"].([]interface{}); ok && len(deaths) > 0 {
+		if death, ok := deaths[len(deaths)-1].(amqp.Table); ok {
+			if keys, ok := death["routing-keys"].([]interface{}); ok && len(keys) > 0 {
+				if key, ok := keys[0].(string); ok {
+					return key
+				}
+			}
+		}
+	}
+	return d.RoutingKey
+}
+
+/* toDeadLetter converts a delivery for display */
+func toDeadLetter(d amqp.Delivery) DeadLetter {
+	msg := rabbitMessage(d)
+	msg.RoutingKey = originalRoutingKey(d)
+	return newDeadLetter(msg)
+}
+
+/* newDeadLetter converts a dead-lettered message for display, keeping JSON bodies as JSON */
+func newDeadLetter(msg *Message) DeadLetter {
+	letter := DeadLetter{
+		MessageID:   msg.ID,
+		RoutingKey:  msg.RoutingKey,
+		Attempts:    msg.Attempt(),
+		LastError:   headerString(msg.Headers, HeaderLastError),
+		Reason:      headerString(msg.Headers, HeaderDeadReason),
+		ContentType: msg.ContentType,
+		Body:        string(msg.Body),
+	}
+	if letter.Reason == "" {
+		letter.Reason = "rejected"
+	}
+	if !msg.Timestamp.IsZero() {
+		ts := msg.Timestamp
+		letter.Timestamp = &ts
+	}
+	if json.Valid(msg.Body) {
+		letter.Body = json.RawMessage(msg.Body)
+	}
+	return letter
+}
+
+/* replayHeaders copies a dead letter's headers without its retry and failure history */
+func replayHeaders(headers amqp.Table) amqp.Table {
+	replay := copyHeaders(headers)
+	for _, key := range []string{HeaderAttempt, HeaderLastError, HeaderDeadReason, HeaderOriginalRoutingKey, "x-death"} {
+		delete(replay, key)
+	}
+	return replay
+}
@@ -0,0 +1,422 @@
+package messaging
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"baseApi/logger"
+)
+
+/*
+MemoryBroker is an in-process topic bus for tests and single-node development.
+
+Queues behave like their RabbitMQ counterparts: a queue is created by the first
+Subscribe, receives every message whose routing key matches one of its patterns,
+keeps messages while no consumer is running (unless AutoDelete), and shares them
+between its consumers. Nothing survives a restart.
+*/
+type MemoryBroker struct {
+	mu     sync.Mutex
+	queues map[string]*memoryQueue
+	closed bool
+}
+
+/* NewMemoryBroker creates an empty in-memory broker */
+func NewMemoryBroker() *MemoryBroker {
+	return &MemoryBroker{queues: make(map[string]*memoryQueue)}
+}
+
+/* Name identifies the backend */
+func (b *MemoryBroker) Name() string {
+	return BackendMemory
+}
+
+/* Ping fails once the broker is closed */
+func (b *MemoryBroker) Ping(ctx context.Context) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		return ErrPublisherClosed
+	}
+	return nil
+}
+
+/* Publish copies the message onto every queue whose patterns match its routing key */
+func (b *MemoryBroker) Publish(ctx context.Context, msg *Message) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		return ErrPublisherClosed
+	}
+
+	stored := storedMessage(msg)
+	routed := false
+	for _, q := range b.queues {
+		if q.matches(stored.RoutingKey) {
+			q.push(storedMessage(stored))
+			routed = true
+		}
+	}
+	if !routed {
+		return fmt.Errorf("%w: %s", ErrUnroutable, msg.RoutingKey)
+	}
+	return nil
+}
+
+/* Subscribe creates (or joins) the queue and starts the worker pool */
+func (b *MemoryBroker) Subscribe(ctx context.Context, cfg ConsumerConfig, router *Router) (Subscription, error) {
+	cfg = cfg.withDefaults()
+
+	b.mu.Lock()
+	if b.closed {
+		b.mu.Unlock()
+		return nil, ErrPublisherClosed
+	}
+	q := b.queues[cfg.Queue]
+	if q == nil {
+		q = &memoryQueue{name: cfg.Queue, ready: make(chan struct{}, 1)}
+		b.queues[cfg.Queue] = q
+	}
+	q.bind(cfg.bindingKeys(router))
+	q.autoDelete = cfg.AutoDelete
+	q.consumers++
+	b.mu.Unlock()
+
+	ctx, cancel := context.WithCancel(ctx)
+	s := &memorySubscription{
+		broker:   b,
+		queue:    q,
+		cfg:      cfg,
+		router:   router,
+		cancel:   cancel,
+		stopping: make(chan struct{}),
+		stopped:  make(chan struct{}),
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < cfg.Workers; i++ {
+		wg.Add(1)
+		go s.work(ctx, &wg)
+	}
+	go func() {
+		wg.Wait()
+		close(s.stopped)
+	}()
+	return s, nil
+}
+
+/* Close rejects further publishes and subscriptions; running subscriptions drain normally */
+func (b *MemoryBroker) Close() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.closed = true
+	return nil
+}
+
+/* InspectDeadLetters returns up to limit dead-lettered messages of a queue */
+func (b *MemoryBroker) InspectDeadLetters(queue string, limit int) (*DeadLetterQueueInfo, error) {
+	q, err := b.queue(queue)
+	if err != nil {
+		return nil, err
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	info := &DeadLetterQueueInfo{
+		Queue:           queue,
+		DeadLetterQueue: DeadLetterQueue(queue),
+		Count:           len(q.dead),
+		Messages:        []DeadLetter{},
+	}
+	for i := 0; i < len(q.dead) && i < limit; i++ {
+		info.Messages = append(info.Messages, newDeadLetter(q.dead[i]))
+	}
+	return info, nil
+}
+
+/* ReplayDeadLetters puts up to limit dead-lettered messages back on their queue, and only it, with a fresh attempt count */
+func (b *MemoryBroker) ReplayDeadLetters(queue string, limit int) (int, error) {
+	q, err := b.queue(queue)
+	if err != nil {
+		return 0, err
+	}
+	if err := b.Ping(context.Background()); err != nil {
+		return 0, err
+	}
+
+	replayed := 0
+	for replayed < limit {
+		msg := q.popDead()
+		if msg == nil {
+			break
+		}
+		replay := storedMessage(msg)
+		replay.Headers = replayHeaders(msg.Headers)
+		q.push(replay)
+		replayed++
+	}
+	return replayed, nil
+}
+
+/* PurgeDeadLetters deletes every dead-lettered message of a queue */
+func (b *MemoryBroker) PurgeDeadLetters(queue string) (int, error) {
+	q, err := b.queue(queue)
+	if err != nil {
+		return 0, err
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	purged := len(q.dead)
+	q.dead = nil
+	return purged, nil
+}
+
+/* queue looks up a queue created by Subscribe */
+func (b *MemoryBroker) queue(name string) (*memoryQueue, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	q := b.queues[name]
+	if q == nil {
+		return nil, ErrQueueNotFound
+	}
+	return q, nil
+}
+
+/* unsubscribe drops a consumer, deleting an auto-delete queue with its last consumer */
+func (b *MemoryBroker) unsubscribe(q *memoryQueue) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	q.consumers--
+	if q.autoDelete && q.consumers == 0 && b.queues[q.name] == q {
+		delete(b.queues, q.name)
+	}
+}
+
+/* memoryQueue holds a queue's pending and dead-lettered messages */
+type memoryQueue struct {
+	name       string
+	patterns   []string
+	autoDelete bool
+	consumers  int // guarded by the broker's mutex, like the fields above
+
+	mu      sync.Mutex
+	pending []*Message
+	dead    []*Message
+	ready   chan struct{} // signalled when pending becomes non-empty
+}
+
+/* bind adds routing-key patterns to the queue */
+func (q *memoryQueue) bind(patterns []string) {
+	for _, pattern := range patterns {
+		if !q.bound(pattern) {
+			q.patterns = append(q.patterns, pattern)
+		}
+	}
+}
+
+func (q *memoryQueue) bound(pattern string) bool {
+	for _, p := range q.patterns {
+		if p == pattern {
+			return true
+		}
+	}
+	return false
+}
+
+/* matches reports whether a routing key matches one of the queue's patterns */
+func (q *memoryQueue) matches(routingKey string) bool {
+	for _, pattern := range q.patterns {
+		if TopicMatch(pattern, routingKey) {
+			return true
+		}
+	}
+	return false
+}
+
+/* push appends a message and wakes a worker */
+func (q *memoryQueue) push(msg *Message) {
+	q.mu.Lock()
+	q.pending = append(q.pending, msg)
+	q.mu.Unlock()
+	q.signal()
+}
+
+/* pop takes the oldest message, waiting until one arrives or stop is closed */
+func (q *memoryQueue) pop(stop <-chan struct{}) *Message {
+	for {
+		q.mu.Lock()
+		if len(q.pending) > 0 {
+			msg := q.pending[0]
+			q.pending = q.pending[1:]
+			more := len(q.pending) > 0
+			q.mu.Unlock()
+			if more {
+				q.signal()
+			}
+			return msg
+		}
+		q.mu.Unlock()
+
+		select {
+		case <-q.ready:
+		case <-stop:
+			return nil
+		}
+	}
+}
+
+func (q *memoryQueue) signal() {
+	select {
+	case q.ready <- struct{}{}:
+	default:
+	}
+}
+
+func (q *memoryQueue) pushDead(msg *Message) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.dead = append(q.dead, msg)
+}
+
+func (q *memoryQueue) popDead() *Message {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if len(q.dead) == 0 {
+		return nil
+	}
+	msg := q.dead[0]
+	q.dead = q.dead[1:]
+	return msg
+}
+
+/* memorySubscription runs one consumer's workers on a memory queue */
+type memorySubscription struct {
+	broker *MemoryBroker
+	queue  *memoryQueue
+	cfg    ConsumerConfig
+	router *Router
+
+	cancel   context.CancelFunc
+	stopping chan struct{}
+	stopped  chan struct{}
+	once     sync.Once
+}
+
+/* Shutdown stops taking messages and waits for in-flight handlers */
+func (s *memorySubscription) Shutdown(ctx context.Context) error {
+	s.once.Do(func() {
+		close(s.stopping)
+	})
+
+	var err error
+	select {
+	case <-s.stopped:
+		logger.Info(fmt.Sprintf("Consumer '%s' drained", s.cfg.ConsumerTag))
+	case <-ctx.Done():
+		err = fmt.Errorf("consumer %s shutdown: %w", s.cfg.ConsumerTag, ctx.Err())
+	}
+
+	s.cancel()
+	s.broker.unsubscribe(s.queue)
+	return err
+}
+
+/* work handles messages until the subscription stops */
+func (s *memorySubscription) work(ctx context.Context, wg *sync.WaitGroup) {
+	defer wg.Done()
+	for {
+		stored := s.queue.pop(s.stopping)
+		if stored == nil {
+			return
+		}
+		s.dispatch(ctx, stored)
+	}
+}
+
+/* dispatch runs the matching handler and settles the message from its result */
+func (s *memorySubscription) dispatch(ctx context.Context, stored *Message) {
+	msg := storedMessage(stored)
+	msg.ack = func() error { return nil }
+	msg.nack = func(requeue bool) error {
+		if requeue {
+			redelivery := storedMessage(stored)
+			redelivery.Redelivered = true
+			s.queue.push(redelivery)
+		} else if s.cfg.Retry != nil {
+			s.deadLetter(stored, fmt.Errorf("rejected by handler"))
+		}
+		return nil
+	}
+
+	err := s.router.Dispatch(ctx, msg)
+	if err == nil {
+		msg.Ack()
+		return
+	}
+
+	reportHandlerError(BackendMemory, s.cfg.Queue, msg, err)
+	if msg.settled {
+		return
+	}
+	switch onFailure(err, s.cfg.Retry, msg.Attempt()) {
+	case actionRetry:
+		msg.settled = true
+		s.retry(stored, err)
+	case actionDeadLetter:
+		msg.settled = true
+		s.deadLetter(stored, err)
+	default:
+		msg.Nack(IsRequeue(err))
+	}
+}
+
+/* retry puts the message back on the queue after the policy's delay for its next attempt */
+func (s *memorySubscription) retry(stored *Message, cause error) {
+	attempt := stored.Attempt() + 1
+	retried := storedMessage(stored)
+	retried.Redelivered = true
+	retried.Headers[HeaderAttempt] = attempt
+	retried.Headers[HeaderOriginalRoutingKey] = stored.RoutingKey
+	retried.Headers[HeaderLastError] = cause.Error()
+
+	delay := s.cfg.Retry.Delays()[attempt-1]
+	time.AfterFunc(delay, func() {
+		s.queue.push(retried)
+	})
+}
+
+/* deadLetter moves the message to the queue's dead letters with the failure recorded in its headers */
+func (s *memorySubscription) deadLetter(stored *Message, cause error) {
+	dead := storedMessage(stored)
+	dead.Headers[HeaderOriginalRoutingKey] = stored.RoutingKey
+	dead.Headers[HeaderLastError] = cause.Error()
+	dead.Headers[HeaderDeadReason] = deadReason(cause)
+	s.queue.pushDead(dead)
+}
+
+/* storedMessage copies a message's content so publishers and handlers can't share mutable state */
+func storedMessage(msg *Message) *Message {
+	stored := &Message{
+		ID:          msg.ID,
+		RoutingKey:  msg.RoutingKey,
+		ContentType: msg.ContentType,
+		Headers:     copyHeaders(msg.Headers),
+		Body:        append([]byte(nil), msg.Body...),
+		Timestamp:   msg.Timestamp,
+		Redelivered: msg.Redelivered,
+	}
+	if stored.ID == "" {
+		stored.ID = newEventID()
+	}
+	if stored.Timestamp.IsZero() {
+		stored.Timestamp = time.Now()
+	}
+	return stored
+}
@@ -0,0 +1,213 @@
+package messaging
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"baseApi/config"
+	"baseApi/logger"
+	"baseApi/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// outboxRelayLockID is the Postgres advisory lock that elects a single active relay
+const outboxRelayLockID int64 = 0x6f7574626f78 // "outbox"
+
+// maxOutboxBackoff caps the delay between publish attempts of one event
+const maxOutboxBackoff = 5 * time.Minute
+
+/* OutboxRelay publishes outbox events to the message broker in commit order */
+type OutboxRelay struct {
+	db           *gorm.DB
+	publisher    func() Publisher
+	pollInterval time.Duration
+	batchSize    int
+	maxAttempts  int
+	retention    time.Duration
+}
+
+/* NewOutboxRelay creates a relay that publishes through the configured broker */
+func NewOutboxRelay(db *gorm.DB, cfg *config.Config) *OutboxRelay {
+	return &OutboxRelay{
+		db: db,
+		publisher: func() Publisher {
+			if b := GetBroker(); b != nil {
+				return b
+			}
+			return nil
+		},
+		pollInterval: cfg.OutboxPollInterval,
+		batchSize:    cfg.OutboxBatchSize,
+		maxAttempts:  cfg.OutboxMaxAttempts,
+		retention:    cfg.OutboxRetention,
+	}
+}
+
+/* Run polls the outbox until the context is cancelled */
+func (r *OutboxRelay) Run(ctx context.Context) {
+	ticker := time.NewTicker(r.pollInterval)
+	defer ticker.Stop()
+
+	logger.Info("Outbox relay started, polling every", r.pollInterval)
+	for {
+		select {
+		case <-ctx.Done():
+			logger.Info("Outbox relay stopped")
+			return
+		case <-ticker.C:
+			r.drain(ctx)
+		}
+	}
+}
+
+/* drain relays full batches until the outbox is caught up */
+func (r *OutboxRelay) drain(ctx context.Context) {
+	for ctx.Err() == nil {
+		publisher := r.publisher()
+		if publisher == nil {
+			return
+		}
+
+		relayed, err := r.relayBatch(ctx, publisher)
+		if err != nil {
+			logger.Error("Outbox relay batch failed:", err)
+			return
+		}
+		if relayed < r.batchSize {
+			r.purgeSent(ctx)
+			return
+		}
+	}
+}
+
+/*
+relayBatch publishes the oldest pending events inside one transaction.
+
+Every API worker runs a relay. A transaction-scoped advisory lock lets only one of
+them relay at a time, which keeps events in commit order; the others skip the tick.
+Rows are also locked FOR UPDATE SKIP LOCKED so an operator's manual replay can't
+double-publish. Delivery is at-least-once: a crash between publishing and commit
+re-sends the event, so consumers should dedupe on the CloudEvent id (also the AMQP message ID).
+
+Order holds per aggregate until an event is parked. Its aggregate's later events are
+held back for the rest of the batch, then published on later ticks; a manual replay
+re-sends the parked event after them, so it can arrive out of order.
+*/
+func (r *OutboxRelay) relayBatch(ctx context.Context, publisher Publisher) (int, error) {
+	relayed := 0
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var leader bool
+		if err := tx.Raw("SELECT pg_try_advisory_xact_lock(?)", outboxRelayLockID).Scan(&leader).Error; err != nil {
+			return err
+		}
+		if !leader {
+			return nil
+		}
+
+		var events []models.OutboxEvent
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("sent_at IS NULL AND failed_at IS NULL").
+			Order("id").
+			Limit(r.batchSize).
+			Find(&events).Error
+		if err != nil {
+			return err
+		}
+
+		parked := map[string]bool{}
+		for i := range events {
+			event := &events[i]
+			now := time.Now()
+
+			aggregate := fmt.Sprintf("%s:%d", event.AggregateType, event.AggregateID)
+			if parked[aggregate] {
+				continue
+			}
+
+			// Later events wait behind one that is backing off, preserving order
+			if event.NextAttemptAt.After(now) {
+				return nil
+			}
+
+			messageID := event.EventID
+			if messageID == "" {
+				messageID = fmt.Sprintf("%d", event.ID)
+			}
+			err := publisher.Publish(ctx, &Message{
+				ID:          messageID,
+				RoutingKey:  event.RoutingKey,
+				ContentType: CloudEventsContentType,
+				Body:        event.Payload,
+			})
+			if err != nil {
+				// Broker outages don't count against the event; retry once reconnected
+				if errors.Is(err, ErrNotConnected) {
+					return nil
+				}
+				if err := r.recordFailure(tx, event, err, now); err != nil {
+					return err
+				}
+				if event.FailedAt == nil {
+					return nil
+				}
+				parked[aggregate] = true
+				continue
+			}
+
+			if err := tx.Model(event).Update("sent_at", now).Error; err != nil {
+				return err
+			}
+			relayed++
+		}
+		return nil
+	})
+	return relayed, err
+}
+
+/* recordFailure schedules a retry with exponential backoff, or gives up after maxAttempts or a return */
+func (r *OutboxRelay) recordFailure(tx *gorm.DB, event *models.OutboxEvent, cause error, now time.Time) error {
+	event.Attempts++
+	updates := map[string]interface{}{
+		"attempts":   event.Attempts,
+		"last_error": cause.Error(),
+	}
+
+	// Retrying can't help an unroutable event until a queue is bound; park it for replay
+	if event.Attempts >= r.maxAttempts || errors.Is(cause, ErrUnroutable) {
+		event.FailedAt = &now
+		updates["failed_at"] = now
+		logger.Error(fmt.Sprintf("Outbox event %d (%s) failed after %d attempts, parking it:", event.ID, event.RoutingKey, event.Attempts), cause)
+	} else {
+		updates["next_attempt_at"] = now.Add(outboxBackoff(event.Attempts))
+		logger.Warn(fmt.Sprintf("Outbox event %d (%s) publish failed, attempt %d:", event.ID, event.RoutingKey, event.Attempts), cause)
+	}
+
+	return tx.Model(event).Updates(updates).Error
+}
+
+/* purgeSent deletes published events older than the retention window */
+func (r *OutboxRelay) purgeSent(ctx context.Context) {
+	if r.retention <= 0 {
+		return
+	}
+	cutoff := time.Now().Add(-r.retention)
+	if err := r.db.WithContext(ctx).Where("sent_at < ?", cutoff).Delete(&models.OutboxEvent{}).Error; err != nil {
+		logger.Error("Failed to purge sent outbox events:", err)
+	}
+}
+
+/* outboxBackoff returns 2^attempts seconds, capped at maxOutboxBackoff */
+func outboxBackoff(attempts int) time.Duration {
+	if attempts > 8 {
+		return maxOutboxBackoff
+	}
+	backoff := time.Duration(1<<uint(attempts)) * time.Second
+	if backoff > maxOutboxBackoff {
+		return maxOutboxBackoff
+	}
+	return backoff
+}
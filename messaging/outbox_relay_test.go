@@ -0,0 +1,227 @@
+package messaging
+
+import (
+	"context"
+	"database/sql/driver"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"baseApi/logger"
+	"baseApi/models"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	gormlogger "gorm.io/gorm/logger"
+)
+
+/* TestMain initializes the logger the relay and brokers log through */
+func TestMain(m *testing.M) {
+	logger.InitLogger()
+	os.Exit(m.Run())
+}
+
+/* recordingPublisher records published message IDs and fails the ones listed in errs */
+type recordingPublisher struct {
+	published []string
+	errs      map[string]error
+}
+
+func (p *recordingPublisher) Publish(ctx context.Context, msg *Message) error {
+	if err := p.errs[msg.ID]; err != nil {
+		return err
+	}
+	p.published = append(p.published, msg.ID)
+	return nil
+}
+
+func (p *recordingPublisher) Close() error { return nil }
+
+/* newTestRelay returns a relay over a sqlmock connection */
+func newTestRelay(t *testing.T, maxAttempts int) (*OutboxRelay, sqlmock.Sqlmock) {
+	t.Helper()
+	conn, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create sqlmock: %v", err)
+	}
+	db, err := gorm.Open(postgres.New(postgres.Config{Conn: conn}), &gorm.Config{
+		Logger: gormlogger.Default.LogMode(gormlogger.Silent),
+	})
+	if err != nil {
+		t.Fatalf("failed to open gorm on sqlmock: %v", err)
+	}
+	t.Cleanup(func() {
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Errorf("database: %v", err)
+		}
+		conn.Close()
+	})
+	return &OutboxRelay{db: db, batchSize: 10, maxAttempts: maxAttempts}, mock
+}
+
+/* expectPending expects the relay to take the lock and select the given events */
+func expectPending(mock sqlmock.Sqlmock, events ...models.OutboxEvent) {
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT pg_try_advisory_xact_lock`).
+		WillReturnRows(sqlmock.NewRows([]string{"pg_try_advisory_xact_lock"}).AddRow(true))
+
+	rows := sqlmock.NewRows([]string{"id", "event_id", "aggregate_type", "aggregate_id", "routing_key", "payload", "attempts", "next_attempt_at"})
+	for _, e := range events {
+		rows.AddRow(e.ID, e.EventID, e.AggregateType, e.AggregateID, e.RoutingKey, []byte(`{}`), e.Attempts, e.NextAttemptAt)
+	}
+	mock.ExpectQuery(`SELECT \* FROM "outbox" WHERE sent_at IS NULL AND failed_at IS NULL ORDER BY id .*FOR UPDATE SKIP LOCKED`).
+		WillReturnRows(rows)
+}
+
+/* expectSent expects an event to be marked sent */
+func expectSent(mock sqlmock.Sqlmock, id uint64) {
+	mock.ExpectExec(`UPDATE "outbox" SET "sent_at"=\$1 WHERE "id" = \$2`).
+		WithArgs(sqlmock.AnyArg(), id).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+}
+
+/* expectRetry expects a failed event to be rescheduled */
+func expectRetry(mock sqlmock.Sqlmock, id uint64, attempts int, at time.Time) {
+	mock.ExpectExec(`UPDATE "outbox" SET "attempts"=\$1,"last_error"=\$2,"next_attempt_at"=\$3 WHERE "id" = \$4`).
+		WithArgs(attempts, sqlmock.AnyArg(), after(at), id).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+}
+
+/* expectParked expects a failed event to be parked */
+func expectParked(mock sqlmock.Sqlmock, id uint64, attempts int) {
+	mock.ExpectExec(`UPDATE "outbox" SET "attempts"=\$1,"failed_at"=\$2,"last_error"=\$3 WHERE "id" = \$4`).
+		WithArgs(attempts, sqlmock.AnyArg(), sqlmock.AnyArg(), id).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+}
+
+/* after matches a time argument no earlier than at */
+type after time.Time
+
+func (a after) Match(v driver.Value) bool {
+	t, ok := v.(time.Time)
+	return ok && !t.Before(time.Time(a))
+}
+
+/* pendingEvent returns an event due now */
+func pendingEvent(id uint64, aggregateID uint, attempts int) models.OutboxEvent {
+	return models.OutboxEvent{
+		ID:            id,
+		EventID:       fmt.Sprintf("event-%d", id),
+		AggregateType: "user",
+		AggregateID:   aggregateID,
+		RoutingKey:    "user.updated",
+		Attempts:      attempts,
+		NextAttemptAt: time.Now().Add(-time.Second),
+	}
+}
+
+/* TestOutboxRelayBacksOff checks a failed publish is retried later and holds back the events after it */
+func TestOutboxRelayBacksOff(t *testing.T) {
+	relay, mock := newTestRelay(t, 5)
+	publisher := &recordingPublisher{errs: map[string]error{"event-2": ErrNacked}}
+
+	start := time.Now()
+	expectPending(mock, pendingEvent(1, 7, 0), pendingEvent(2, 7, 1), pendingEvent(3, 8, 0))
+	expectSent(mock, 1)
+	// Second attempt: 2^2 seconds from now
+	expectRetry(mock, 2, 2, start.Add(4*time.Second))
+	mock.ExpectCommit()
+
+	relayed, err := relay.relayBatch(context.Background(), publisher)
+	if err != nil {
+		t.Fatalf("relayBatch: %v", err)
+	}
+	if relayed != 1 || fmt.Sprint(publisher.published) != "[event-1]" {
+		t.Errorf("relayed %d %v, want only event-1 before the failure", relayed, publisher.published)
+	}
+}
+
+/* TestOutboxRelayWaitsBehindBackoff checks no event overtakes one that is backing off */
+func TestOutboxRelayWaitsBehindBackoff(t *testing.T) {
+	relay, mock := newTestRelay(t, 5)
+	publisher := &recordingPublisher{}
+
+	backingOff := pendingEvent(1, 7, 2)
+	backingOff.NextAttemptAt = time.Now().Add(time.Minute)
+	expectPending(mock, backingOff, pendingEvent(2, 8, 0))
+	mock.ExpectCommit()
+
+	relayed, err := relay.relayBatch(context.Background(), publisher)
+	if err != nil {
+		t.Fatalf("relayBatch: %v", err)
+	}
+	if relayed != 0 || len(publisher.published) != 0 {
+		t.Errorf("published %v while event 1 was backing off", publisher.published)
+	}
+}
+
+/* TestOutboxRelayParks checks events are parked after maxAttempts or when unroutable, holding back their aggregate */
+func TestOutboxRelayParks(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		attempts int
+	}{
+		{"max attempts", ErrNacked, 2},
+		{"unroutable", fmt.Errorf("%w: user.updated (312 NO_ROUTE)", ErrUnroutable), 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			relay, mock := newTestRelay(t, 3)
+			publisher := &recordingPublisher{errs: map[string]error{"event-1": tt.err}}
+
+			// Event 3 belongs to the parked aggregate, event 2 doesn't
+			expectPending(mock, pendingEvent(1, 7, tt.attempts), pendingEvent(2, 8, 0), pendingEvent(3, 7, 0))
+			expectParked(mock, 1, tt.attempts+1)
+			expectSent(mock, 2)
+			mock.ExpectCommit()
+
+			relayed, err := relay.relayBatch(context.Background(), publisher)
+			if err != nil {
+				t.Fatalf("relayBatch: %v", err)
+			}
+			if relayed != 1 || fmt.Sprint(publisher.published) != "[event-2]" {
+				t.Errorf("relayed %d %v, want only the other aggregate's event-2", relayed, publisher.published)
+			}
+		})
+	}
+}
+
+/* TestOutboxRelayBrokerOutage checks an outage stops the batch without counting against the event */
+func TestOutboxRelayBrokerOutage(t *testing.T) {
+	relay, mock := newTestRelay(t, 3)
+	publisher := &recordingPublisher{errs: map[string]error{"event-1": ErrNotConnected}}
+
+	expectPending(mock, pendingEvent(1, 7, 2), pendingEvent(2, 8, 0))
+	mock.ExpectCommit()
+
+	if _, err := relay.relayBatch(context.Background(), publisher); err != nil {
+		t.Fatalf("relayBatch: %v", err)
+	}
+	if len(publisher.published) != 0 {
+		t.Errorf("published %v during the outage", publisher.published)
+	}
+}
+
+/* TestOutboxBackoff checks the retry delay doubles up to its cap */
+func TestOutboxBackoff(t *testing.T) {
+	tests := []struct {
+		attempts int
+		want     time.Duration
+	}{
+		{1, 2 * time.Second},
+		{2, 4 * time.Second},
+		{8, 256 * time.Second},
+		{9, maxOutboxBackoff},
+		{40, maxOutboxBackoff},
+	}
+
+	for _, tt := range tests {
+		if got := outboxBackoff(tt.attempts); got != tt.want {
+			t.Errorf("outboxBackoff(%d) = %s, want %s", tt.attempts, got, tt.want)
+		}
+	}
+}
@@ -2,8 +2,9 @@ package messaging
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
-	"log"
+	"sync"
 	"time"
 
 	"baseApi/config"
@@ -12,47 +13,85 @@ import (
 	"github.com/streadway/amqp"
 )
 
+// Outage modes for publishes attempted while the broker is unreachable
+const (
+	OutageModeFail   = "fail"   // return ErrNotConnected immediately
+	OutageModeBuffer = "buffer" // queue in memory and publish after reconnecting
+)
+
+var (
+	// ErrNotConnected is returned by fail-fast publishes while the broker (RabbitMQ, Redis) is down
+	ErrNotConnected = errors.New("message broker is not connected")
+	// ErrBufferFull is returned when the outage buffer cannot take more messages
+	ErrBufferFull = errors.New("RabbitMQ outage buffer is full")
+	// ErrPublisherClosed is returned after Close
+	ErrPublisherClosed = errors.New("RabbitMQ publisher is closed")
+)
+
+/* pendingPublish is a message held in the outage buffer */
+type pendingPublish struct {
+	routingKey string
+	msg        amqp.Publishing
+}
+
+/*
+RabbitMQPublisher publishes to the topic exchange over a pool of channels.
+
+amqp.Channel is not safe for concurrent publishing, so each publish borrows a
+channel from the pool. When the connection drops, a watcher reconnects with
+exponential backoff, re-declares the exchange and rebuilds the pool.
+*/
 type RabbitMQPublisher struct {
-	connection *amqp.Connection
-	channel    *amqp.Channel
-	exchange   string
+	url            string
+	exchange       string
+	poolSize       int
+	outageMode     string
+	publishTimeout time.Duration
+	confirmTimeout time.Duration
+	maxBackoff     time.Duration
+
+	mu          sync.RWMutex
+	connection  *amqp.Connection
+	pool        *channelPool
+	reconnected chan struct{} // closed and replaced after each successful reconnect
+
+	// Buffered messages keep their order: flushMu holds new publishes while a flush runs,
+	// and a message whose flush failed waits in head, ahead of the buffer
+	flushMu sync.RWMutex
+	buffer  chan pendingPublish
+	head    *pendingPublish
+	closed  chan struct{}
+	once    sync.Once
 }
 
 var rabbitMQInstance *RabbitMQPublisher
 
-/* InitRabbitMQ initializes RabbitMQ connection and sets up topic exchange */
+/*
+InitRabbitMQ initializes RabbitMQ connection and sets up topic exchange.
+
+If the first connection fails the error is returned, but the publisher is still
+installed and keeps reconnecting in the background.
+*/
 func InitRabbitMQ(cfg *config.Config) error {
-	conn, err := amqp.Dial(cfg.RabbitMQURL)
-	if err != nil {
-		return fmt.Errorf("failed to connect to RabbitMQ: %w", err)
+	r := &RabbitMQPublisher{
+		url:            cfg.RabbitMQURL,
+		exchange:       cfg.RabbitMQExchange,
+		poolSize:       cfg.RabbitMQChannelPoolSize,
+		outageMode:     cfg.RabbitMQOutageMode,
+		publishTimeout: cfg.RabbitMQPublishTimeout,
+		confirmTimeout: cfg.RabbitMQConfirmTimeout,
+		maxBackoff:     cfg.RabbitMQReconnectMaxBackoff,
+		reconnected:    make(chan struct{}),
+		closed:         make(chan struct{}),
 	}
-
-	ch, err := conn.Channel()
-	if err != nil {
-		conn.Close()
-		return fmt.Errorf("failed to open channel: %w", err)
-	}
-
-	// Declare topic exchange
-	err = ch.ExchangeDeclare(
-		cfg.RabbitMQExchange, // name
-		"topic",              // type
-		true,                 // durable
-		false,                // auto-deleted
-		false,                // internal
-		false,                // no-wait
-		nil,                  // arguments
-	)
-	if err != nil {
-		ch.Close()
-		conn.Close()
-		return fmt.Errorf("failed to declare exchange: %w", err)
+	if r.outageMode == OutageModeBuffer {
+		r.buffer = make(chan pendingPublish, cfg.RabbitMQBufferSize)
 	}
+	rabbitMQInstance = r
 
-	rabbitMQInstance = &RabbitMQPublisher{
-		connection: conn,
-		channel:    ch,
-		exchange:   cfg.RabbitMQExchange,
+	if err := r.connect(); err != nil {
+		go r.reconnect()
+		return err
 	}
 
 	logger.Info("RabbitMQ initialized successfully with topic exchange:", cfg.RabbitMQExchange)
@@ -64,9 +103,14 @@ func GetRabbitMQPublisher() *RabbitMQPublisher {
 	return rabbitMQInstance
 }
 
-/* PublishJSON publishes JSON data to RabbitMQ topic exchange */
-func (r *RabbitMQPublisher) PublishJSON(routingKey string, data interface{}) error {
-	if r == nil || r.channel == nil {
+/*
+PublishJSON publishes JSON data to RabbitMQ topic exchange.
+
+Messages are mandatory and confirmed; pass WithConfirm to wait for the broker's
+ack, otherwise nacks and unroutable returns are only logged and counted.
+*/
+func (r *RabbitMQPublisher) PublishJSON(routingKey string, data interface{}, opts ...PublishOption) error {
+	if r == nil {
 		return fmt.Errorf("RabbitMQ publisher not initialized")
 	}
 
@@ -76,37 +120,163 @@ func (r *RabbitMQPublisher) PublishJSON(routingKey string, data interface{}) err
 		return fmt.Errorf("failed to marshal data to JSON: %w", err)
 	}
 
-	// Publish message
-	err = r.channel.Publish(
-		r.exchange,   // exchange
-		routingKey,   // routing key
-		false,        // mandatory
-		false,        // immediate
-		amqp.Publishing{
-			ContentType: "application/json",
-			Body:        jsonData,
-		},
-	)
+	msg := amqp.Publishing{
+		ContentType: "application/json",
+		Body:        jsonData,
+	}
+	if err := r.publishOrBuffer(routingKey, msg, r.options(opts)); err != nil {
+		return err
+	}
+
+	logger.Info(fmt.Sprintf("Published message to exchange '%s' with routing key '%s'", r.exchange, routingKey))
+	return nil
+}
+
+/* PublishUserEvent publishes a user event as a schema-validated CloudEvent */
+func (r *RabbitMQPublisher) PublishUserEvent(eventType string, userID uint, data interface{}, opts ...PublishOption) error {
+	if r == nil {
+		return fmt.Errorf("RabbitMQ publisher not initialized")
+	}
+
+	event, err := NewUserEvent(eventType, userID, data)
+	if err != nil {
+		return err
+	}
+
+	body, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to marshal event: %w", err)
+	}
+	return r.publishOrBuffer(UserEventRoutingKey(eventType), amqp.Publishing{
+		ContentType:  CloudEventsContentType,
+		DeliveryMode: amqp.Persistent,
+		MessageId:    event.ID,
+		Timestamp:    time.Now(),
+		Body:         body,
+	}, r.options(opts))
+}
+
+/* options applies publish options over the configured defaults */
+func (r *RabbitMQPublisher) options(opts []PublishOption) publishOptions {
+	o := publishOptions{}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if o.waitConfirm && o.timeout <= 0 {
+		o.timeout = r.confirmTimeout
+	}
+	return o
+}
+
+/* publishOrBuffer publishes, or holds the message for later when configured to buffer during outages */
+func (r *RabbitMQPublisher) publishOrBuffer(routingKey string, msg amqp.Publishing, opts publishOptions) error {
+	if r.buffer == nil {
+		return r.publish(routingKey, msg, opts)
+	}
+
+	r.flushMu.RLock()
+	defer r.flushMu.RUnlock()
+
+	// Queue behind messages still waiting for the flush, even if the connection is back
+	if r.head == nil && len(r.buffer) == 0 {
+		err := r.publish(routingKey, msg, opts)
+		if !errors.Is(err, ErrNotConnected) {
+			return err
+		}
+	}
+
+	select {
+	case r.buffer <- pendingPublish{routingKey: routingKey, msg: msg}:
+		logger.Warn(fmt.Sprintf("RabbitMQ unavailable, buffered message with routing key '%s'", routingKey))
+		return nil
+	default:
+		return ErrBufferFull
+	}
+}
+
+/* publish sends one message to the topic exchange */
+func (r *RabbitMQPublisher) publish(routingKey string, msg amqp.Publishing, opts publishOptions) error {
+	return r.publishTo(r.exchange, routingKey, msg, opts)
+}
+
+/* publishTo sends one message on a pooled channel, optionally waiting for its confirm */
+func (r *RabbitMQPublisher) publishTo(exchange, routingKey string, msg amqp.Publishing, opts publishOptions) error {
+	select {
+	case <-r.closed:
+		return ErrPublisherClosed
+	default:
+	}
+
+	r.mu.RLock()
+	pool := r.pool
+	r.mu.RUnlock()
+	if pool == nil {
+		return ErrNotConnected
+	}
+
+	ch, err := pool.acquire(r.publishTimeout)
 	if err != nil {
+		return err
+	}
+
+	done, err := ch.publish(exchange, routingKey, msg, opts.waitConfirm)
+	if err != nil {
+		// A failed publish means the channel (or its connection) is closed
+		pool.discard(ch)
+		if errors.Is(err, amqp.ErrClosed) {
+			return fmt.Errorf("failed to publish message: %w", ErrNotConnected)
+		}
 		return fmt.Errorf("failed to publish message: %w", err)
 	}
 
-	logger.Info(fmt.Sprintf("Published message to exchange '%s' with routing key '%s'", r.exchange, routingKey))
+	// The channel is free for other publishers while this one waits for its confirm
+	pool.release(ch)
+
+	if done == nil {
+		return nil
+	}
+	if err := awaitConfirm(done, opts.timeout); err != nil {
+		return fmt.Errorf("failed to publish message: %w", err)
+	}
 	return nil
 }
 
-/* PublishUserEvent publishes user-related events */
-func (r *RabbitMQPublisher) PublishUserEvent(eventType string, userID uint, data interface{}) error {
-	routingKey := fmt.Sprintf("user.%s", eventType)
-	
-	eventData := map[string]interface{}{
-		"event_type": eventType,
-		"user_id":    userID,
-		"data":       data,
-		"timestamp":  fmt.Sprintf("%d", getCurrentTimestamp()),
+/* Channel opens a dedicated channel on the current connection (for consumers) */
+func (r *RabbitMQPublisher) Channel() (*amqp.Channel, error) {
+	r.mu.RLock()
+	conn := r.connection
+	r.mu.RUnlock()
+	if conn == nil {
+		return nil, ErrNotConnected
 	}
+	return conn.Channel()
+}
 
-	return r.PublishJSON(routingKey, eventData)
+/* Exchange returns the topic exchange name */
+func (r *RabbitMQPublisher) Exchange() string {
+	return r.exchange
+}
+
+/* Reconnected returns a channel that is closed after the next successful reconnect */
+func (r *RabbitMQPublisher) Reconnected() <-chan struct{} {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.reconnected
+}
+
+/* Done returns a channel that is closed when the publisher is closed */
+func (r *RabbitMQPublisher) Done() <-chan struct{} {
+	return r.closed
+}
+
+/* UserEventRoutingKey returns the topic routing key for a user event type */
+func UserEventRoutingKey(eventType string) string {
+	return fmt.Sprintf("user.%s", eventType)
+}
+
+/* NewUserEvent builds the CloudEvent for a user event, validating data against its schema */
+func NewUserEvent(eventType string, userID uint, data interface{}) (*CloudEvent, error) {
+	return NewCloudEvent(UserEventRoutingKey(eventType), UserEventSchemaVersion, fmt.Sprintf("users/%d", userID), data)
 }
 
 /* PublishSystemEvent publishes system-related events */
@@ -122,17 +292,27 @@ func (r *RabbitMQPublisher) PublishSystemEvent(eventType string, data interface{
 	return r.PublishJSON(routingKey, eventData)
 }
 
-/* Close closes RabbitMQ connection and channel */
+/* Close stops reconnecting and closes the channel pool and connection */
 func (r *RabbitMQPublisher) Close() error {
-	if r.channel != nil {
-		if err := r.channel.Close(); err != nil {
-			log.Printf("Error closing RabbitMQ channel: %v", err)
-		}
+	r.once.Do(func() {
+		close(r.closed)
+	})
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.buffer != nil && len(r.buffer) > 0 {
+		logger.Warn(fmt.Sprintf("Dropping %d buffered RabbitMQ messages on shutdown", len(r.buffer)))
+	}
+	if r.pool != nil {
+		r.pool.close()
+		r.pool = nil
 	}
 	if r.connection != nil {
-		if err := r.connection.Close(); err != nil {
-			log.Printf("Error closing RabbitMQ connection: %v", err)
+		if err := r.connection.Close(); err != nil && !errors.Is(err, amqp.ErrClosed) {
+			logger.Error("Error closing RabbitMQ connection:", err)
 		}
+		r.connection = nil
 	}
 	return nil
 }
@@ -140,4 +320,4 @@ func (r *RabbitMQPublisher) Close() error {
 /* getCurrentTimestamp returns current unix timestamp */
 func getCurrentTimestamp() int64 {
 	return time.Now().Unix()
-}
\ No newline at end of file
+}
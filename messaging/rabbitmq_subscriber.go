@@ -0,0 +1,364 @@
+package messaging
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"baseApi/logger"
+
+	"github.com/streadway/amqp"
+)
+
+/* Name identifies the backend */
+func (r *RabbitMQPublisher) Name() string {
+	return BackendRabbitMQ
+}
+
+/* Ping reports ErrNotConnected while the connection is down or being re-established */
+func (r *RabbitMQPublisher) Ping(ctx context.Context) error {
+	r.mu.RLock()
+	conn := r.connection
+	r.mu.RUnlock()
+	if conn == nil || conn.IsClosed() {
+		return ErrNotConnected
+	}
+	return nil
+}
+
+/*
+Publish sends a message to the topic exchange as a persistent delivery.
+
+It never buffers and always waits for the broker's confirm: callers that track
+delivery themselves (the outbox relay) must see outages, nacks and returns as errors.
+*/
+func (r *RabbitMQPublisher) Publish(ctx context.Context, msg *Message) error {
+	if r == nil {
+		return fmt.Errorf("RabbitMQ publisher not initialized")
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	timeout := r.confirmTimeout
+	if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < timeout {
+		timeout = time.Until(deadline)
+	}
+
+	timestamp := msg.Timestamp
+	if timestamp.IsZero() {
+		timestamp = time.Now()
+	}
+	return r.publish(msg.RoutingKey, amqp.Publishing{
+		Headers:      amqp.Table(msg.Headers),
+		ContentType:  msg.ContentType,
+		DeliveryMode: amqp.Persistent,
+		MessageId:    msg.ID,
+		Timestamp:    timestamp,
+		Body:         msg.Body,
+	}, publishOptions{waitConfirm: true, timeout: timeout})
+}
+
+/* Subscribe declares and binds the queue, then consumes with the configured worker pool */
+func (r *RabbitMQPublisher) Subscribe(ctx context.Context, cfg ConsumerConfig, router *Router) (Subscription, error) {
+	if r == nil {
+		return nil, fmt.Errorf("RabbitMQ not initialized")
+	}
+
+	s := &rabbitSubscription{
+		publisher: r,
+		cfg:       cfg.withDefaults(),
+		router:    router,
+		stopping:  make(chan struct{}),
+		stopped:   make(chan struct{}),
+	}
+	deliveries, err := s.subscribe()
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, s.cancel = context.WithCancel(ctx)
+	go s.supervise(ctx, deliveries)
+	return s, nil
+}
+
+/* rabbitSubscription consumes one queue bound to the topic exchange */
+type rabbitSubscription struct {
+	publisher *RabbitMQPublisher
+	cfg       ConsumerConfig
+	router    *Router
+
+	mu       sync.Mutex
+	channel  *amqp.Channel
+	cancel   context.CancelFunc
+	stopping chan struct{}
+	stopped  chan struct{}
+	once     sync.Once
+}
+
+/* subscribe opens a channel, declares the topology and starts consuming */
+func (s *rabbitSubscription) subscribe() (<-chan amqp.Delivery, error) {
+	ch, err := s.publisher.Channel()
+	if err != nil {
+		return nil, fmt.Errorf("failed to open consumer channel: %w", err)
+	}
+
+	if err := s.declare(ch); err != nil {
+		ch.Close()
+		return nil, err
+	}
+
+	deliveries, err := ch.Consume(
+		s.cfg.Queue,       // queue
+		s.cfg.ConsumerTag, // consumer
+		false,             // auto-ack
+		false,             // exclusive
+		false,             // no-local
+		false,             // no-wait
+		nil,               // args
+	)
+	if err != nil {
+		ch.Close()
+		return nil, fmt.Errorf("failed to consume queue %s: %w", s.cfg.Queue, err)
+	}
+
+	// Shutdown reads the channel under the same lock, so it either cancels this one or we close it
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	select {
+	case <-s.stopping:
+		ch.Close()
+		return nil, fmt.Errorf("consumer %s is shutting down", s.cfg.ConsumerTag)
+	default:
+	}
+	s.channel = ch
+	return deliveries, nil
+}
+
+/* supervise runs the worker pool and resubscribes whenever the channel is lost */
+func (s *rabbitSubscription) supervise(ctx context.Context, deliveries <-chan amqp.Delivery) {
+	defer close(s.stopped)
+
+	for {
+		var wg sync.WaitGroup
+		for i := 0; i < s.cfg.Workers; i++ {
+			wg.Add(1)
+			go s.work(ctx, &wg, deliveries)
+		}
+		wg.Wait()
+
+		select {
+		case <-s.stopping:
+			return
+		default:
+		}
+
+		// The channel is gone; Shutdown has nothing to cancel until resubscribed
+		s.mu.Lock()
+		s.channel = nil
+		s.mu.Unlock()
+
+		logger.Warn(fmt.Sprintf("Consumer '%s' lost its channel, resubscribing", s.cfg.ConsumerTag))
+		deliveries = s.resubscribe()
+		if deliveries == nil {
+			return
+		}
+	}
+}
+
+/* resubscribe retries subscribe after each reconnect (or periodically for channel-only failures) */
+func (s *rabbitSubscription) resubscribe() <-chan amqp.Delivery {
+	backoff := minReconnectBackoff
+	for {
+		select {
+		case <-s.stopping:
+			return nil
+		case <-s.publisher.Done():
+			return nil
+		case <-s.publisher.Reconnected():
+		case <-time.After(backoff):
+		}
+
+		deliveries, err := s.subscribe()
+		if err == nil {
+			logger.Info(fmt.Sprintf("Consumer '%s' resubscribed to queue '%s'", s.cfg.ConsumerTag, s.cfg.Queue))
+			return deliveries
+		}
+
+		if backoff *= 2; backoff > maxResubscribeBackoff {
+			backoff = maxResubscribeBackoff
+		}
+	}
+}
+
+/* declare sets QoS and declares the queue with its bindings */
+func (s *rabbitSubscription) declare(ch *amqp.Channel) error {
+	exchange := s.publisher.Exchange()
+	if err := ch.Qos(s.cfg.Prefetch, 0, false); err != nil {
+		return fmt.Errorf("failed to set prefetch: %w", err)
+	}
+
+	args := s.cfg.QueueArgs
+	if s.cfg.Retry != nil {
+		args = DeadLetterArgs(exchange, s.cfg.Queue, args)
+	}
+
+	_, err := ch.QueueDeclare(
+		s.cfg.Queue,       // name
+		!s.cfg.AutoDelete, // durable
+		s.cfg.AutoDelete,  // auto-delete
+		false,             // exclusive
+		false,             // no-wait
+		args,              // arguments
+	)
+	if err != nil {
+		return fmt.Errorf("failed to declare queue %s: %w", s.cfg.Queue, err)
+	}
+
+	if s.cfg.Retry != nil {
+		if err := DeclareRetryTopology(ch, exchange, s.cfg.Queue, *s.cfg.Retry); err != nil {
+			return err
+		}
+	}
+
+	for _, pattern := range s.cfg.bindingKeys(s.router) {
+		if err := ch.QueueBind(s.cfg.Queue, pattern, exchange, false, nil); err != nil {
+			return fmt.Errorf("failed to bind queue %s to %s: %w", s.cfg.Queue, pattern, err)
+		}
+	}
+	return nil
+}
+
+/* Shutdown stops new deliveries, waits for in-flight handlers, then closes the channel */
+func (s *rabbitSubscription) Shutdown(ctx context.Context) error {
+	s.once.Do(func() {
+		close(s.stopping)
+	})
+
+	s.mu.Lock()
+	ch := s.channel
+	s.mu.Unlock()
+
+	// Cancelling the consumer closes the deliveries channel once buffered messages are drained.
+	// While disconnected there is no channel, and closing stopping ends the resubscribe loop.
+	if ch != nil {
+		if err := ch.Cancel(s.cfg.ConsumerTag, false); err != nil {
+			logger.Error("Failed to cancel consumer", s.cfg.ConsumerTag+":", err)
+		}
+	}
+
+	var err error
+	select {
+	case <-s.stopped:
+		logger.Info(fmt.Sprintf("Consumer '%s' drained", s.cfg.ConsumerTag))
+	case <-ctx.Done():
+		// Handlers see their context cancelled; unacked messages are redelivered once the channel closes
+		err = fmt.Errorf("consumer %s shutdown: %w", s.cfg.ConsumerTag, ctx.Err())
+	}
+
+	s.cancel()
+	if ch != nil {
+		ch.Close()
+	}
+	return err
+}
+
+/* work handles deliveries until the channel closes */
+func (s *rabbitSubscription) work(ctx context.Context, wg *sync.WaitGroup, deliveries <-chan amqp.Delivery) {
+	defer wg.Done()
+	for d := range deliveries {
+		s.dispatch(ctx, d)
+	}
+}
+
+/* dispatch runs the matching handler and settles the delivery from its result */
+func (s *rabbitSubscription) dispatch(ctx context.Context, d amqp.Delivery) {
+	msg := rabbitMessage(d)
+
+	err := s.router.Dispatch(ctx, msg)
+	if err == nil {
+		if ackErr := msg.Ack(); ackErr != nil {
+			s.report(msg, fmt.Errorf("failed to ack: %w", ackErr))
+		}
+		return
+	}
+
+	s.report(msg, err)
+	if msg.settled {
+		return
+	}
+	switch onFailure(err, s.cfg.Retry, msg.Attempt()) {
+	case actionRetry:
+		s.retry(d, msg, err)
+	case actionDeadLetter:
+		s.deadLetter(d, msg, err)
+	default:
+		if nackErr := msg.Nack(IsRequeue(err)); nackErr != nil {
+			s.report(msg, fmt.Errorf("failed to nack: %w", nackErr))
+		}
+	}
+}
+
+/* retry publishes the message to its next delayed retry queue and acks the original */
+func (s *rabbitSubscription) retry(d amqp.Delivery, msg *Message, cause error) {
+	attempt := msg.Attempt() + 1
+	headers := copyHeaders(msg.Headers)
+	headers[HeaderAttempt] = int32(attempt)
+	headers[HeaderOriginalRoutingKey] = msg.RoutingKey
+	headers[HeaderLastError] = cause.Error()
+
+	r := s.publisher
+	err := r.publishTo(RetryExchange(r.exchange), retryQueue(s.cfg.Queue, attempt),
+		republishing(d, headers), publishOptions{waitConfirm: true, timeout: r.confirmTimeout})
+	if err != nil {
+		// Couldn't schedule the retry; put it straight back rather than lose it
+		s.report(msg, fmt.Errorf("failed to schedule retry %d: %w", attempt, err))
+		msg.Nack(true)
+		return
+	}
+	msg.Ack()
+}
+
+/* deadLetter moves the message to the dead queue with the failure recorded in its headers */
+func (s *rabbitSubscription) deadLetter(d amqp.Delivery, msg *Message, cause error) {
+	headers := copyHeaders(msg.Headers)
+	headers[HeaderOriginalRoutingKey] = msg.RoutingKey
+	headers[HeaderLastError] = cause.Error()
+	headers[HeaderDeadReason] = deadReason(cause)
+
+	r := s.publisher
+	err := r.publishTo(DeadLetterExchange(r.exchange), s.cfg.Queue,
+		republishing(d, headers), publishOptions{waitConfirm: true, timeout: r.confirmTimeout})
+	if err != nil {
+		// The queue's own x-dead-letter-exchange still routes it to the dead queue
+		msg.Nack(false)
+		return
+	}
+	msg.Ack()
+}
+
+/* report logs a handling failure and sends it to Sentry */
+func (s *rabbitSubscription) report(msg *Message, err error) {
+	reportHandlerError(BackendRabbitMQ, s.cfg.Queue, msg, err)
+}
+
+/* rabbitMessage wraps a delivery, restoring the topic key of retried messages */
+func rabbitMessage(d amqp.Delivery) *Message {
+	msg := &Message{
+		ID:          d.MessageId,
+		RoutingKey:  d.RoutingKey,
+		ContentType: d.ContentType,
+		Headers:     d.Headers,
+		Body:        d.Body,
+		Timestamp:   d.Timestamp,
+		Redelivered: d.Redelivered,
+		ack:         func() error { return d.Ack(false) },
+		nack:        func(requeue bool) error { return d.Nack(false, requeue) },
+	}
+	// Retried messages come back through the default exchange with the queue name as key
+	if original := headerString(d.Headers, HeaderOriginalRoutingKey); original != "" {
+		msg.RoutingKey = original
+	}
+	return msg
+}
@@ -0,0 +1,41 @@
+package messaging
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/streadway/amqp"
+)
+
+/* TestRabbitSubscriptionShutdownWhileDisconnected checks Shutdown works without a channel and can be called twice */
+func TestRabbitSubscriptionShutdownWhileDisconnected(t *testing.T) {
+	s := &rabbitSubscription{
+		publisher: &RabbitMQPublisher{}, // never connected
+		cfg:       ConsumerConfig{Queue: "users", ConsumerTag: "users-1", Workers: 1},
+		router:    &Router{},
+		stopping:  make(chan struct{}),
+		stopped:   make(chan struct{}),
+	}
+
+	// The channel was lost, so the supervisor is waiting to resubscribe
+	lost := make(chan amqp.Delivery)
+	close(lost)
+	ctx, cancel := context.WithCancel(context.Background())
+	s.cancel = cancel
+	go s.supervise(ctx, lost)
+
+	for i := 0; i < 2; i++ {
+		shutdownCtx, done := context.WithTimeout(context.Background(), time.Second)
+		err := s.Shutdown(shutdownCtx)
+		done()
+		if err != nil {
+			t.Fatalf("Shutdown %d: %v", i+1, err)
+		}
+	}
+	select {
+	case <-s.stopped:
+	default:
+		t.Error("supervisor is still running")
+	}
+}
@@ -0,0 +1,628 @@
+package messaging
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"baseApi/cache"
+	"baseApi/config"
+	"baseApi/logger"
+
+	"github.com/go-redis/redis/v8"
+)
+
+const (
+	redisReadBlock      = 2 * time.Second // XREADGROUP block time; bounds how long Shutdown waits for the reader
+	redisReclaimEvery   = time.Second     // how often pending entries are checked for redelivery
+	redisReclaimBatch   = 100
+	redisRequeueDelay   = time.Second // redelivery delay of Requeue errors without a RetryPolicy
+	redisErrorBackoff   = time.Second
+	redisGroupExistsErr = "BUSYGROUP"
+)
+
+// Stream entry fields
+const (
+	redisFieldID          = "id"
+	redisFieldRoutingKey  = "routing_key"
+	redisFieldContentType = "content_type"
+	redisFieldTimestamp   = "timestamp"
+	redisFieldHeaders     = "headers"
+	redisFieldBody        = "body"
+)
+
+/*
+RedisStreamsBroker publishes every message to one Redis stream and subscribes with consumer groups.
+
+Each queue is a consumer group reading the whole stream; entries whose routing key
+matches none of the group's patterns are acknowledged and skipped. Failed entries
+stay in the group's pending list and are claimed again once their retry delay has
+passed; entries left pending by a consumer that went away are claimed by another
+after REDIS_STREAM_CLAIM_IDLE. Dead letters go to the stream "<stream>.<queue>.dead";
+replayed ones to "<stream>.<queue>.replay", which only that queue's group reads.
+*/
+type RedisStreamsBroker struct {
+	client    *redis.Client
+	stream    string
+	maxLen    int64
+	claimIdle time.Duration
+}
+
+/* NewRedisStreamsBroker creates a broker on the shared Redis client (see cache.InitRedis) */
+func NewRedisStreamsBroker(cfg *config.Config) (*RedisStreamsBroker, error) {
+	client := cache.GetRedisClient()
+	if client == nil {
+		return nil, fmt.Errorf("redis streams broker: Redis not initialized")
+	}
+	return &RedisStreamsBroker{
+		client:    client,
+		stream:    cfg.RedisStreamName,
+		maxLen:    int64(cfg.RedisStreamMaxLen),
+		claimIdle: cfg.RedisStreamClaimIdle,
+	}, nil
+}
+
+/* Name identifies the backend */
+func (b *RedisStreamsBroker) Name() string {
+	return BackendRedis
+}
+
+/* Ping checks that Redis answers */
+func (b *RedisStreamsBroker) Ping(ctx context.Context) error {
+	return b.client.Ping(ctx).Err()
+}
+
+/* Publish appends the message to the stream, trimming it to about MaxLen entries */
+func (b *RedisStreamsBroker) Publish(ctx context.Context, msg *Message) error {
+	values, err := redisValues(msg)
+	if err != nil {
+		return err
+	}
+
+	err = b.client.XAdd(ctx, &redis.XAddArgs{
+		Stream: b.stream,
+		MaxLen: b.maxLen,
+		Approx: true,
+		Values: values,
+	}).Err()
+	if err != nil {
+		return redisError("failed to publish to Redis stream", err)
+	}
+	return nil
+}
+
+/* Close is a no-op: the Redis client is shared with the cache and closed with it */
+func (b *RedisStreamsBroker) Close() error {
+	return nil
+}
+
+/* Subscribe creates the queue's consumer group (at the end of the stream) and starts consuming */
+func (b *RedisStreamsBroker) Subscribe(ctx context.Context, cfg ConsumerConfig, router *Router) (Subscription, error) {
+	cfg = cfg.withDefaults()
+
+	err := b.client.XGroupCreateMkStream(ctx, b.stream, cfg.Queue, "$").Err()
+	if err != nil && !strings.HasPrefix(err.Error(), redisGroupExistsErr) {
+		return nil, redisError(fmt.Sprintf("failed to create consumer group %s", cfg.Queue), err)
+	}
+	// Replayed dead letters are read from the start; they were only ever meant for this group
+	err = b.client.XGroupCreateMkStream(ctx, b.replayStream(cfg.Queue), cfg.Queue, "0").Err()
+	if err != nil && !strings.HasPrefix(err.Error(), redisGroupExistsErr) {
+		return nil, redisError(fmt.Sprintf("failed to create consumer group %s", cfg.Queue), err)
+	}
+
+	hostname, _ := os.Hostname()
+	ctx, cancel := context.WithCancel(ctx)
+	s := &redisSubscription{
+		broker:     b,
+		cfg:        cfg,
+		router:     router,
+		streams:    []string{b.stream, b.replayStream(cfg.Queue)},
+		patterns:   cfg.bindingKeys(router),
+		consumer:   fmt.Sprintf("%s-%s-%d", cfg.ConsumerTag, hostname, os.Getpid()),
+		deliveries: make(chan redisDelivery, cfg.Prefetch),
+		inflight:   make(map[redisEntry]bool),
+		cancel:     cancel,
+		stopping:   make(chan struct{}),
+		stopped:    make(chan struct{}),
+	}
+
+	var readers, workers sync.WaitGroup
+	readers.Add(2)
+	go s.read(ctx, &readers)
+	go s.reclaim(ctx, &readers)
+	for i := 0; i < cfg.Workers; i++ {
+		workers.Add(1)
+		go s.work(ctx, &workers)
+	}
+	go func() {
+		readers.Wait()
+		close(s.deliveries)
+		workers.Wait()
+		close(s.stopped)
+	}()
+	return s, nil
+}
+
+/* InspectDeadLetters returns up to limit entries of a queue's dead-letter stream */
+func (b *RedisStreamsBroker) InspectDeadLetters(queue string, limit int) (*DeadLetterQueueInfo, error) {
+	ctx := context.Background()
+	dead := b.deadStream(queue)
+	if err := b.checkGroup(ctx, queue); err != nil {
+		return nil, err
+	}
+
+	count, err := b.client.XLen(ctx, dead).Result()
+	if err != nil {
+		return nil, redisError("dead-letter stream operation failed", err)
+	}
+	entries, err := b.client.XRangeN(ctx, dead, "-", "+", int64(limit)).Result()
+	if err != nil {
+		return nil, redisError("dead-letter stream operation failed", err)
+	}
+
+	info := &DeadLetterQueueInfo{
+		Queue:           queue,
+		DeadLetterQueue: dead,
+		Count:           int(count),
+		Messages:        []DeadLetter{},
+	}
+	for _, entry := range entries {
+		info.Messages = append(info.Messages, newDeadLetter(redisMessage(entry)))
+	}
+	return info, nil
+}
+
+/* ReplayDeadLetters moves up to limit dead letters to the queue's replay stream with a fresh attempt count */
+func (b *RedisStreamsBroker) ReplayDeadLetters(queue string, limit int) (int, error) {
+	ctx := context.Background()
+	dead := b.deadStream(queue)
+	if err := b.checkGroup(ctx, queue); err != nil {
+		return 0, err
+	}
+
+	entries, err := b.client.XRangeN(ctx, dead, "-", "+", int64(limit)).Result()
+	if err != nil {
+		return 0, redisError("dead-letter stream operation failed", err)
+	}
+
+	replayed := 0
+	for _, entry := range entries {
+		msg := redisMessage(entry)
+		msg.Headers = replayHeaders(msg.Headers)
+		values, err := redisValues(msg)
+		if err != nil {
+			return replayed, err
+		}
+		// Not the shared stream: every other consumer group would get it again
+		err = b.client.XAdd(ctx, &redis.XAddArgs{
+			Stream: b.replayStream(queue),
+			MaxLen: b.maxLen,
+			Approx: true,
+			Values: values,
+		}).Err()
+		if err != nil {
+			return replayed, redisError("failed to replay dead letter", err)
+		}
+		if err := b.client.XDel(ctx, dead, entry.ID).Err(); err != nil {
+			return replayed, redisError("dead-letter stream operation failed", err)
+		}
+		replayed++
+	}
+	return replayed, nil
+}
+
+/* PurgeDeadLetters deletes a queue's dead-letter stream */
+func (b *RedisStreamsBroker) PurgeDeadLetters(queue string) (int, error) {
+	ctx := context.Background()
+	dead := b.deadStream(queue)
+	if err := b.checkGroup(ctx, queue); err != nil {
+		return 0, err
+	}
+
+	count, err := b.client.XLen(ctx, dead).Result()
+	if err != nil {
+		return 0, redisError("dead-letter stream operation failed", err)
+	}
+	if err := b.client.Del(ctx, dead).Err(); err != nil {
+		return 0, redisError("dead-letter stream operation failed", err)
+	}
+	return int(count), nil
+}
+
+/* deadStream names the stream holding a queue's dead letters */
+func (b *RedisStreamsBroker) deadStream(queue string) string {
+	return b.stream + "." + DeadLetterQueue(queue)
+}
+
+/* replayStream names the stream holding a queue's replayed dead letters */
+func (b *RedisStreamsBroker) replayStream(queue string) string {
+	return b.stream + "." + queue + ".replay"
+}
+
+/* checkGroup returns ErrQueueNotFound unless the queue's consumer group exists */
+func (b *RedisStreamsBroker) checkGroup(ctx context.Context, queue string) error {
+	groups, err := b.client.XInfoGroups(ctx, b.stream).Result()
+	if err != nil {
+		if strings.Contains(err.Error(), "no such key") {
+			return ErrQueueNotFound
+		}
+		return redisError("dead-letter stream operation failed", err)
+	}
+	for _, group := range groups {
+		if group.Name == queue {
+			return nil
+		}
+	}
+	return ErrQueueNotFound
+}
+
+/* redisDelivery is a message with the stream entry it was read from */
+type redisDelivery struct {
+	msg   *Message
+	entry redisEntry
+}
+
+/* redisEntry identifies an entry of the shared stream or of a queue's replay stream */
+type redisEntry struct {
+	stream string
+	id     string
+}
+
+/* redisSubscription is one consumer of a consumer group */
+type redisSubscription struct {
+	broker   *RedisStreamsBroker
+	cfg      ConsumerConfig
+	router   *Router
+	streams  []string // the shared stream and the queue's replay stream
+	patterns []string
+	consumer string
+
+	deliveries chan redisDelivery
+
+	mu       sync.Mutex
+	inflight map[redisEntry]bool // entries read or claimed and not yet settled
+
+	cancel   context.CancelFunc
+	stopping chan struct{}
+	stopped  chan struct{}
+	once     sync.Once
+}
+
+/* Shutdown stops reading, waits for in-flight handlers and leaves unsettled entries pending */
+func (s *redisSubscription) Shutdown(ctx context.Context) error {
+	s.once.Do(func() {
+		close(s.stopping)
+	})
+
+	var err error
+	select {
+	case <-s.stopped:
+		logger.Info(fmt.Sprintf("Consumer '%s' drained", s.cfg.ConsumerTag))
+	case <-ctx.Done():
+		// Unacknowledged entries stay pending and are claimed by another consumer later
+		err = fmt.Errorf("consumer %s shutdown: %w", s.cfg.ConsumerTag, ctx.Err())
+	}
+
+	s.cancel()
+
+	// Per-process groups (AutoDelete) would otherwise pin stream entries in their pending lists forever
+	if s.cfg.AutoDelete {
+		for _, stream := range s.streams {
+			if derr := s.broker.client.XGroupDestroy(context.Background(), stream, s.cfg.Queue).Err(); derr != nil {
+				logger.Warn(fmt.Sprintf("Failed to destroy consumer group %s:", s.cfg.Queue), derr)
+			}
+		}
+	}
+	return err
+}
+
+/* read fetches new entries of the group's streams until the subscription stops */
+func (s *redisSubscription) read(ctx context.Context, wg *sync.WaitGroup) {
+	defer wg.Done()
+
+	client, group := s.broker.client, s.cfg.Queue
+	args := append(append([]string{}, s.streams...), ">", ">")
+	for !s.isStopping() {
+		streams, err := client.XReadGroup(ctx, &redis.XReadGroupArgs{
+			Group:    group,
+			Consumer: s.consumer,
+			Streams:  args,
+			Count:    int64(s.cfg.Prefetch),
+			Block:    redisReadBlock,
+		}).Result()
+		if err != nil {
+			if err != redis.Nil && ctx.Err() == nil {
+				logger.Error(fmt.Sprintf("Consumer '%s' failed to read stream %s:", s.cfg.ConsumerTag, s.broker.stream), err)
+				s.sleep(redisErrorBackoff)
+			}
+			continue
+		}
+
+		for _, st := range streams {
+			for _, entry := range st.Messages {
+				s.deliver(ctx, redisMessage(entry), redisEntry{stream: st.Stream, id: entry.ID})
+			}
+		}
+	}
+}
+
+/*
+reclaim redelivers pending entries whose retry delay has passed.
+
+Entries held by this consumer are retried after the policy's delay for their
+next attempt; entries held by other consumers are taken over once they have been
+idle for the claim timeout (the consumer probably crashed).
+*/
+func (s *redisSubscription) reclaim(ctx context.Context, wg *sync.WaitGroup) {
+	defer wg.Done()
+
+	ticker := time.NewTicker(redisReclaimEvery)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-s.stopping:
+			return
+		case <-ticker.C:
+		}
+
+		for _, stream := range s.streams {
+			s.reclaimStream(ctx, stream)
+		}
+	}
+}
+
+/* reclaimStream redelivers the due pending entries of one of the group's streams */
+func (s *redisSubscription) reclaimStream(ctx context.Context, stream string) {
+	client := s.broker.client
+	pending, err := client.XPendingExt(ctx, &redis.XPendingExtArgs{
+		Stream: stream,
+		Group:  s.cfg.Queue,
+		Start:  "-",
+		End:    "+",
+		Count:  redisReclaimBatch,
+	}).Result()
+	if err != nil {
+		if ctx.Err() == nil {
+			logger.Error(fmt.Sprintf("Consumer '%s' failed to list pending entries:", s.cfg.ConsumerTag), err)
+		}
+		return
+	}
+
+	for _, p := range pending {
+		minIdle := s.broker.claimIdle
+		if p.Consumer == s.consumer {
+			if s.isInflight(redisEntry{stream: stream, id: p.ID}) {
+				continue
+			}
+			minIdle = s.retryDelay(int(p.RetryCount))
+		}
+		if p.Idle < minIdle {
+			continue
+		}
+
+		claimed, err := client.XClaim(ctx, &redis.XClaimArgs{
+			Stream:   stream,
+			Group:    s.cfg.Queue,
+			Consumer: s.consumer,
+			MinIdle:  minIdle,
+			Messages: []string{p.ID},
+		}).Result()
+		if err != nil {
+			continue
+		}
+		for _, entry := range claimed {
+			if entry.Values == nil {
+				// Trimmed from the stream while pending; nothing left to deliver
+				client.XAck(ctx, stream, s.cfg.Queue, entry.ID)
+				continue
+			}
+			msg := redisMessage(entry)
+			msg.Redelivered = true
+			msg.Headers[HeaderAttempt] = int(p.RetryCount)
+			s.deliver(ctx, msg, redisEntry{stream: stream, id: entry.ID})
+		}
+	}
+}
+
+/* deliver hands an entry to the workers, acknowledging entries no pattern matches */
+func (s *redisSubscription) deliver(ctx context.Context, msg *Message, entry redisEntry) {
+	client, group := s.broker.client, s.cfg.Queue
+	if !s.matches(msg.RoutingKey) {
+		client.XAck(ctx, entry.stream, group, entry.id)
+		return
+	}
+
+	s.mu.Lock()
+	s.inflight[entry] = true
+	s.mu.Unlock()
+
+	msg.ack = func() error {
+		defer s.settle(entry)
+		return client.XAck(ctx, entry.stream, group, entry.id).Err()
+	}
+	msg.nack = func(requeue bool) error {
+		switch {
+		case requeue:
+			// Left pending; reclaim redelivers it
+			s.settle(entry)
+			return nil
+		case s.cfg.Retry != nil:
+			return s.deadLetter(ctx, msg, entry, fmt.Errorf("rejected by handler"))
+		default:
+			return msg.ack()
+		}
+	}
+	s.deliveries <- redisDelivery{msg: msg, entry: entry}
+}
+
+/* work handles delivered entries until the readers stop */
+func (s *redisSubscription) work(ctx context.Context, wg *sync.WaitGroup) {
+	defer wg.Done()
+	for d := range s.deliveries {
+		s.dispatch(ctx, d.msg, d.entry)
+	}
+}
+
+/* dispatch runs the matching handler and settles the entry from its result */
+func (s *redisSubscription) dispatch(ctx context.Context, msg *Message, entry redisEntry) {
+	err := s.router.Dispatch(ctx, msg)
+	if err == nil {
+		if ackErr := msg.Ack(); ackErr != nil {
+			s.report(msg, fmt.Errorf("failed to ack: %w", ackErr))
+		}
+		return
+	}
+
+	s.report(msg, err)
+	if msg.settled {
+		return
+	}
+	switch onFailure(err, s.cfg.Retry, msg.Attempt()) {
+	case actionRetry, actionRequeue:
+		msg.Nack(true)
+	case actionDeadLetter:
+		msg.settled = true
+		if dlErr := s.deadLetter(ctx, msg, entry, err); dlErr != nil {
+			s.report(msg, fmt.Errorf("failed to dead-letter: %w", dlErr))
+		}
+	default:
+		msg.Nack(false)
+	}
+}
+
+/* deadLetter appends the entry to the queue's dead-letter stream and acknowledges it */
+func (s *redisSubscription) deadLetter(ctx context.Context, msg *Message, entry redisEntry, cause error) error {
+	defer s.settle(entry)
+
+	dead := storedMessage(msg)
+	dead.Headers[HeaderOriginalRoutingKey] = msg.RoutingKey
+	dead.Headers[HeaderLastError] = cause.Error()
+	dead.Headers[HeaderDeadReason] = deadReason(cause)
+	values, err := redisValues(dead)
+	if err != nil {
+		return err
+	}
+
+	client := s.broker.client
+	err = client.XAdd(ctx, &redis.XAddArgs{Stream: s.broker.deadStream(s.cfg.Queue), Values: values}).Err()
+	if err != nil {
+		// Left pending: it is redelivered and dead-lettered again later
+		return redisError("failed to dead-letter entry", err)
+	}
+	return client.XAck(ctx, entry.stream, s.cfg.Queue, entry.id).Err()
+}
+
+/* retryDelay is how long an entry delivered n times waits before its next delivery */
+func (s *redisSubscription) retryDelay(deliveries int) time.Duration {
+	if s.cfg.Retry == nil {
+		return redisRequeueDelay
+	}
+	delays := s.cfg.Retry.Delays()
+	if deliveries < 1 {
+		return delays[0]
+	}
+	if deliveries > len(delays) {
+		return delays[len(delays)-1]
+	}
+	return delays[deliveries-1]
+}
+
+func (s *redisSubscription) matches(routingKey string) bool {
+	for _, pattern := range s.patterns {
+		if TopicMatch(pattern, routingKey) {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *redisSubscription) isInflight(entry redisEntry) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.inflight[entry]
+}
+
+func (s *redisSubscription) settle(entry redisEntry) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.inflight, entry)
+}
+
+func (s *redisSubscription) isStopping() bool {
+	select {
+	case <-s.stopping:
+		return true
+	default:
+		return false
+	}
+}
+
+func (s *redisSubscription) sleep(d time.Duration) {
+	select {
+	case <-s.stopping:
+	case <-time.After(d):
+	}
+}
+
+/* report logs a handling failure and sends it to Sentry */
+func (s *redisSubscription) report(msg *Message, err error) {
+	reportHandlerError(BackendRedis, s.cfg.Queue, msg, err)
+}
+
+/* redisValues encodes a message as stream entry fields */
+func redisValues(msg *Message) (map[string]interface{}, error) {
+	stored := storedMessage(msg)
+	values := map[string]interface{}{
+		redisFieldID:          stored.ID,
+		redisFieldRoutingKey:  stored.RoutingKey,
+		redisFieldContentType: stored.ContentType,
+		redisFieldTimestamp:   stored.Timestamp.UTC().Format(time.RFC3339Nano),
+		redisFieldBody:        string(stored.Body),
+	}
+	if len(stored.Headers) > 0 {
+		headers, err := json.Marshal(stored.Headers)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal message headers: %w", err)
+		}
+		values[redisFieldHeaders] = string(headers)
+	}
+	return values, nil
+}
+
+/* redisMessage decodes a stream entry */
+func redisMessage(entry redis.XMessage) *Message {
+	field := func(name string) string {
+		value, _ := entry.Values[name].(string)
+		return value
+	}
+
+	msg := &Message{
+		ID:          field(redisFieldID),
+		RoutingKey:  field(redisFieldRoutingKey),
+		ContentType: field(redisFieldContentType),
+		Headers:     map[string]interface{}{},
+		Body:        []byte(field(redisFieldBody)),
+	}
+	if headers := field(redisFieldHeaders); headers != "" {
+		json.Unmarshal([]byte(headers), &msg.Headers)
+	}
+	if ts, err := time.Parse(time.RFC3339Nano, field(redisFieldTimestamp)); err == nil {
+		msg.Timestamp = ts
+	}
+	return msg
+}
+
+/* redisError wraps a Redis failure, marking connection problems as ErrNotConnected */
+func redisError(action string, err error) error {
+	var netErr net.Error
+	if errors.As(err, &netErr) || errors.Is(err, redis.ErrClosed) || strings.Contains(err.Error(), "connection refused") {
+		return fmt.Errorf("%s: %v: %w", action, err, ErrNotConnected)
+	}
+	return fmt.Errorf("%s: %w", action, err)
+}
@@ -0,0 +1,138 @@
+package messaging
+
+import (
+	"bytes"
+	"embed"
+	"encoding/json"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/santhosh-tekuri/jsonschema/v5"
+)
+
+// User event data schema version currently published
+const UserEventSchemaVersion = "v1"
+
+// SchemaBaseURI prefixes CloudEvents "dataschema" URIs; it should point at the schema catalog endpoint
+var SchemaBaseURI = "https://api.example.com/api/v1/events/schemas/"
+
+//go:embed schemas/*.json
+var schemaFiles embed.FS
+
+/* EventSchema describes one versioned event data schema in the catalog */
+type EventSchema struct {
+	Type        string `json:"type"`
+	Event       string `json:"event"`
+	Version     string `json:"version"`
+	DataSchema  string `json:"dataschema"`
+	Title       string `json:"title"`
+	Description string `json:"description"`
+}
+
+type compiledSchema struct {
+	info   EventSchema
+	raw    []byte
+	schema *jsonschema.Schema
+}
+
+// schemas holds every embedded schema, keyed by "<event>/<version>"
+var schemas = map[string]*compiledSchema{}
+
+/* init compiles the embedded schemas named <event>.<version>.json */
+func init() {
+	entries, err := schemaFiles.ReadDir("schemas")
+	if err != nil {
+		panic(err)
+	}
+
+	compiler := jsonschema.NewCompiler()
+	compiler.Draft = jsonschema.Draft2020
+	compiler.AssertFormat = true
+
+	for _, entry := range entries {
+		name := strings.TrimSuffix(entry.Name(), ".json")
+		dot := strings.LastIndex(name, ".")
+		if dot < 0 {
+			panic("event schema file name must be <event>.<version>.json: " + entry.Name())
+		}
+		event, version := name[:dot], name[dot+1:]
+
+		raw, err := schemaFiles.ReadFile(path.Join("schemas", entry.Name()))
+		if err != nil {
+			panic(err)
+		}
+		url := "mem://schemas/" + event + "/" + version
+		if err := compiler.AddResource(url, bytes.NewReader(raw)); err != nil {
+			panic(fmt.Sprintf("invalid event schema %s: %v", entry.Name(), err))
+		}
+		schema := compiler.MustCompile(url)
+
+		var meta struct {
+			Title       string `json:"title"`
+			Description string `json:"description"`
+		}
+		json.Unmarshal(raw, &meta)
+
+		schemas[event+"/"+version] = &compiledSchema{
+			info: EventSchema{
+				Type:        EventTypePrefix + event,
+				Event:       event,
+				Version:     version,
+				Title:       meta.Title,
+				Description: meta.Description,
+			},
+			raw:    raw,
+			schema: schema,
+		}
+	}
+}
+
+/* SchemaURI returns the dataschema URI of an event version */
+func SchemaURI(event, version string) string {
+	return SchemaBaseURI + event + "/" + version
+}
+
+/* SchemaCatalog lists every published event schema, sorted by event and version */
+func SchemaCatalog() []EventSchema {
+	catalog := make([]EventSchema, 0, len(schemas))
+	for _, s := range schemas {
+		info := s.info
+		info.DataSchema = SchemaURI(info.Event, info.Version)
+		catalog = append(catalog, info)
+	}
+	sort.Slice(catalog, func(i, j int) bool {
+		if catalog[i].Event != catalog[j].Event {
+			return catalog[i].Event < catalog[j].Event
+		}
+		return catalog[i].Version < catalog[j].Version
+	})
+	return catalog
+}
+
+/* Schema returns the raw JSON Schema document of an event version */
+func Schema(event, version string) ([]byte, bool) {
+	s, ok := schemas[event+"/"+version]
+	if !ok {
+		return nil, false
+	}
+	return s.raw, true
+}
+
+/* ValidateEventData checks encoded event data against its versioned schema */
+func ValidateEventData(event, version string, data []byte) error {
+	s, ok := schemas[event+"/"+version]
+	if !ok {
+		return fmt.Errorf("no schema registered for event %s %s", event, version)
+	}
+
+	var doc interface{}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return fmt.Errorf("invalid %s data: %w", event, err)
+	}
+	if err := s.schema.Validate(doc); err != nil {
+		return fmt.Errorf("%s data does not match schema %s: %w", event, version, err)
+	}
+	return nil
+}
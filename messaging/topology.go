@@ -0,0 +1,193 @@
+package messaging
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/streadway/amqp"
+)
+
+// Headers carried by retried and dead-lettered messages
+const (
+	HeaderAttempt            = "x-retry-attempt"
+	HeaderOriginalRoutingKey = "x-original-routing-key"
+	HeaderLastError          = "x-last-error"
+	HeaderDeadReason         = "x-dead-reason"
+)
+
+/*
+RetryPolicy configures delayed retries for a consumer queue.
+
+Each delay gets its own retry queue whose x-message-ttl holds messages before
+dead-lettering them back onto the work queue, so a slow retry never blocks a fast
+one. Delays grow from InitialDelay by doubling up to MaxDelay.
+*/
+type RetryPolicy struct {
+	MaxRetries   int           // retries before a message is dead-lettered (default 5)
+	InitialDelay time.Duration // first retry delay (default 1s)
+	MaxDelay     time.Duration // cap for later delays (default 5m)
+}
+
+/* withDefaults fills unset policy fields */
+func (p RetryPolicy) withDefaults() RetryPolicy {
+	if p.MaxRetries <= 0 {
+		p.MaxRetries = 5
+	}
+	if p.InitialDelay <= 0 {
+		p.InitialDelay = time.Second
+	}
+	if p.MaxDelay <= 0 {
+		p.MaxDelay = 5 * time.Minute
+	}
+	return p
+}
+
+/* Delays returns the delay before each retry attempt */
+func (p RetryPolicy) Delays() []time.Duration {
+	p = p.withDefaults()
+	delays := make([]time.Duration, p.MaxRetries)
+	delay := p.InitialDelay
+	for i := range delays {
+		delays[i] = delay
+		if delay *= 2; delay > p.MaxDelay {
+			delay = p.MaxDelay
+		}
+	}
+	return delays
+}
+
+/* RetryExchange names the direct exchange retry queues are bound to */
+func RetryExchange(exchange string) string {
+	return exchange + ".retry"
+}
+
+/* DeadLetterExchange names the direct exchange dead queues are bound to */
+func DeadLetterExchange(exchange string) string {
+	return exchange + ".dlx"
+}
+
+/* DeadLetterQueue names the queue holding a work queue's dead-lettered messages */
+func DeadLetterQueue(queue string) string {
+	return queue + ".dead"
+}
+
+/* retryQueue names the retry queue for one attempt */
+func retryQueue(queue string, attempt int) string {
+	return fmt.Sprintf("%s.retry.%d", queue, attempt)
+}
+
+/* declareRetryExchanges declares the retry and dead-letter exchanges next to the topic exchange */
+func declareRetryExchanges(ch *amqp.Channel, exchange string) error {
+	for _, name := range []string{RetryExchange(exchange), DeadLetterExchange(exchange)} {
+		if err := ch.ExchangeDeclare(name, "direct", true, false, false, false, nil); err != nil {
+			return fmt.Errorf("failed to declare exchange %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+/* DeadLetterArgs returns the work queue arguments that route rejected messages to its dead queue */
+func DeadLetterArgs(exchange, queue string, args amqp.Table) amqp.Table {
+	merged := amqp.Table{}
+	for k, v := range args {
+		merged[k] = v
+	}
+	merged["x-dead-letter-exchange"] = DeadLetterExchange(exchange)
+	merged["x-dead-letter-routing-key"] = queue
+	return merged
+}
+
+/*
+DeclareRetryTopology declares the retry queues and dead queue of a work queue.
+
+	<exchange>.retry --<queue>.retry.N--> <queue>.retry.N (TTL) --default exchange--> <queue>
+	<exchange>.dlx   --<queue>---------> <queue>.dead
+
+The work queue itself must be declared with DeadLetterArgs.
+*/
+func DeclareRetryTopology(ch *amqp.Channel, exchange, queue string, policy RetryPolicy) error {
+	if err := declareRetryExchanges(ch, exchange); err != nil {
+		return err
+	}
+
+	for i, delay := range policy.Delays() {
+		name := retryQueue(queue, i+1)
+		_, err := ch.QueueDeclare(name, true, false, false, false, retryQueueArgs(queue, delay))
+		if err != nil {
+			return fmt.Errorf("failed to declare retry queue %s: %w", name, err)
+		}
+		if err := ch.QueueBind(name, name, RetryExchange(exchange), false, nil); err != nil {
+			return fmt.Errorf("failed to bind retry queue %s: %w", name, err)
+		}
+	}
+
+	dead := DeadLetterQueue(queue)
+	if _, err := ch.QueueDeclare(dead, true, false, false, false, nil); err != nil {
+		return fmt.Errorf("failed to declare dead-letter queue %s: %w", dead, err)
+	}
+	if err := ch.QueueBind(dead, queue, DeadLetterExchange(exchange), false, nil); err != nil {
+		return fmt.Errorf("failed to bind dead-letter queue %s: %w", dead, err)
+	}
+	return nil
+}
+
+/* retryQueueArgs holds messages for delay, then dead-letters them back onto queue through the default exchange */
+func retryQueueArgs(queue string, delay time.Duration) amqp.Table {
+	return amqp.Table{
+		"x-message-ttl":             int64(delay / time.Millisecond),
+		"x-dead-letter-exchange":    "",
+		"x-dead-letter-routing-key": queue,
+	}
+}
+
+/* headerInt reads an integer header whatever width the broker (or JSON, for Redis Streams) decoded it as */
+func headerInt(headers amqp.Table, key string) int {
+	switch v := headers[key].(type) {
+	case int:
+		return v
+	case float64:
+		return int(v)
+	case int16:
+		return int(v)
+	case int32:
+		return int(v)
+	case int64:
+		return int(v)
+	case uint8:
+		return int(v)
+	}
+	return 0
+}
+
+/* headerString reads a string header */
+func headerString(headers amqp.Table, key string) string {
+	if v, ok := headers[key].(string); ok {
+		return v
+	}
+	return ""
+}
+
+/* copyHeaders returns a writable copy of delivery headers */
+func copyHeaders(headers amqp.Table) amqp.Table {
+	copied := amqp.Table{}
+	for k, v := range headers {
+		copied[k] = v
+	}
+	return copied
+}
+
+/* republishing rebuilds a publishing from a delivery with new headers */
+func republishing(d amqp.Delivery, headers amqp.Table) amqp.Publishing {
+	return amqp.Publishing{
+		Headers:         headers,
+		ContentType:     d.ContentType,
+		ContentEncoding: d.ContentEncoding,
+		DeliveryMode:    amqp.Persistent,
+		CorrelationId:   d.CorrelationId,
+		MessageId:       d.MessageId,
+		Timestamp:       d.Timestamp,
+		Type:            d.Type,
+		AppId:           d.AppId,
+		Body:            d.Body,
+	}
+}
@@ -0,0 +1,124 @@
+package messaging
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/streadway/amqp"
+)
+
+/* TestRetryPolicyDelays checks delays double from InitialDelay up to MaxDelay */
+func TestRetryPolicyDelays(t *testing.T) {
+	tests := []struct {
+		policy RetryPolicy
+		want   string
+	}{
+		{RetryPolicy{}, "[1s 2s 4s 8s 16s]"},
+		{RetryPolicy{MaxRetries: 4, InitialDelay: 100 * time.Millisecond, MaxDelay: 300 * time.Millisecond}, "[100ms 200ms 300ms 300ms]"},
+		{RetryPolicy{MaxRetries: 1, InitialDelay: time.Minute}, "[1m0s]"},
+	}
+
+	for _, tt := range tests {
+		if got := fmt.Sprint(tt.policy.Delays()); got != tt.want {
+			t.Errorf("%+v: got %s, want %s", tt.policy, got, tt.want)
+		}
+	}
+}
+
+/* TestRetryTopologyNames checks the queue and exchange names the topology is declared with */
+func TestRetryTopologyNames(t *testing.T) {
+	names := map[string]string{
+		RetryExchange("events"):      "events.retry",
+		DeadLetterExchange("events"): "events.dlx",
+		DeadLetterQueue("emails"):    "emails.dead",
+		retryQueue("emails", 3):      "emails.retry.3",
+	}
+	for got, want := range names {
+		if got != want {
+			t.Errorf("got %s, want %s", got, want)
+		}
+	}
+}
+
+/* TestRetryQueueArgs checks retry queues expire messages back onto their work queue only */
+func TestRetryQueueArgs(t *testing.T) {
+	args := retryQueueArgs("emails", 1500*time.Millisecond)
+
+	if args["x-message-ttl"] != int64(1500) {
+		t.Errorf("got TTL %v, want 1500 ms", args["x-message-ttl"])
+	}
+	// The default exchange routes by queue name, so other queues bound to the topic key never see retries
+	if args["x-dead-letter-exchange"] != "" || args["x-dead-letter-routing-key"] != "emails" {
+		t.Errorf("got %v, want dead-lettering to emails through the default exchange", args)
+	}
+}
+
+/* TestDeadLetterArgs checks work queues dead-letter to their own dead queue without losing their arguments */
+func TestDeadLetterArgs(t *testing.T) {
+	own := amqp.Table{"x-max-length": int32(1000)}
+	args := DeadLetterArgs("events", "emails", own)
+
+	if args["x-dead-letter-exchange"] != "events.dlx" || args["x-dead-letter-routing-key"] != "emails" {
+		t.Errorf("got %v, want dead-lettering to events.dlx with key emails", args)
+	}
+	if args["x-max-length"] != int32(1000) {
+		t.Errorf("lost the queue's own arguments: %v", args)
+	}
+	if _, changed := own["x-dead-letter-exchange"]; changed {
+		t.Error("DeadLetterArgs modified its input")
+	}
+}
+
+/* TestOriginalRoutingKey checks replays recover the topic key from our header or the broker's x-death */
+func TestOriginalRoutingKey(t *testing.T) {
+	tests := []struct {
+		name     string
+		delivery amqp.Delivery
+		want     string
+	}{
+		{
+			name:     "dead-lettered by the consumer",
+			delivery: amqp.Delivery{RoutingKey: "emails", Headers: amqp.Table{HeaderOriginalRoutingKey: "user.created"}},
+			want:     "user.created",
+		},
+		{
+			name: "nacked to the DLX by the broker",
+			delivery: amqp.Delivery{RoutingKey: "emails", Headers: amqp.Table{
+				"x-death": []interface{}{amqp.Table{"routing-keys": []interface{}{"user.updated"}}},
+			}},
+			want: "user.updated",
+		},
+		{
+			name:     "no history",
+			delivery: amqp.Delivery{RoutingKey: "user.deleted"},
+			want:     "user.deleted",
+		},
+	}
+
+	for _, tt := range tests {
+		if got := originalRoutingKey(tt.delivery); got != tt.want {
+			t.Errorf("%s: got %s, want %s", tt.name, got, tt.want)
+		}
+	}
+}
+
+/* TestReplayHeaders checks replayed messages start over without their failure history */
+func TestReplayHeaders(t *testing.T) {
+	headers := amqp.Table{
+		HeaderAttempt:            int32(5),
+		HeaderLastError:          "smtp unavailable",
+		HeaderDeadReason:         "retries_exhausted",
+		HeaderOriginalRoutingKey: "user.created",
+		"x-death":                []interface{}{},
+		"traceparent":            "00-abc-def-01",
+	}
+	replay := replayHeaders(headers)
+
+	if len(replay) != 1 || replay["traceparent"] != "00-abc-def-01" {
+		t.Errorf("got %v, want only the application headers", replay)
+	}
+	if len(headers) != 6 {
+		t.Error("replayHeaders modified the dead letter's headers")
+	}
+}
@@ -0,0 +1,46 @@
+package middleware
+
+import (
+	"crypto/subtle"
+	"strings"
+
+	"baseApi/apperror"
+
+	"github.com/gin-gonic/gin"
+)
+
+// accessTokenParam carries the token for clients that can't send an Authorization header
+const accessTokenParam = "access_token"
+
+/* AdminAuth requires "Authorization: Bearer <token>" matching the configured admin token */
+func AdminAuth(token string) gin.HandlerFunc {
+	return adminAuth(token, false)
+}
+
+/*
+StreamAuth is AdminAuth that also accepts the token as ?access_token=.
+
+Browsers can't set headers on EventSource or WebSocket connections; the query
+parameter is redacted from request logs.
+*/
+func StreamAuth(token string) gin.HandlerFunc {
+	return adminAuth(token, true)
+}
+
+/* adminAuth checks the bearer token, falling back to ?access_token= when allowQuery is set */
+func adminAuth(token string, allowQuery bool) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		provided := strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer ")
+		if provided == "" && allowQuery {
+			provided = c.Query(accessTokenParam)
+		}
+		if token == "" || subtle.ConstantTimeCompare([]byte(provided), []byte(token)) != 1 {
+			c.Header("WWW-Authenticate", "Bearer")
+			c.Error(apperror.Unauthorized("Admin token required").WithKey("error.admin_token_required", nil))
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
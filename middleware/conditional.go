@@ -0,0 +1,89 @@
+package middleware
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"baseApi/apperror"
+
+	"github.com/gin-gonic/gin"
+)
+
+/* RequireIfMatch rejects unsafe requests that do not carry an If-Match header */
+func RequireIfMatch() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		switch c.Request.Method {
+		case "PUT", "PATCH", "DELETE":
+			if c.GetHeader("If-Match") == "" {
+				c.Error(apperror.PreconditionRequired("If-Match header is required").WithKey("error.if_match_required", nil))
+				c.Abort()
+				return
+			}
+		}
+
+		c.Next()
+	}
+}
+
+/* CacheControl sets the Cache-Control policy for the routes it is attached to */
+func CacheControl(policy string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if policy != "" {
+			c.Header("Cache-Control", policy)
+		}
+		c.Next()
+	}
+}
+
+/* NotModified sets ETag/Last-Modified validators and writes a 304 if the client's copy is fresh */
+func NotModified(c *gin.Context, etag string, lastModified time.Time) bool {
+	if etag != "" {
+		c.Header("ETag", etag)
+	}
+	if !lastModified.IsZero() {
+		c.Header("Last-Modified", lastModified.UTC().Format(http.TimeFormat))
+	}
+
+	if c.Request.Method != http.MethodGet && c.Request.Method != http.MethodHead {
+		return false
+	}
+
+	// If-None-Match takes precedence over If-Modified-Since (RFC 9110 13.2.2)
+	if inm := c.GetHeader("If-None-Match"); inm != "" {
+		if etag != "" && etagMatchesWeak(inm, etag) {
+			writeNotModified(c)
+			return true
+		}
+		return false
+	}
+
+	if ims := c.GetHeader("If-Modified-Since"); ims != "" && !lastModified.IsZero() {
+		since, err := http.ParseTime(ims)
+		if err == nil && !lastModified.Truncate(time.Second).After(since) {
+			writeNotModified(c)
+			return true
+		}
+	}
+
+	return false
+}
+
+/* etagMatchesWeak reports whether any tag in an If-None-Match header weakly matches etag */
+func etagMatchesWeak(header, etag string) bool {
+	target := strings.TrimPrefix(etag, "W/")
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimSpace(tag)
+		if tag == "*" || strings.TrimPrefix(tag, "W/") == target {
+			return true
+		}
+	}
+	return false
+}
+
+/* writeNotModified writes an empty 304 response */
+func writeNotModified(c *gin.Context) {
+	c.Status(http.StatusNotModified)
+	c.Writer.WriteHeaderNow()
+	c.Abort()
+}
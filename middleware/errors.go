@@ -0,0 +1,97 @@
+package middleware
+
+import (
+	"mime"
+	"strconv"
+	"strings"
+
+	"baseApi/apperror"
+	"baseApi/dto"
+	"baseApi/logger"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+/* ErrorHandler renders errors attached with c.Error as the standard API response */
+func ErrorHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Next()
+
+		if len(c.Errors) == 0 || c.Writer.Written() {
+			return
+		}
+
+		// The last error attached is the one that ended the request
+		appErr := apperror.From(c.Errors.Last().Err)
+		if appErr.Status >= 500 {
+			logger.WithFields(logrus.Fields{
+				"code":  appErr.Code,
+				"path":  c.Request.URL.Path,
+				"cause": appErr.Err,
+			}).Error(appErr.Message)
+		}
+
+		requestID := c.GetString("request_id")
+		locale := Locale(c)
+		response := appErr.Localize(locale).ToResponse()
+		response.Error.RequestID = requestID
+
+		// RFC 7807 is opt-in; APIResponse stays the default envelope
+		if WantsProblemJSON(c) {
+			instance := ""
+			if requestID != "" {
+				instance = "urn:request-id:" + requestID
+			}
+			c.Header("Content-Type", dto.ContentTypeProblem)
+			c.JSON(response.StatusCode, response.ToProblem(instance, locale))
+			return
+		}
+
+		c.JSON(response.StatusCode, response)
+	}
+}
+
+/* WantsProblemJSON reports whether the client prefers application/problem+json over application/json */
+func WantsProblemJSON(c *gin.Context) bool {
+	accept := c.GetHeader("Accept")
+	if accept == "" {
+		return false
+	}
+	// A tie keeps the APIResponse envelope, so */* and application/* still get it
+	return acceptQuality(accept, dto.ContentTypeProblem) > acceptQuality(accept, dto.ContentTypeJSON)
+}
+
+/* acceptQuality returns the q-value the most specific matching range of an Accept header gives mediaType */
+func acceptQuality(accept, mediaType string) float64 {
+	group, _, _ := strings.Cut(mediaType, "/")
+	quality, specificity := 0.0, -1
+	for _, part := range strings.Split(accept, ",") {
+		mediaRange, params, err := mime.ParseMediaType(part)
+		if err != nil {
+			continue
+		}
+
+		rangeSpecificity := -1
+		switch mediaRange {
+		case mediaType:
+			rangeSpecificity = 2
+		case group + "/*":
+			rangeSpecificity = 1
+		case "*/*":
+			rangeSpecificity = 0
+		}
+		if rangeSpecificity <= specificity {
+			continue
+		}
+
+		q := 1.0
+		if value, ok := params["q"]; ok {
+			if parsed, err := strconv.ParseFloat(value, 64); err == nil {
+				q = parsed
+			}
+		}
+		quality, specificity = q, rangeSpecificity
+	}
+	return quality
+}
@@ -0,0 +1,177 @@
+package middleware
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"time"
+
+	"baseApi/apperror"
+	"baseApi/cache"
+	"baseApi/logger"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	IdempotencyKeyHeader      = "Idempotency-Key"
+	IdempotencyReplayedHeader = "Idempotent-Replayed"
+	maxIdempotencyKeyLength   = 255
+)
+
+/* idempotencyRecord is the stored outcome of a request made with an Idempotency-Key */
+type idempotencyRecord struct {
+	Fingerprint string            `json:"fingerprint"`
+	StatusCode  int               `json:"statusCode"`
+	Headers     map[string]string `json:"headers"`
+	Body        []byte            `json:"body"`
+}
+
+/* idempotencyWriter captures the response body so it can be stored for replay */
+type idempotencyWriter struct {
+	gin.ResponseWriter
+	body *bytes.Buffer
+}
+
+/* Write writes to the client and the capture buffer */
+func (w *idempotencyWriter) Write(b []byte) (int, error) {
+	w.body.Write(b)
+	return w.ResponseWriter.Write(b)
+}
+
+/* WriteString writes to the client and the capture buffer */
+func (w *idempotencyWriter) WriteString(s string) (int, error) {
+	w.body.WriteString(s)
+	return w.ResponseWriter.WriteString(s)
+}
+
+/* IdempotencyMiddleware replays stored responses for requests retried with the same Idempotency-Key */
+func IdempotencyMiddleware(ttl, lockTTL time.Duration) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := c.GetHeader(IdempotencyKeyHeader)
+		if key == "" || cache.GetRedisClient() == nil {
+			c.Next()
+			return
+		}
+		if len(key) > maxIdempotencyKeyLength {
+			c.Error(apperror.BadRequest("Idempotency-Key must be at most 255 characters").
+				WithKey("error.idempotency_key_too_long", map[string]string{"max": "255"}))
+			c.Abort()
+			return
+		}
+
+		var bodyBytes []byte
+		if c.Request.Body != nil {
+			bodyBytes, _ = io.ReadAll(c.Request.Body)
+			c.Request.Body = io.NopCloser(bytes.NewBuffer(bodyBytes))
+		}
+		fingerprint := requestFingerprint(c, bodyBytes)
+
+		recordKey := "idempotency:" + c.Request.Method + ":" + c.FullPath() + ":" + key
+		lockKey := recordKey + ":lock"
+
+		// Replay a completed request, or reject reuse of the key for a different payload
+		if replayStored(c, recordKey, fingerprint) {
+			return
+		}
+
+		// Only one in-flight request per key; concurrent duplicates are told to retry
+		// The lock holds a token of its own so only this request releases it
+		token := NewRequestID()
+		locked, err := cache.SetNX(lockKey, token, lockTTL)
+		if err != nil {
+			logger.Error("Idempotency lock failed, processing request without it:", err)
+			c.Next()
+			return
+		}
+		if !locked {
+			c.Header("Retry-After", "1")
+			c.Error(apperror.Conflict("A request with this Idempotency-Key is already in progress").
+				WithKey("error.idempotency_in_progress", nil))
+			c.Abort()
+			return
+		}
+		defer func() {
+			if err := cache.DeleteIfValue(lockKey, token); err != nil {
+				logger.Error("Failed to release idempotency lock:", err)
+			}
+		}()
+
+		// A duplicate may have completed between the first lookup and taking the lock
+		if replayStored(c, recordKey, fingerprint) {
+			return
+		}
+
+		writer := &idempotencyWriter{ResponseWriter: c.Writer, body: &bytes.Buffer{}}
+		c.Writer = writer
+
+		c.Next()
+
+		// Errors attached with c.Error are rendered later by ErrorHandler, so the
+		// writer still holds Gin's default 200 and no body. They are not stored;
+		// a failed request changed nothing and may be retried with the same key.
+		if len(c.Errors) > 0 {
+			return
+		}
+
+		// Server errors are not stored so the client can retry them
+		if c.Writer.Status() >= 500 {
+			return
+		}
+
+		record := idempotencyRecord{
+			Fingerprint: fingerprint,
+			StatusCode:  c.Writer.Status(),
+			Headers: map[string]string{
+				"Content-Type": c.Writer.Header().Get("Content-Type"),
+				"Location":     c.Writer.Header().Get("Location"),
+				"ETag":         c.Writer.Header().Get("ETag"),
+			},
+			Body: writer.body.Bytes(),
+		}
+		if err := cache.Set(recordKey, record, ttl); err != nil {
+			logger.Error("Failed to store idempotent response:", err)
+		}
+	}
+}
+
+/* requestFingerprint hashes the parts of a request that must match on retry */
+func requestFingerprint(c *gin.Context, body []byte) string {
+	hash := sha256.New()
+	hash.Write([]byte(c.Request.Method))
+	hash.Write([]byte(c.Request.URL.Path))
+	hash.Write(body)
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+/* replayStored answers from a stored record, rejecting reuse of the key for a different payload */
+func replayStored(c *gin.Context, recordKey, fingerprint string) bool {
+	var record idempotencyRecord
+	if err := cache.Get(recordKey, &record); err != nil {
+		return false
+	}
+
+	if record.Fingerprint != fingerprint {
+		c.Error(apperror.Conflict("Idempotency-Key was already used with a different request body").
+			WithKey("error.idempotency_key_reused", nil))
+		c.Abort()
+		return true
+	}
+
+	replayResponse(c, record)
+	return true
+}
+
+/* replayResponse writes a stored response back to the client */
+func replayResponse(c *gin.Context, record idempotencyRecord) {
+	for name, value := range record.Headers {
+		if value != "" {
+			c.Header(name, value)
+		}
+	}
+	c.Header(IdempotencyReplayedHeader, "true")
+	c.Status(record.StatusCode)
+	c.Writer.Write(record.Body)
+	c.Abort()
+}
@@ -0,0 +1,38 @@
+package middleware
+
+import (
+	"baseApi/i18n"
+
+	"github.com/gin-gonic/gin"
+)
+
+/* LocaleMiddleware negotiates the response locale: ?lang=, then the locale cookie, then Accept-Language */
+func LocaleMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		locale := ""
+
+		// Explicit user preference wins over the browser's Accept-Language
+		if lang := c.Query("lang"); i18n.Supported(lang) {
+			locale = lang
+		} else if cookie, err := c.Cookie("locale"); err == nil && i18n.Supported(cookie) {
+			locale = cookie
+		} else {
+			locale = i18n.Negotiate(c.GetHeader("Accept-Language"))
+		}
+
+		c.Set("locale", locale)
+		c.Header("Content-Language", locale)
+		// The locale cookie selects a representation too, so shared caches must key on it
+		c.Header("Vary", "Accept, Accept-Language, Cookie")
+
+		c.Next()
+	}
+}
+
+/* Locale returns the negotiated locale for the request */
+func Locale(c *gin.Context) string {
+	if locale := c.GetString("locale"); locale != "" {
+		return locale
+	}
+	return i18n.DefaultLocale
+}
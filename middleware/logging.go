@@ -3,6 +3,7 @@ package middleware
 import (
 	"bytes"
 	"io"
+	"net/url"
 	"strings"
 	"time"
 
@@ -35,9 +36,10 @@ func LoggingMiddleware() gin.HandlerFunc {
 		// Prepare log fields
 		logFields := logrus.Fields{
 			"timestamp":     startTime.Format("2006-01-02 15:04:05"),
+			"request_id":    c.GetString("request_id"),
 			"method":        c.Request.Method,
 			"path":          c.Request.URL.Path,
-			"query_params":  c.Request.URL.RawQuery,
+			"query_params":  redactQuery(c.Request.URL),
 			"status_code":   c.Writer.Status(),
 			"duration_ms":   duration.Milliseconds(),
 			"client_ip":     c.ClientIP(),
@@ -50,7 +52,7 @@ func LoggingMiddleware() gin.HandlerFunc {
 		headers := make(map[string]string)
 		for name, values := range c.Request.Header {
 			// Skip sensitive headers
-			if !isSensitiveHeader(name) {
+			if !IsSensitiveHeader(name) {
 				headers[name] = strings.Join(values, ", ")
 			} else {
 				headers[name] = "[REDACTED]"
@@ -75,7 +77,7 @@ func LoggingMiddleware() gin.HandlerFunc {
 			}
 			
 			// Check if body contains sensitive data
-			if containsSensitiveData(bodyStr) {
+			if ContainsSensitiveData(bodyStr) {
 				logFields["request_body"] = "[CONTAINS SENSITIVE DATA]"
 			} else {
 				logFields["request_body"] = bodyStr
@@ -91,8 +93,8 @@ func LoggingMiddleware() gin.HandlerFunc {
 	}
 }
 
-/* isSensitiveHeader checks if a header contains sensitive information */
-func isSensitiveHeader(headerName string) bool {
+/* IsSensitiveHeader checks if a header contains sensitive information */
+func IsSensitiveHeader(headerName string) bool {
 	sensitiveHeaders := []string{
 		"authorization",
 		"cookie",
@@ -110,8 +112,25 @@ func isSensitiveHeader(headerName string) bool {
 	return false
 }
 
-/* containsSensitiveData checks if request body contains sensitive information */
-func containsSensitiveData(body string) bool {
+/* redactQuery encodes query parameters with credentials (?access_token=) replaced */
+func redactQuery(u *url.URL) string {
+	query := u.Query()
+	if !query.Has(accessTokenParam) {
+		return u.RawQuery
+	}
+	query.Set(accessTokenParam, "[REDACTED]")
+	return query.Encode()
+}
+
+/* redactURL is the URL with redactQuery applied */
+func redactURL(u *url.URL) string {
+	redacted := *u
+	redacted.RawQuery = redactQuery(u)
+	return redacted.String()
+}
+
+/* ContainsSensitiveData checks if request body contains sensitive information */
+func ContainsSensitiveData(body string) bool {
 	sensitiveFields := []string{
 		"password",
 		"token",
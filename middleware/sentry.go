@@ -3,6 +3,7 @@ package middleware
 import (
 	"time"
 
+	"baseApi/apperror"
 	"baseApi/monitoring"
 
 	"github.com/getsentry/sentry-go"
@@ -23,9 +24,9 @@ func SentryMiddleware() gin.HandlerFunc {
 			sentry.ConfigureScope(func(scope *sentry.Scope) {
 				scope.SetContext("request", map[string]interface{}{
 					"method":       c.Request.Method,
-					"url":          c.Request.URL.String(),
+					"url":          redactURL(c.Request.URL),
 					"path":         c.Request.URL.Path,
-					"query":        c.Request.URL.RawQuery,
+					"query":        redactQuery(c.Request.URL),
 					"headers":      filterSensitiveHeaders(c.Request.Header),
 					"content_type": c.GetHeader("Content-Type"),
 					"user_agent":   c.GetHeader("User-Agent"),
@@ -157,6 +158,10 @@ func CaptureErrorMiddleware() gin.HandlerFunc {
 		// Check if there are any errors in the context
 		if len(c.Errors) > 0 {
 			for _, ginErr := range c.Errors {
+				// Client errors are expected outcomes, not incidents
+				if apperror.From(ginErr.Err).Status < 500 {
+					continue
+				}
 				monitoring.CaptureError(
 					ginErr.Err,
 					map[string]interface{}{
@@ -0,0 +1,27 @@
+package models
+
+import (
+	"time"
+)
+
+/* OutboxEvent is a domain event written in the same transaction as the change it describes */
+type OutboxEvent struct {
+	ID            uint64     `json:"id" gorm:"primaryKey"`
+	EventID       string     `json:"eventId" gorm:"column:event_id;size:36"`
+	AggregateType string     `json:"aggregateType" gorm:"column:aggregate_type;not null;size:50"`
+	AggregateID   uint       `json:"aggregateId" gorm:"column:aggregate_id;not null"`
+	EventType     string     `json:"eventType" gorm:"column:event_type;not null;size:50"`
+	RoutingKey    string     `json:"routingKey" gorm:"column:routing_key;not null;size:100"`
+	Payload       []byte     `json:"payload" gorm:"column:payload;type:jsonb;not null"`
+	Attempts      int        `json:"attempts" gorm:"column:attempts;not null;default:0"`
+	LastError     string     `json:"lastError" gorm:"column:last_error;type:text"`
+	NextAttemptAt time.Time  `json:"nextAttemptAt" gorm:"column:next_attempt_at;not null"`
+	CreatedAt     time.Time  `json:"createdAt" gorm:"column:created_at"`
+	SentAt        *time.Time `json:"sentAt" gorm:"column:sent_at;index"`
+	FailedAt      *time.Time `json:"failedAt" gorm:"column:failed_at"`
+}
+
+/* TableName specifies the table name for OutboxEvent model */
+func (OutboxEvent) TableName() string {
+	return "outbox"
+}
@@ -17,6 +17,7 @@ type User struct {
 	FirstName string         `json:"firstName" gorm:"column:first_name;size:50"`
 	LastName  string         `json:"lastName" gorm:"column:last_name;size:50"`
 	IsActive  bool           `json:"isActive" gorm:"column:is_active;default:true"`
+	Version   uint           `json:"version" gorm:"column:version;not null;default:1"`
 	CreatedAt time.Time      `json:"createdAt" gorm:"column:created_at"`
 	UpdatedAt time.Time      `json:"updatedAt" gorm:"column:updated_at"`
 	DeletedAt gorm.DeletedAt `json:"-" gorm:"column:deleted_at;index"`
@@ -36,6 +37,7 @@ func (u *User) ToDTO() dto.UserResponse {
 		FirstName: u.FirstName,
 		LastName:  u.LastName,
 		IsActive:  u.IsActive,
+		Version:   u.Version,
 		CreatedAt: u.CreatedAt,
 		UpdatedAt: u.UpdatedAt,
 	}
@@ -49,6 +51,7 @@ func (u *User) FromCreateDTO(req dto.CreateUserRequest) {
 	u.FirstName = req.FirstName
 	u.LastName = req.LastName
 	u.IsActive = true
+	u.Version = 1
 }
 
 /* UpdateFromDTO updates User model from UpdateUserRequest DTO */
@@ -70,6 +73,29 @@ func (u *User) UpdateFromDTO(req dto.UpdateUserRequest) {
 	}
 }
 
+/* ToPatchDocument converts User model to the document a PATCH request is applied to */
+func (u *User) ToPatchDocument() dto.PatchUserDocument {
+	isActive := u.IsActive
+	return dto.PatchUserDocument{
+		Username:  u.Username,
+		Email:     u.Email,
+		FirstName: u.FirstName,
+		LastName:  u.LastName,
+		IsActive:  &isActive,
+	}
+}
+
+/* ApplyPatchDocument replaces User fields with a patched document, including empty values */
+func (u *User) ApplyPatchDocument(doc dto.PatchUserDocument) {
+	u.Username = doc.Username
+	u.Email = doc.Email
+	u.FirstName = doc.FirstName
+	u.LastName = doc.LastName
+	if doc.IsActive != nil {
+		u.IsActive = *doc.IsActive
+	}
+}
+
 /* ToListDTO converts slice of User models to UserListResponse DTO */
 func ToUserListDTO(users []User, pagination *dto.PaginationMeta) dto.UserListResponse {
 	userDTOs := make([]dto.UserResponse, len(users))
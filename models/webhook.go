@@ -0,0 +1,130 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"baseApi/dto"
+
+	"gorm.io/gorm"
+)
+
+// Webhook delivery statuses
+const (
+	DeliveryPending   = "pending"   // waiting for its first attempt
+	DeliveryRetrying  = "retrying"  // failed, next attempt scheduled
+	DeliverySucceeded = "succeeded" // receiver answered 2xx
+	DeliveryFailed    = "failed"    // attempts exhausted or endpoint disabled
+)
+
+/* StringList is a string slice stored as a JSONB array */
+type StringList []string
+
+/* Value implements driver.Valuer */
+func (l StringList) Value() (driver.Value, error) {
+	if l == nil {
+		return "[]", nil
+	}
+	b, err := json.Marshal([]string(l))
+	return string(b), err
+}
+
+/* Scan implements sql.Scanner */
+func (l *StringList) Scan(value interface{}) error {
+	switch v := value.(type) {
+	case nil:
+		*l = nil
+		return nil
+	case []byte:
+		return json.Unmarshal(v, (*[]string)(l))
+	case string:
+		return json.Unmarshal([]byte(v), (*[]string)(l))
+	}
+	return fmt.Errorf("cannot scan %T into StringList", value)
+}
+
+/* WebhookEndpoint is a partner URL that receives signed HTTP callbacks for matching events */
+type WebhookEndpoint struct {
+	ID                  uint           `json:"id" gorm:"primaryKey"`
+	URL                 string         `json:"url" gorm:"column:url;not null;size:500"`
+	Description         string         `json:"description" gorm:"column:description;size:255"`
+	Events              StringList     `json:"events" gorm:"column:events;type:jsonb;not null"`
+	Secret              string         `json:"-" gorm:"column:secret;not null;size:128"`
+	IsActive            bool           `json:"isActive" gorm:"column:is_active;not null;default:true"`
+	ConsecutiveFailures int            `json:"consecutiveFailures" gorm:"column:consecutive_failures;not null;default:0"`
+	LastSuccessAt       *time.Time     `json:"lastSuccessAt" gorm:"column:last_success_at"`
+	DisabledAt          *time.Time     `json:"disabledAt" gorm:"column:disabled_at"`
+	DisabledReason      string         `json:"disabledReason" gorm:"column:disabled_reason;size:255"`
+	CreatedAt           time.Time      `json:"createdAt" gorm:"column:created_at"`
+	UpdatedAt           time.Time      `json:"updatedAt" gorm:"column:updated_at"`
+	DeletedAt           gorm.DeletedAt `json:"-" gorm:"column:deleted_at;index"`
+}
+
+/* TableName specifies the table name for WebhookEndpoint model */
+func (WebhookEndpoint) TableName() string {
+	return "webhook_endpoints"
+}
+
+/* ToDTO converts WebhookEndpoint model to WebhookResponse DTO (without the secret) */
+func (w *WebhookEndpoint) ToDTO() dto.WebhookResponse {
+	return dto.WebhookResponse{
+		ID:                  w.ID,
+		URL:                 w.URL,
+		Description:         w.Description,
+		Events:              []string(w.Events),
+		IsActive:            w.IsActive,
+		ConsecutiveFailures: w.ConsecutiveFailures,
+		LastSuccessAt:       w.LastSuccessAt,
+		DisabledAt:          w.DisabledAt,
+		DisabledReason:      w.DisabledReason,
+		CreatedAt:           w.CreatedAt,
+		UpdatedAt:           w.UpdatedAt,
+	}
+}
+
+/* WebhookDelivery is one event sent (or to be sent) to one endpoint, with the outcome of its last attempt */
+type WebhookDelivery struct {
+	ID             uint64     `json:"id" gorm:"primaryKey"`
+	EndpointID     uint       `json:"endpointId" gorm:"column:endpoint_id;not null;uniqueIndex:idx_webhook_deliveries_endpoint_event"`
+	EventID        string     `json:"eventId" gorm:"column:event_id;not null;size:64;uniqueIndex:idx_webhook_deliveries_endpoint_event"`
+	EventType      string     `json:"eventType" gorm:"column:event_type;not null;size:100"`
+	Payload        []byte     `json:"payload" gorm:"column:payload;type:jsonb;not null"`
+	Status         string     `json:"status" gorm:"column:status;not null;size:20;default:pending;index:idx_webhook_deliveries_due,priority:1"`
+	Attempts       int        `json:"attempts" gorm:"column:attempts;not null;default:0"`
+	NextAttemptAt  time.Time  `json:"nextAttemptAt" gorm:"column:next_attempt_at;not null;index:idx_webhook_deliveries_due,priority:2"`
+	ResponseStatus int        `json:"responseStatus" gorm:"column:response_status"`
+	ResponseBody   string     `json:"responseBody" gorm:"column:response_body;type:text"`
+	LastError      string     `json:"lastError" gorm:"column:last_error;type:text"`
+	DurationMs     int64      `json:"durationMs" gorm:"column:duration_ms"`
+	DeliveredAt    *time.Time `json:"deliveredAt" gorm:"column:delivered_at"`
+	CreatedAt      time.Time  `json:"createdAt" gorm:"column:created_at;index"`
+	UpdatedAt      time.Time  `json:"updatedAt" gorm:"column:updated_at"`
+}
+
+/* TableName specifies the table name for WebhookDelivery model */
+func (WebhookDelivery) TableName() string {
+	return "webhook_deliveries"
+}
+
+/* ToDTO converts WebhookDelivery model to WebhookDeliveryResponse DTO */
+func (d *WebhookDelivery) ToDTO() dto.WebhookDeliveryResponse {
+	return dto.WebhookDeliveryResponse{
+		ID:             d.ID,
+		EndpointID:     d.EndpointID,
+		EventID:        d.EventID,
+		EventType:      d.EventType,
+		Status:         d.Status,
+		Attempts:       d.Attempts,
+		NextAttemptAt:  d.NextAttemptAt,
+		ResponseStatus: d.ResponseStatus,
+		ResponseBody:   d.ResponseBody,
+		LastError:      d.LastError,
+		DurationMs:     d.DurationMs,
+		DeliveredAt:    d.DeliveredAt,
+		CreatedAt:      d.CreatedAt,
+		UpdatedAt:      d.UpdatedAt,
+		Payload:        json.RawMessage(d.Payload),
+	}
+}
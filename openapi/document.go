@@ -0,0 +1,118 @@
+package openapi
+
+// Version is the OpenAPI version of the generated document
+const Version = "3.1.0"
+
+/* Document is an OpenAPI 3.1 document, limited to the parts the generator fills in */
+type Document struct {
+	OpenAPI    string              `json:"openapi"`
+	Info       Info                `json:"info"`
+	Tags       []Tag               `json:"tags,omitempty"`
+	Paths      map[string]PathItem `json:"paths"`
+	Components Components          `json:"components"`
+}
+
+/* Info describes the API */
+type Info struct {
+	Title       string `json:"title"`
+	Description string `json:"description,omitempty"`
+	Version     string `json:"version"`
+}
+
+/* Tag groups operations in Swagger UI */
+type Tag struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+}
+
+/* PathItem holds the operations of one path, keyed by lower-case HTTP method */
+type PathItem map[string]*Operation
+
+/* Operation documents one route */
+type Operation struct {
+	OperationID string                `json:"operationId"`
+	Summary     string                `json:"summary,omitempty"`
+	Description string                `json:"description,omitempty"`
+	Tags        []string              `json:"tags,omitempty"`
+	Parameters  []*Parameter          `json:"parameters,omitempty"`
+	RequestBody *RequestBody          `json:"requestBody,omitempty"`
+	Responses   map[string]*Response  `json:"responses"`
+	Security    []map[string][]string `json:"security,omitempty"`
+}
+
+/* Parameter is a path, query or header parameter, or a reference to a shared one */
+type Parameter struct {
+	Ref         string  `json:"$ref,omitempty"`
+	Name        string  `json:"name,omitempty"`
+	In          string  `json:"in,omitempty"`
+	Description string  `json:"description,omitempty"`
+	Required    bool    `json:"required,omitempty"`
+	Schema      *Schema `json:"schema,omitempty"`
+}
+
+/* RequestBody lists the accepted request bodies by media type */
+type RequestBody struct {
+	Required bool                 `json:"required,omitempty"`
+	Content  map[string]MediaType `json:"content"`
+}
+
+/* Response is an operation response, or a reference to a shared one */
+type Response struct {
+	Ref         string                `json:"$ref,omitempty"`
+	Description string                `json:"description,omitempty"`
+	Headers     map[string]*Parameter `json:"headers,omitempty"`
+	Content     map[string]MediaType  `json:"content,omitempty"`
+}
+
+/* MediaType is the body schema of one content type */
+type MediaType struct {
+	Schema *Schema `json:"schema"`
+}
+
+/* Components holds the schemas, parameters and responses operations refer to */
+type Components struct {
+	Schemas         map[string]*Schema         `json:"schemas"`
+	Parameters      map[string]*Parameter      `json:"parameters,omitempty"`
+	Responses       map[string]*Response       `json:"responses,omitempty"`
+	SecuritySchemes map[string]*SecurityScheme `json:"securitySchemes,omitempty"`
+}
+
+/* SecurityScheme describes how a route is authorized */
+type SecurityScheme struct {
+	Type        string `json:"type"`
+	Scheme      string `json:"scheme,omitempty"`
+	Description string `json:"description,omitempty"`
+}
+
+/*
+Schema is a JSON Schema (draft 2020-12, the dialect of OpenAPI 3.1).
+
+Type is a string, or a list of types for nullable values; an empty Schema
+accepts any value.
+*/
+type Schema struct {
+	Ref                  string             `json:"$ref,omitempty"`
+	Type                 interface{}        `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Description          string             `json:"description,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	AllOf                []*Schema          `json:"allOf,omitempty"`
+	OneOf                []*Schema          `json:"oneOf,omitempty"`
+	Enum                 []interface{}      `json:"enum,omitempty"`
+	Pattern              string             `json:"pattern,omitempty"`
+	MinLength            *int               `json:"minLength,omitempty"`
+	MaxLength            *int               `json:"maxLength,omitempty"`
+	Minimum              *float64           `json:"minimum,omitempty"`
+	Maximum              *float64           `json:"maximum,omitempty"`
+	MinItems             *int               `json:"minItems,omitempty"`
+	MaxItems             *int               `json:"maxItems,omitempty"`
+	Default              interface{}        `json:"default,omitempty"`
+}
+
+/* ref returns a schema referring to a component schema */
+func ref(name string) *Schema {
+	return &Schema{Ref: "#/components/schemas/" + name}
+}
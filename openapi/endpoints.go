@@ -0,0 +1,383 @@
+package openapi
+
+import (
+	"net/http"
+
+	"baseApi/dto"
+	"baseApi/messaging"
+)
+
+/*
+endpoint documents a route registered in routes.SetupRoutes.
+
+Request bodies are JSON unless Content lists other media types. Success
+responses are APIResponse envelopes with Data as "data", unless Produces names
+the media type of a bare body. Body, Data and Content values are dto structs
+(or any Go value) described by reflection, or a *Schema.
+*/
+type endpoint struct {
+	Method      string
+	Path        string // as registered with Gin: /v1/users/:id
+	ID          string // operationId; the handler method name when empty
+	Tag         string
+	Summary     string
+	Description string
+
+	Query   interface{}            // struct bound with ShouldBindQuery
+	Params  []string               // shared parameters, see sharedParameters
+	Body    interface{}            // JSON request body
+	Content map[string]interface{} // request bodies by media type, instead of Body
+
+	Status    int         // success status; 200 when zero
+	Data      interface{} // "data" of the envelope; nil when the response has none
+	Paginated bool        // the envelope carries "pagination"
+	Produces  string      // media type of a bare success body
+	WebSocket bool        // the success response is the 101 of a WebSocket upgrade
+
+	Errors []int // error statuses besides 500 (and 401 for Admin)
+	Admin  bool  // requires the admin token
+}
+
+// tags lists the operation groups in display order
+var tags = []Tag{
+	{Name: "Health"},
+	{Name: "Users", Description: "User accounts; writes can be made conditional with If-Match"},
+	{Name: "User stream", Description: "Live user changes as Server-Sent Events or WebSocket messages"},
+	{Name: "Events", Description: "JSON Schemas of the published CloudEvents"},
+	{Name: "Dead letters", Description: "Messages the broker gave up on"},
+	{Name: "Webhooks", Description: "Webhook endpoints and their delivery log"},
+	{Name: "GraphQL", Description: "graphql/schema.graphql over the user services"},
+}
+
+/*
+undocumented lists the paths deliberately left out of the document, with the
+reason; Diff reports any other route without an endpoint.
+*/
+var undocumented = map[string]string{
+	"/debug/vars":                    "expvar runtime counters",
+	"/v2/*path":                      "v2 JSON routes, described by grpc/v2/user_service.proto",
+	"/grpc.UserService/:method":      "gRPC-Web and h2c gRPC calls, described by grpc/user_service.proto",
+	"/grpc.v2.UserService/:method":   "gRPC-Web and h2c gRPC calls, described by grpc/v2/user_service.proto",
+	"/grpc.health.v1.Health/:method": "gRPC health checks",
+	"/openapi.json":                  "this document",
+	"/docs/*any":                     "Swagger UI for this document",
+}
+
+// pathParameters describes the path parameters by name
+var pathParameters = map[string]*Parameter{
+	"id":         {Description: "Numeric ID", Schema: &Schema{Type: "integer", Minimum: float(0)}},
+	"deliveryId": {Description: "Delivery ID", Schema: &Schema{Type: "integer", Format: "int64", Minimum: float(0)}},
+	"username":   {Schema: &Schema{Type: "string"}},
+	"queue":      {Description: "Consumer queue whose dead-letter queue is used", Schema: &Schema{Type: "string"}},
+	"event":      {Description: "Event name, e.g. user.created", Schema: &Schema{Type: "string"}},
+	"version":    {Description: "Schema version, e.g. v1", Schema: &Schema{Type: "string"}},
+}
+
+// sharedParameters are the header and query parameters endpoints refer to by name
+var sharedParameters = map[string]*Parameter{
+	"IfMatch": {
+		Name: "If-Match", In: "header",
+		Description: `ETag of the version the change is based on ("3-en"); 412 when the user has changed since. Required when REQUIRE_IF_MATCH is set.`,
+		Schema:      &Schema{Type: "string"},
+	},
+	"IfNoneMatch": {
+		Name: "If-None-Match", In: "header",
+		Description: "ETag of a cached copy; 304 when it is still current",
+		Schema:      &Schema{Type: "string"},
+	},
+	"IdempotencyKey": {
+		Name: "Idempotency-Key", In: "header",
+		Description: "Retries with the same key replay the stored response instead of creating the user again",
+		Schema:      &Schema{Type: "string", MaxLength: integer(255)},
+	},
+	"StreamEvents": {
+		Name: "events", In: "query",
+		Description: "Comma-separated event names or patterns (created, user.deleted, user.*); all events when empty",
+		Schema:      &Schema{Type: "string"},
+	},
+	"StreamUserIDs": {
+		Name: "userId", In: "query",
+		Description: "Comma-separated user IDs; all users when empty",
+		Schema:      &Schema{Type: "string"},
+	},
+	"LastEventID": {
+		Name: "Last-Event-ID", In: "header",
+		Description: "ID of the last event received; the events after it are replayed",
+		Schema:      &Schema{Type: "string", MaxLength: integer(64)},
+	},
+	"LastEventIDQuery": {
+		Name: "lastEventId", In: "query",
+		Description: "ID of the last event received; the events after it are replayed",
+		Schema:      &Schema{Type: "string", MaxLength: integer(64)},
+	},
+	"AccessToken": {
+		Name: "access_token", In: "query",
+		Description: "Admin token, for clients that can't send an Authorization header",
+		Schema:      &Schema{Type: "string"},
+	},
+	"DeadLetterLimit": {
+		Name: "limit", In: "query",
+		Description: "Number of messages; values over 100 are capped",
+		Schema:      &Schema{Type: "integer", Default: 10},
+	},
+}
+
+var (
+	healthSchema = &Schema{
+		Type: "object",
+		Properties: map[string]*Schema{
+			"status":    {Type: "string"},
+			"timestamp": {Type: "string", Format: "date-time"},
+			"uptime":    {Type: "string"},
+			"version":   {Type: "string"},
+			"services":  {Type: "object", AdditionalProperties: &Schema{Type: "string"}},
+		},
+	}
+
+	jsonPatchSchema = &Schema{
+		Type: "array",
+		Items: &Schema{
+			Type: "object",
+			Properties: map[string]*Schema{
+				"op":    {Type: "string", Enum: []interface{}{"add", "remove", "replace", "move", "copy", "test"}},
+				"path":  {Type: "string"},
+				"from":  {Type: "string"},
+				"value": {},
+			},
+			Required: []string{"op", "path"},
+		},
+	}
+
+	graphQLRequestSchema = &Schema{
+		Type: "object",
+		Properties: map[string]*Schema{
+			"query":         {Type: "string"},
+			"operationName": {Type: "string"},
+			"variables":     {Type: "object"},
+		},
+		Required: []string{"query"},
+	}
+
+	graphQLResponseSchema = &Schema{
+		Type:        "object",
+		Description: `Failed fields are listed in "errors" with extensions.code, the APIResponse error code`,
+		Properties: map[string]*Schema{
+			"data":   {Type: []string{"object", "null"}},
+			"errors": {Type: "array", Items: &Schema{Type: "object"}},
+		},
+	}
+)
+
+/* deadLetterCountSchema is the data of replay and purge responses */
+func deadLetterCountSchema(count string) *Schema {
+	return &Schema{
+		Type:       "object",
+		Properties: map[string]*Schema{"queue": {Type: "string"}, count: {Type: "integer"}},
+	}
+}
+
+// endpoints documents every route of routes.SetupRoutes but the undocumented ones
+var endpoints = []endpoint{
+	{
+		Method: http.MethodGet, Path: "/health", ID: "GetHealth", Tag: "Health",
+		Summary: "Health check",
+		Data:    healthSchema,
+	},
+
+	// Users
+	{
+		Method: http.MethodPost, Path: "/v1/users", Tag: "Users",
+		Summary: "Create a user",
+		Params:  []string{"IdempotencyKey"},
+		Body:    dto.CreateUserRequest{},
+		Status:  http.StatusCreated,
+		Data:    dto.UserResponse{},
+		Errors:  []int{http.StatusBadRequest, http.StatusConflict, http.StatusUnprocessableEntity},
+	},
+	{
+		Method: http.MethodGet, Path: "/v1/users", Tag: "Users",
+		Summary:   "List users",
+		Query:     dto.UserSearchRequest{},
+		Params:    []string{"IfNoneMatch"},
+		Data:      []dto.UserResponse{},
+		Paginated: true,
+		Errors:    []int{http.StatusNotModified, http.StatusBadRequest},
+	},
+	{
+		Method: http.MethodGet, Path: "/v1/users/:id", Tag: "Users",
+		Summary: "Get a user",
+		Params:  []string{"IfNoneMatch"},
+		Data:    dto.UserResponse{},
+		Errors:  []int{http.StatusNotModified, http.StatusBadRequest, http.StatusNotFound},
+	},
+	{
+		Method: http.MethodGet, Path: "/v1/users/username/:username", Tag: "Users",
+		Summary: "Get a user by username",
+		Params:  []string{"IfNoneMatch"},
+		Data:    dto.UserResponse{},
+		Errors:  []int{http.StatusNotModified, http.StatusBadRequest, http.StatusNotFound},
+	},
+	{
+		Method: http.MethodPut, Path: "/v1/users/:id", Tag: "Users",
+		Summary:     "Update a user",
+		Description: "Empty fields are left unchanged.",
+		Params:      []string{"IfMatch"},
+		Body:        dto.UpdateUserRequest{},
+		Data:        dto.UserResponse{},
+		Errors: []int{http.StatusBadRequest, http.StatusNotFound, http.StatusConflict,
+			http.StatusPreconditionFailed, http.StatusUnprocessableEntity, http.StatusPreconditionRequired},
+	},
+	{
+		Method: http.MethodPatch, Path: "/v1/users/:id", Tag: "Users",
+		Summary:     "Patch a user",
+		Description: "JSON Merge Patch (RFC 7386) or JSON Patch (RFC 6902) of the patchable user document; the patched document must be valid.",
+		Params:      []string{"IfMatch"},
+		Content: map[string]interface{}{
+			dto.ContentTypeMergePatch: &Schema{Type: "object", Description: "Merge patch of PatchUserDocument"},
+			dto.ContentTypeJSONPatch:  jsonPatchSchema,
+		},
+		Data: dto.UserResponse{},
+		Errors: []int{http.StatusBadRequest, http.StatusNotFound, http.StatusConflict, http.StatusPreconditionFailed,
+			http.StatusUnsupportedMediaType, http.StatusUnprocessableEntity, http.StatusPreconditionRequired},
+	},
+	{
+		Method: http.MethodDelete, Path: "/v1/users/:id", Tag: "Users",
+		Summary: "Delete a user",
+		Params:  []string{"IfMatch"},
+		Errors: []int{http.StatusBadRequest, http.StatusNotFound, http.StatusConflict,
+			http.StatusPreconditionFailed, http.StatusPreconditionRequired},
+	},
+
+	// User stream
+	{
+		Method: http.MethodGet, Path: "/v1/users/stream", Tag: "User stream",
+		Summary:     "Stream user changes (SSE)",
+		Description: `Each event is "id: <CloudEvent id>", "event: user.created|user.updated|user.deleted" and the CloudEvent as data; "reset" asks the client to reload.`,
+		Params:      []string{"StreamEvents", "StreamUserIDs", "LastEventID", "AccessToken"},
+		Produces:    "text/event-stream",
+		Data:        &Schema{Type: "string"},
+		Errors:      []int{http.StatusBadRequest},
+		Admin:       true,
+	},
+	{
+		Method: http.MethodGet, Path: "/v1/users/stream/ws", Tag: "User stream",
+		Summary:     "Stream user changes (WebSocket)",
+		Description: `The same events as JSON text frames {"id", "event", "data"}.`,
+		Params:      []string{"StreamEvents", "StreamUserIDs", "LastEventIDQuery", "AccessToken"},
+		WebSocket:   true,
+		Errors:      []int{http.StatusBadRequest},
+		Admin:       true,
+	},
+
+	// Events
+	{
+		Method: http.MethodGet, Path: "/v1/events/schemas", Tag: "Events",
+		Summary: "List event schemas",
+		Data:    []messaging.EventSchema{},
+	},
+	{
+		Method: http.MethodGet, Path: "/v1/events/schemas/:event/:version", Tag: "Events",
+		Summary:  "Get an event data schema",
+		Produces: dto.ContentTypeSchema,
+		Data:     &Schema{Type: "object", Description: "JSON Schema (draft 2020-12) of the CloudEvent data"},
+		Errors:   []int{http.StatusNotFound},
+	},
+
+	// Dead letters
+	{
+		Method: http.MethodGet, Path: "/v1/admin/dead-letters/:queue", Tag: "Dead letters",
+		Summary: "Inspect a dead-letter queue",
+		Params:  []string{"DeadLetterLimit"},
+		Data:    messaging.DeadLetterQueueInfo{},
+		Errors:  []int{http.StatusNotFound, http.StatusServiceUnavailable},
+		Admin:   true,
+	},
+	{
+		Method: http.MethodPost, Path: "/v1/admin/dead-letters/:queue/replay", Tag: "Dead letters",
+		Summary: "Replay dead letters to their original routing keys",
+		Params:  []string{"DeadLetterLimit"},
+		Data:    deadLetterCountSchema("replayed"),
+		Errors:  []int{http.StatusNotFound, http.StatusServiceUnavailable},
+		Admin:   true,
+	},
+	{
+		Method: http.MethodDelete, Path: "/v1/admin/dead-letters/:queue", Tag: "Dead letters",
+		Summary: "Purge a dead-letter queue",
+		Data:    deadLetterCountSchema("purged"),
+		Errors:  []int{http.StatusNotFound, http.StatusServiceUnavailable},
+		Admin:   true,
+	},
+
+	// Webhooks
+	{
+		Method: http.MethodPost, Path: "/v1/webhooks", Tag: "Webhooks",
+		Summary:     "Register a webhook endpoint",
+		Description: "The signing secret is generated when empty and only returned here.",
+		Body:        dto.CreateWebhookRequest{},
+		Status:      http.StatusCreated,
+		Data:        dto.WebhookResponse{},
+		Errors:      []int{http.StatusBadRequest},
+		Admin:       true,
+	},
+	{
+		Method: http.MethodGet, Path: "/v1/webhooks", Tag: "Webhooks",
+		Summary: "List webhook endpoints",
+		Data:    []dto.WebhookResponse{},
+		Admin:   true,
+	},
+	{
+		Method: http.MethodGet, Path: "/v1/webhooks/:id", Tag: "Webhooks",
+		Summary: "Get a webhook endpoint",
+		Data:    dto.WebhookResponse{},
+		Errors:  []int{http.StatusBadRequest, http.StatusNotFound},
+		Admin:   true,
+	},
+	{
+		Method: http.MethodPatch, Path: "/v1/webhooks/:id", Tag: "Webhooks",
+		Summary: "Update a webhook endpoint",
+		Body:    dto.UpdateWebhookRequest{},
+		Data:    dto.WebhookResponse{},
+		Errors:  []int{http.StatusBadRequest, http.StatusNotFound},
+		Admin:   true,
+	},
+	{
+		Method: http.MethodDelete, Path: "/v1/webhooks/:id", Tag: "Webhooks",
+		Summary: "Delete a webhook endpoint",
+		Errors:  []int{http.StatusBadRequest, http.StatusNotFound},
+		Admin:   true,
+	},
+	{
+		Method: http.MethodGet, Path: "/v1/webhooks/:id/deliveries", Tag: "Webhooks",
+		Summary:   "List a webhook's deliveries",
+		Query:     dto.WebhookDeliverySearchRequest{},
+		Data:      []dto.WebhookDeliveryResponse{},
+		Paginated: true,
+		Errors:    []int{http.StatusBadRequest, http.StatusNotFound},
+		Admin:     true,
+	},
+	{
+		Method: http.MethodGet, Path: "/v1/webhooks/:id/deliveries/:deliveryId", Tag: "Webhooks",
+		Summary: "Get a delivery with its payload",
+		Data:    dto.WebhookDeliveryResponse{},
+		Errors:  []int{http.StatusBadRequest, http.StatusNotFound},
+		Admin:   true,
+	},
+
+	// GraphQL
+	{
+		Method: http.MethodPost, Path: "/graphql", Tag: "GraphQL",
+		Summary:  "Run a GraphQL query or mutation",
+		Body:     graphQLRequestSchema,
+		Produces: dto.ContentTypeJSON,
+		Data:     graphQLResponseSchema,
+		Errors:   []int{http.StatusBadRequest},
+	},
+	{
+		Method: http.MethodGet, Path: "/graphql/ws", Tag: "GraphQL",
+		Summary:     "GraphQL subscriptions",
+		Description: "graphql-transport-ws protocol of the graphql-ws client library.",
+		Params:      []string{"AccessToken"},
+		WebSocket:   true,
+		Admin:       true,
+	},
+}
@@ -0,0 +1,261 @@
+package openapi
+
+import (
+	_ "embed"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"baseApi/dto"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SwaggerUI is the Swagger UI page for the document at ../openapi.json
+//
+//go:embed swagger-ui.html
+var SwaggerUI []byte
+
+const description = `Responses use the APIResponse envelope: "data" on success, "error" with a code, ` +
+	"localized message, validations and request ID otherwise. Send `Accept: application/problem+json` " +
+	"for RFC 7807 errors instead.\n\n" +
+	"Messages are localized (en, vi) from `?lang=`, the locale cookie or `Accept-Language`; " +
+	"every response carries the `X-Request-ID` of the request."
+
+/*
+Build generates the OpenAPI document of the given routes from their endpoints.
+
+Only registered routes are documented, so the document follows the
+configuration (admin routes need ADMIN_TOKEN, ...). Routes without an endpoint
+are left out; Diff lists them.
+*/
+func Build(routes gin.RoutesInfo, version string) (*Document, error) {
+	b := &builder{g: newGenerator(), parameters: make(map[string]*Parameter), responses: make(map[string]*Response)}
+
+	registered := make(map[string]gin.RouteInfo, len(routes))
+	for _, route := range routes {
+		registered[route.Method+" "+route.Path] = route
+	}
+
+	doc := &Document{
+		OpenAPI: Version,
+		Info:    Info{Title: "baseApi", Description: description, Version: version},
+		Paths:   make(map[string]PathItem),
+	}
+	usedTags := make(map[string]bool)
+	for _, e := range endpoints {
+		route, ok := registered[e.Method+" "+e.Path]
+		if !ok {
+			continue
+		}
+
+		path, params := b.pathParameters(e.Path)
+		op := b.operation(e, route)
+		op.Parameters = append(params, op.Parameters...)
+		if doc.Paths[path] == nil {
+			doc.Paths[path] = make(PathItem)
+		}
+		doc.Paths[path][strings.ToLower(e.Method)] = op
+		usedTags[e.Tag] = true
+	}
+	for _, tag := range tags {
+		if usedTags[tag.Name] {
+			doc.Tags = append(doc.Tags, tag)
+		}
+	}
+
+	doc.Components = Components{
+		Schemas:    b.g.schemas,
+		Parameters: b.parameters,
+		Responses:  b.responses,
+		SecuritySchemes: map[string]*SecurityScheme{
+			"adminToken": {Type: "http", Scheme: "bearer", Description: "ADMIN_TOKEN"},
+		},
+	}
+	if len(b.g.errs) > 0 {
+		return doc, errors.Join(b.g.errs...)
+	}
+	return doc, nil
+}
+
+/*
+Diff compares routes with the documented endpoints. undocumented lists the
+routes ("GET /v1/users") with neither an endpoint nor an undocumented entry;
+unrouted the endpoints no route serves. Both are empty when the document
+matches a router with every optional route group enabled.
+*/
+func Diff(routes gin.RoutesInfo) (undocumented, unrouted []string) {
+	documented := make(map[string]bool, len(endpoints))
+	for _, e := range endpoints {
+		documented[e.Method+" "+e.Path] = true
+	}
+
+	registered := make(map[string]bool, len(routes))
+	for _, route := range routes {
+		key := route.Method + " " + route.Path
+		registered[key] = true
+		if !documented[key] && !isUndocumented(route.Path) {
+			undocumented = append(undocumented, key)
+		}
+	}
+	for _, e := range endpoints {
+		if key := e.Method + " " + e.Path; !registered[key] {
+			unrouted = append(unrouted, key)
+		}
+	}
+	sort.Strings(undocumented)
+	return undocumented, unrouted
+}
+
+func isUndocumented(path string) bool {
+	_, ok := undocumented[path]
+	return ok
+}
+
+/* builder collects the shared parameters and responses operations refer to */
+type builder struct {
+	g          *generator
+	parameters map[string]*Parameter
+	responses  map[string]*Response
+}
+
+/* operation documents one endpoint served by route */
+func (b *builder) operation(e endpoint, route gin.RouteInfo) *Operation {
+	op := &Operation{
+		OperationID: e.ID,
+		Summary:     e.Summary,
+		Description: e.Description,
+		Tags:        []string{e.Tag},
+		Responses:   make(map[string]*Response),
+	}
+	if op.OperationID == "" {
+		op.OperationID = handlerName(route.Handler)
+	}
+
+	if e.Query != nil {
+		op.Parameters = append(op.Parameters, b.g.parameters(e.Query)...)
+	}
+	for _, name := range e.Params {
+		op.Parameters = append(op.Parameters, b.parameter(name))
+	}
+
+	switch {
+	case e.Content != nil:
+		op.RequestBody = &RequestBody{Required: true, Content: make(map[string]MediaType)}
+		for mediaType, body := range e.Content {
+			op.RequestBody.Content[mediaType] = MediaType{Schema: b.g.schema(body)}
+		}
+	case e.Body != nil:
+		op.RequestBody = &RequestBody{Required: true, Content: map[string]MediaType{
+			dto.ContentTypeJSON: {Schema: b.g.schema(e.Body)},
+		}}
+	}
+
+	status := e.Status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	switch {
+	case e.WebSocket:
+		op.Responses[strconv.Itoa(http.StatusSwitchingProtocols)] = &Response{Description: "Upgraded to WebSocket"}
+	case e.Produces != "":
+		op.Responses[strconv.Itoa(status)] = &Response{
+			Description: http.StatusText(status),
+			Content:     map[string]MediaType{e.Produces: {Schema: b.g.schema(e.Data)}},
+		}
+	default:
+		op.Responses[strconv.Itoa(status)] = &Response{
+			Description: http.StatusText(status),
+			Content:     map[string]MediaType{dto.ContentTypeJSON: {Schema: b.envelope(e)}},
+		}
+	}
+
+	errorStatuses := append([]int{}, e.Errors...)
+	if e.Admin {
+		op.Security = []map[string][]string{{"adminToken": {}}}
+		errorStatuses = append(errorStatuses, http.StatusUnauthorized)
+	}
+	errorStatuses = append(errorStatuses, http.StatusInternalServerError)
+	for _, status := range errorStatuses {
+		op.Responses[strconv.Itoa(status)] = b.errorResponse(status)
+	}
+	return op
+}
+
+/* envelope is the APIResponse schema with the endpoint's data */
+func (b *builder) envelope(e endpoint) *Schema {
+	envelope := b.g.schema(dto.APIResponse{})
+	if e.Data == nil && !e.Paginated {
+		return envelope
+	}
+
+	content := &Schema{Type: "object", Properties: make(map[string]*Schema)}
+	if e.Data != nil {
+		content.Properties["data"] = b.g.schema(e.Data)
+		content.Required = append(content.Required, "data")
+	}
+	if e.Paginated {
+		content.Properties["pagination"] = b.g.schema(dto.PaginationMeta{})
+		content.Required = append(content.Required, "pagination")
+	}
+	return &Schema{AllOf: []*Schema{envelope, content}}
+}
+
+/* errorResponse refers to the shared response of an error status, adding it on first use */
+func (b *builder) errorResponse(status int) *Response {
+	name := strings.ReplaceAll(http.StatusText(status), " ", "")
+	if _, ok := b.responses[name]; !ok {
+		response := &Response{Description: http.StatusText(status)}
+		if status >= 400 {
+			response.Content = map[string]MediaType{
+				dto.ContentTypeJSON:    {Schema: b.g.schema(dto.APIResponse{})},
+				dto.ContentTypeProblem: {Schema: b.g.schema(dto.ProblemDetails{})},
+			}
+		}
+		b.responses[name] = response
+	}
+	return &Response{Ref: "#/components/responses/" + name}
+}
+
+/* parameter refers to a shared parameter, adding it on first use */
+func (b *builder) parameter(name string) *Parameter {
+	param, ok := sharedParameters[name]
+	if !ok {
+		b.g.errs = append(b.g.errs, fmt.Errorf("unknown parameter %s", name))
+		return &Parameter{Name: name}
+	}
+	b.parameters[name] = param
+	return &Parameter{Ref: "#/components/parameters/" + name}
+}
+
+/* pathParameters converts a Gin path to an OpenAPI one (/users/:id -> /users/{id}) and describes its parameters */
+func (b *builder) pathParameters(ginPath string) (string, []*Parameter) {
+	segments := strings.Split(ginPath, "/")
+	var params []*Parameter
+	for i, segment := range segments {
+		if !strings.HasPrefix(segment, ":") && !strings.HasPrefix(segment, "*") {
+			continue
+		}
+		name := segment[1:]
+		segments[i] = "{" + name + "}"
+
+		param := &Parameter{Name: name, In: "path", Required: true, Schema: &Schema{Type: "string"}}
+		if described, ok := pathParameters[name]; ok {
+			param.Description = described.Description
+			param.Schema = described.Schema
+		} else {
+			b.g.errs = append(b.g.errs, fmt.Errorf("%s: path parameter %s is not described", ginPath, name))
+		}
+		params = append(params, param)
+	}
+	return strings.Join(segments, "/"), params
+}
+
+/* handlerName extracts the method name from a Gin handler name: baseApi/handlers.(*UserHandler).GetUser-fm -> GetUser */
+func handlerName(name string) string {
+	name = strings.TrimSuffix(name, "-fm")
+	return name[strings.LastIndex(name, ".")+1:]
+}
@@ -0,0 +1,285 @@
+package openapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"baseApi/validation"
+)
+
+var (
+	timeType       = reflect.TypeOf(time.Time{})
+	rawMessageType = reflect.TypeOf(json.RawMessage{})
+)
+
+/*
+generator turns Go types into schemas. Named structs become component schemas
+referred to by name, so each dto struct is described once.
+
+Binding tags become the matching constraints: required, min/max (length, value
+or item count by kind), oneof, email, http_url and the custom rules of the
+validation package. A rule without an equivalent is an error rather than a
+silently looser schema.
+*/
+type generator struct {
+	schemas map[string]*Schema
+	types   map[string]reflect.Type
+	errs    []error
+}
+
+func newGenerator() *generator {
+	return &generator{schemas: make(map[string]*Schema), types: make(map[string]reflect.Type)}
+}
+
+/* schema returns the schema of v's type, or v itself when it is already a *Schema */
+func (g *generator) schema(v interface{}) *Schema {
+	if s, ok := v.(*Schema); ok {
+		return s
+	}
+	return g.typeSchema(reflect.TypeOf(v))
+}
+
+func (g *generator) typeSchema(t reflect.Type) *Schema {
+	if t == nil {
+		return &Schema{}
+	}
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	switch t {
+	case timeType:
+		return &Schema{Type: "string", Format: "date-time"}
+	case rawMessageType:
+		return &Schema{}
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32:
+		return &Schema{Type: "integer"}
+	case reflect.Int64:
+		return &Schema{Type: "integer", Format: "int64"}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return &Schema{Type: "integer", Minimum: float(0)}
+	case reflect.Uint64:
+		return &Schema{Type: "integer", Format: "int64", Minimum: float(0)}
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: "number"}
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Slice, reflect.Array:
+		return &Schema{Type: "array", Items: g.typeSchema(t.Elem())}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: g.typeSchema(t.Elem())}
+	case reflect.Interface:
+		return &Schema{}
+	case reflect.Struct:
+		return g.component(t)
+	}
+	g.errs = append(g.errs, fmt.Errorf("type %s has no schema equivalent", t))
+	return &Schema{}
+}
+
+/* component registers a named struct's schema and returns a reference to it */
+func (g *generator) component(t reflect.Type) *Schema {
+	name := t.Name()
+	if name == "" {
+		return g.object(t)
+	}
+	if existing, ok := g.types[name]; ok {
+		if existing != t {
+			g.errs = append(g.errs, fmt.Errorf("schema %s is both %s and %s", name, existing, t))
+		}
+		return ref(name)
+	}
+
+	// Registered before its fields so self-references terminate
+	g.types[name] = t
+	g.schemas[name] = g.object(t)
+	return ref(name)
+}
+
+/* object describes the JSON fields of a struct */
+func (g *generator) object(t reflect.Type) *Schema {
+	s := &Schema{Type: "object", Properties: make(map[string]*Schema)}
+	for _, f := range g.fields(t, "json") {
+		s.Properties[f.name] = f.schema
+		if f.required {
+			s.Required = append(s.Required, f.name)
+		}
+	}
+	return s
+}
+
+/* field is a struct field as seen by encoding/json or Gin's form binding */
+type field struct {
+	name     string
+	index    []int
+	schema   *Schema
+	required bool
+}
+
+/* fields lists the fields of a struct named by tag ("json" or "form"), with their binding rules applied */
+func (g *generator) fields(t reflect.Type, tag string) []field {
+	var fields []field
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name, options, _ := strings.Cut(f.Tag.Get(tag), ",")
+		if !f.IsExported() || name == "-" {
+			continue
+		}
+		if f.Anonymous && name == "" && f.Type.Kind() == reflect.Struct {
+			for _, embedded := range g.fields(f.Type, tag) {
+				embedded.index = append([]int{i}, embedded.index...)
+				fields = append(fields, embedded)
+			}
+			continue
+		}
+		if name == "" {
+			if tag == "form" {
+				continue // only tagged fields are documented as query parameters
+			}
+			name = f.Name
+		}
+
+		schema := g.typeSchema(f.Type)
+		required := g.applyRules(schema, f.Type, strings.Split(f.Tag.Get("binding"), ","), t.Name()+"."+f.Name)
+
+		// A pointer without omitempty is sent as null when unset; requests may send null too
+		if f.Type.Kind() == reflect.Ptr && !strings.Contains(options, "omitempty") {
+			if typ, ok := schema.Type.(string); ok {
+				schema.Type = []string{typ, "null"}
+			}
+		}
+		fields = append(fields, field{name: name, index: f.Index, schema: schema, required: required})
+	}
+	return fields
+}
+
+/* applyRules adds the constraints of binding rules to a field's schema and reports whether it is required */
+func (g *generator) applyRules(s *Schema, t reflect.Type, rules []string, fieldName string) bool {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	required := false
+	for i, rule := range rules {
+		name, param, _ := strings.Cut(rule, "=")
+		switch name {
+		case "", "omitempty":
+		case "required":
+			required = true
+		case "dive":
+			if s.Items != nil {
+				g.applyRules(s.Items, t.Elem(), rules[i+1:], fieldName+"[]")
+			}
+			return required
+		case "min", "max":
+			g.bound(s, t.Kind(), name, param, fieldName)
+		case "oneof":
+			for _, value := range strings.Fields(param) {
+				s.Enum = append(s.Enum, g.value(t.Kind(), value, fieldName))
+			}
+		case "email":
+			s.Format = "email"
+		case "http_url":
+			s.Format = "uri"
+		default:
+			pattern, ok := validation.Pattern(name)
+			if !ok {
+				g.errs = append(g.errs, fmt.Errorf("%s: binding rule %q has no schema equivalent", fieldName, name))
+				continue
+			}
+			s.Pattern = pattern
+			if name == "webhook_event" {
+				s.MaxLength = integer(validation.MaxWebhookEventLength)
+			}
+		}
+	}
+	return required
+}
+
+/* bound sets min/max as a length, an item count or a value depending on the field's kind */
+func (g *generator) bound(s *Schema, kind reflect.Kind, rule, param, fieldName string) {
+	n, err := strconv.ParseFloat(param, 64)
+	if err != nil {
+		g.errs = append(g.errs, fmt.Errorf("%s: invalid %s=%s", fieldName, rule, param))
+		return
+	}
+
+	switch kind {
+	case reflect.String:
+		if rule == "min" {
+			s.MinLength = integer(int(n))
+		} else {
+			s.MaxLength = integer(int(n))
+		}
+	case reflect.Slice, reflect.Array, reflect.Map:
+		if rule == "min" {
+			s.MinItems = integer(int(n))
+		} else {
+			s.MaxItems = integer(int(n))
+		}
+	default:
+		if rule == "min" {
+			s.Minimum = float(n)
+		} else {
+			s.Maximum = float(n)
+		}
+	}
+}
+
+/* value converts a oneof option to the field's JSON type */
+func (g *generator) value(kind reflect.Kind, option, fieldName string) interface{} {
+	if kind == reflect.String {
+		return option
+	}
+	n, err := strconv.ParseFloat(option, 64)
+	if err != nil {
+		g.errs = append(g.errs, fmt.Errorf("%s: invalid oneof option %q", fieldName, option))
+		return option
+	}
+	return n
+}
+
+/*
+parameters describes a struct bound with ShouldBindQuery as query parameters.
+Defaults are read back from its SetDefaults method when it has one.
+*/
+func (g *generator) parameters(query interface{}) []*Parameter {
+	t := reflect.TypeOf(query)
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	defaults := reflect.New(t)
+	if setter, ok := defaults.Interface().(interface{ SetDefaults() }); ok {
+		setter.SetDefaults()
+	}
+
+	var params []*Parameter
+	for _, f := range g.fields(t, "form") {
+		// Pointers stay optional in the query string; null is a JSON-only value
+		if types, ok := f.schema.Type.([]string); ok {
+			f.schema.Type = types[0]
+		}
+		if value := defaults.Elem().FieldByIndex(f.index); !value.IsZero() {
+			f.schema.Default = value.Interface()
+		}
+		params = append(params, &Parameter{Name: f.name, In: "query", Required: f.required, Schema: f.schema})
+	}
+	return params
+}
+
+func integer(n int) *int {
+	return &n
+}
+
+func float(n float64) *float64 {
+	return &n
+}
@@ -1,28 +1,39 @@
 package routes
 
 import (
+	"expvar"
 	"time"
 
+	"baseApi/config"
 	"baseApi/dto"
 	"baseApi/handlers"
+	"baseApi/i18n"
+	"baseApi/logger"
 	"baseApi/middleware"
+	"baseApi/validation"
 
 	"github.com/gin-gonic/gin"
 )
 
 /* SetupRoutes configures all API routes */
-func SetupRoutes() *gin.Engine {
+func SetupRoutes(cfg *config.Config) *gin.Engine {
 	// Set Gin to release mode in production
 	// gin.SetMode(gin.ReleaseMode)
 
 	router := gin.New()
 
+	// Custom binding rules (mirroring DB constraints) and JSON field names in errors
+	validation.RegisterRules()
+
 	// Apply global middleware
-	router.Use(middleware.RecoveryWithSentry()) // Custom recovery with Sentry
+	router.Use(middleware.RecoveryWithSentry())  // Custom recovery with Sentry
+	router.Use(middleware.RequestIDMiddleware()) // X-Request-ID for logs, Sentry and error responses
+	router.Use(middleware.LocaleMiddleware())    // en/vi from ?lang=, locale cookie or Accept-Language
 	router.Use(middleware.CORSMiddleware())
-	router.Use(middleware.SentryMiddleware())        // Sentry error tracking and performance
-	router.Use(middleware.LoggingMiddleware())       // Request logging
-	router.Use(middleware.CaptureErrorMiddleware())  // Capture Gin errors
+	router.Use(middleware.SentryMiddleware())       // Sentry error tracking and performance
+	router.Use(middleware.LoggingMiddleware())      // Request logging
+	router.Use(middleware.CaptureErrorMiddleware()) // Capture Gin errors
+	router.Use(middleware.ErrorHandler())           // Render c.Error as APIResponse
 
 	// Health check endpoint with standardized response
 	router.GET("/health", func(c *gin.Context) {
@@ -36,35 +47,165 @@ func SetupRoutes() *gin.Engine {
 				"redis":    "connected",
 			},
 		}
-		
+
 		response := dto.SuccessResponse(
-		dto.StatusOK,
-		"Service is healthy",
-		healthData,
-	)
+			dto.StatusOK,
+			i18n.T(middleware.Locale(c), "success.healthy", nil),
+			healthData,
+		)
 		c.JSON(response.StatusCode, response)
 	})
 
+	// Runtime counters (rabbitmq_publisher: published, confirmed, nacked, returned, ...),
+	// guarded by the admin token since they include cmdline and memstats
+	if cfg.AdminToken != "" {
+		router.GET("/debug/vars", middleware.AdminAuth(cfg.AdminToken), gin.WrapH(expvar.Handler()))
+	}
+
 	// API v1 routes
 	v1 := router.Group("/v1")
 	{
-		setupUserRoutes(v1)
+		setupUserRoutes(v1, cfg)
+		setupEventRoutes(v1)
+		if cfg.AdminToken != "" {
+			setupAdminRoutes(v1, cfg)
+			setupWebhookRoutes(v1, cfg)
+		}
+	}
+
+	// API v2 from grpc/v2/user_service.proto: JSON routes, gRPC-Web and h2c gRPC
+	if cfg.GRPCGatewayEnabled && cfg.GRPCPort != "" {
+		setupGatewayRoutes(router, cfg)
+	}
+
+	// GraphQL over the user services; subscriptions need the stream and the admin token
+	if cfg.GraphQLEnabled {
+		setupGraphQLRoutes(router, cfg)
+	}
+
+	// OpenAPI document of the routes above; registered last so it sees them all
+	if cfg.APIDocsEnabled {
+		setupDocsRoutes(router, cfg)
 	}
 
 	return router
 }
 
 /* setupUserRoutes configures user-related routes */
-func setupUserRoutes(rg *gin.RouterGroup) {
+func setupUserRoutes(rg *gin.RouterGroup, cfg *config.Config) {
 	userHandler := handlers.NewUserHandler()
 
 	users := rg.Group("/users")
+	if cfg.RequireIfMatch {
+		users.Use(middleware.RequireIfMatch()) // 428 for PUT/PATCH/DELETE without If-Match
+	}
+
+	// Cache-Control policies, revalidated with ETag / Last-Modified
+	userCache := middleware.CacheControl(cfg.UserCacheControl)
+	listCache := middleware.CacheControl(cfg.UserListCacheControl)
+
+	// Retried POSTs with the same Idempotency-Key replay the stored response
+	idempotent := middleware.IdempotencyMiddleware(cfg.IdempotencyTTL, cfg.IdempotencyLockTTL)
+	{
+		users.POST("", idempotent, userHandler.CreateUser)                         // POST /api/v1/users
+		users.GET("", listCache, userHandler.GetAllUsers)                          // GET /api/v1/users?page=1&limit=10
+		users.GET("/:id", userCache, userHandler.GetUser)                          // GET /api/v1/users/1
+		users.GET("/username/:username", userCache, userHandler.GetUserByUsername) // GET /api/v1/users/username/john
+		users.PUT("/:id", userHandler.UpdateUser)                                  // PUT /api/v1/users/1
+		users.PATCH("/:id", userHandler.PatchUser)                                 // PATCH /api/v1/users/1
+		users.DELETE("/:id", userHandler.DeleteUser)                               // DELETE /api/v1/users/1
+	}
+
+	// Live user changes for the admin UI; the admin token may also be passed as ?access_token=
+	if cfg.UserStreamEnabled && cfg.AdminToken != "" {
+		streamHandler := handlers.NewUserStreamHandler(cfg)
+		streamAuth := middleware.StreamAuth(cfg.AdminToken)
+
+		users.GET("/stream", streamAuth, streamHandler.StreamUsers)             // GET /api/v1/users/stream?events=created,deleted&userId=1,2
+		users.GET("/stream/ws", streamAuth, streamHandler.StreamUsersWebSocket) // GET /api/v1/users/stream/ws?lastEventId=<id>
+	}
+}
+
+/* setupEventRoutes configures the published event schema catalog */
+func setupEventRoutes(rg *gin.RouterGroup) {
+	eventHandler := handlers.NewEventHandler()
+
+	events := rg.Group("/events")
+	{
+		events.GET("/schemas", eventHandler.GetSchemaCatalog)          // GET /api/v1/events/schemas
+		events.GET("/schemas/:event/:version", eventHandler.GetSchema) // GET /api/v1/events/schemas/user.created/v1
+	}
+}
+
+/* setupAdminRoutes configures operator routes, guarded by the admin token */
+func setupAdminRoutes(rg *gin.RouterGroup, cfg *config.Config) {
+	adminHandler := handlers.NewAdminHandler()
+
+	admin := rg.Group("/admin", middleware.AdminAuth(cfg.AdminToken))
+	{
+		admin.GET("/dead-letters/:queue", adminHandler.GetDeadLetters)            // GET /api/v1/admin/dead-letters/billing?limit=10
+		admin.POST("/dead-letters/:queue/replay", adminHandler.ReplayDeadLetters) // POST /api/v1/admin/dead-letters/billing/replay?limit=10
+		admin.DELETE("/dead-letters/:queue", adminHandler.PurgeDeadLetters)       // DELETE /api/v1/admin/dead-letters/billing
+	}
+}
+
+/* setupWebhookRoutes configures webhook endpoint management and delivery logs, guarded by the admin token */
+func setupWebhookRoutes(rg *gin.RouterGroup, cfg *config.Config) {
+	webhookHandler := handlers.NewWebhookHandler()
+
+	webhooks := rg.Group("/webhooks", middleware.AdminAuth(cfg.AdminToken))
 	{
-		users.POST("", userHandler.CreateUser)                          // POST /api/v1/users
-		users.GET("", userHandler.GetAllUsers)                          // GET /api/v1/users?page=1&limit=10
-		users.GET("/:id", userHandler.GetUser)                          // GET /api/v1/users/1
-		users.GET("/username/:username", userHandler.GetUserByUsername) // GET /api/v1/users/username/john
-		users.PUT("/:id", userHandler.UpdateUser)                       // PUT /api/v1/users/1
-		users.DELETE("/:id", userHandler.DeleteUser)                    // DELETE /api/v1/users/1
-	}
-}
\ No newline at end of file
+		webhooks.POST("", webhookHandler.CreateWebhook)                         // POST /api/v1/webhooks
+		webhooks.GET("", webhookHandler.GetWebhooks)                            // GET /api/v1/webhooks
+		webhooks.GET("/:id", webhookHandler.GetWebhook)                         // GET /api/v1/webhooks/1
+		webhooks.PATCH("/:id", webhookHandler.UpdateWebhook)                    // PATCH /api/v1/webhooks/1
+		webhooks.DELETE("/:id", webhookHandler.DeleteWebhook)                   // DELETE /api/v1/webhooks/1
+		webhooks.GET("/:id/deliveries", webhookHandler.GetDeliveries)           // GET /api/v1/webhooks/1/deliveries?status=failed
+		webhooks.GET("/:id/deliveries/:deliveryId", webhookHandler.GetDelivery) // GET /api/v1/webhooks/1/deliveries/42
+	}
+}
+
+/* setupGatewayRoutes mounts the gRPC gateway; JSON routes are forwarded to the gRPC server on GRPC_PORT */
+func setupGatewayRoutes(router *gin.Engine, cfg *config.Config) {
+	gatewayHandler, err := handlers.NewGatewayHandler(cfg)
+	if err != nil {
+		logger.Error("gRPC gateway disabled:", err)
+		return
+	}
+
+	router.Any("/v2/*path", gatewayHandler.ServeREST) // GET /v2/users/1, POST /v2/users, PATCH /v2/users/1, ...
+
+	// gRPC-Web and gRPC calls are POSTs to /<package>.<Service>/<Method>
+	for _, service := range gatewayHandler.Services() {
+		router.POST("/"+service+"/:method", gatewayHandler.ServeGRPC) // POST /grpc.v2.UserService/GetUser
+	}
+	router.UseH2C = true // gRPC clients speak HTTP/2 without TLS
+}
+
+/* setupGraphQLRoutes mounts the GraphQL endpoint and its graphql-transport-ws subscriptions */
+func setupGraphQLRoutes(router *gin.Engine, cfg *config.Config) {
+	graphQLHandler, err := handlers.NewGraphQLHandler(cfg)
+	if err != nil {
+		logger.Error("GraphQL disabled:", err)
+		return
+	}
+
+	router.POST("/graphql", graphQLHandler.Query) // POST /graphql {"query": "{ user(id: 1) { username } }"}
+
+	// Live user changes like /v1/users/stream; the admin token may also be passed as ?access_token=
+	if cfg.UserStreamEnabled && cfg.AdminToken != "" {
+		router.GET("/graphql/ws", middleware.StreamAuth(cfg.AdminToken), graphQLHandler.Subscribe) // GET /graphql/ws (graphql-transport-ws)
+	}
+}
+
+/* setupDocsRoutes serves the OpenAPI document generated from the registered routes and Swagger UI */
+func setupDocsRoutes(router *gin.Engine, cfg *config.Config) {
+	openAPIHandler, err := handlers.NewOpenAPIHandler(router.Routes(), cfg.AppVersion)
+	if err != nil {
+		logger.Error("API docs disabled:", err)
+		return
+	}
+
+	router.GET("/openapi.json", openAPIHandler.GetSpec) // GET /openapi.json
+	router.GET("/docs/*any", openAPIHandler.SwaggerUI)  // GET /docs/ (Swagger UI)
+}
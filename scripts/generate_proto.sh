@@ -23,13 +23,24 @@ if ! command -v protoc-gen-go-grpc &> /dev/null; then
     go install google.golang.org/grpc/cmd/protoc-gen-go-grpc@latest
 fi
 
-# Generate protobuf code
+# Check if protoc-gen-grpc-gateway is installed
+if ! command -v protoc-gen-grpc-gateway &> /dev/null; then
+    echo "Installing protoc-gen-grpc-gateway..."
+    go install github.com/grpc-ecosystem/grpc-gateway/v2/protoc-gen-grpc-gateway@v2.18.0
+fi
+
+# Generate protobuf code; google/api/*.proto are vendored in grpc/third_party
 echo "Generating Go code from protobuf..."
-protoc --go_out=. --go_opt=paths=source_relative \
+protoc -I . -I grpc/third_party \
+    --go_out=. --go_opt=paths=source_relative \
     --go-grpc_out=. --go-grpc_opt=paths=source_relative \
-    grpc/user_service.proto
+    --grpc-gateway_out=. --grpc-gateway_opt=paths=source_relative \
+    grpc/user_service.proto grpc/v2/user_service.proto
 
 echo "✅ Protobuf code generated successfully!"
 echo "Generated files:"
 echo "  - grpc/user_service.pb.go"
-echo "  - grpc/user_service_grpc.pb.go"
\ No newline at end of file
+echo "  - grpc/user_service_grpc.pb.go"
+echo "  - grpc/v2/user_service.pb.go"
+echo "  - grpc/v2/user_service_grpc.pb.go"
+echo "  - grpc/v2/user_service.pb.gw.go"
\ No newline at end of file
@@ -37,6 +37,9 @@ CREATE TABLE IF NOT EXISTS users (
     -- Active status (GORM: IsActive bool `gorm:"default:true"`)
     is_active BOOLEAN DEFAULT true,
     
+    -- Optimistic locking version, exposed as ETag (GORM: Version uint `gorm:"not null;default:1"`)
+    version INTEGER NOT NULL DEFAULT 1,
+    
     -- Timestamps (GORM tự động thêm)
     created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
     updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
@@ -45,6 +48,92 @@ CREATE TABLE IF NOT EXISTS users (
     deleted_at TIMESTAMP WITH TIME ZONE DEFAULT NULL
 );
 
+/* Thêm cột version cho bảng đã tồn tại (optimistic locking) */
+ALTER TABLE users ADD COLUMN IF NOT EXISTS version INTEGER NOT NULL DEFAULT 1;
+
+-- ===========================================
+-- OUTBOX TABLE
+-- ===========================================
+
+/* Domain events ghi cùng transaction với thay đổi user, relay worker publish sang RabbitMQ */
+CREATE TABLE IF NOT EXISTS outbox (
+    id BIGSERIAL PRIMARY KEY,
+    event_id VARCHAR(36),
+    aggregate_type VARCHAR(50) NOT NULL,
+    aggregate_id INTEGER NOT NULL,
+    event_type VARCHAR(50) NOT NULL,
+    routing_key VARCHAR(100) NOT NULL,
+    payload JSONB NOT NULL,
+    attempts INTEGER NOT NULL DEFAULT 0,
+    last_error TEXT,
+    next_attempt_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    sent_at TIMESTAMP WITH TIME ZONE DEFAULT NULL,
+    failed_at TIMESTAMP WITH TIME ZONE DEFAULT NULL
+);
+
+/* CloudEvents id cho bảng outbox đã tồn tại */
+ALTER TABLE outbox ADD COLUMN IF NOT EXISTS event_id VARCHAR(36);
+
+/* Index cho relay: chỉ các event chưa gửi, theo thứ tự commit */
+CREATE INDEX IF NOT EXISTS idx_outbox_pending ON outbox(id)
+    WHERE sent_at IS NULL AND failed_at IS NULL;
+
+/* Index cho việc dọn dẹp event đã gửi */
+CREATE INDEX IF NOT EXISTS idx_outbox_sent_at ON outbox(sent_at);
+
+-- ===========================================
+-- WEBHOOK TABLES
+-- ===========================================
+
+/* Endpoint của đối tác nhận webhook; events là danh sách routing key/pattern (user.created, user.*) */
+CREATE TABLE IF NOT EXISTS webhook_endpoints (
+    id SERIAL PRIMARY KEY,
+    url VARCHAR(500) NOT NULL,
+    description VARCHAR(255),
+    events JSONB NOT NULL DEFAULT '[]',
+    secret VARCHAR(128) NOT NULL,
+    is_active BOOLEAN NOT NULL DEFAULT true,
+    consecutive_failures INTEGER NOT NULL DEFAULT 0,
+    last_success_at TIMESTAMP WITH TIME ZONE DEFAULT NULL,
+    disabled_at TIMESTAMP WITH TIME ZONE DEFAULT NULL,
+    disabled_reason VARCHAR(255),
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    deleted_at TIMESTAMP WITH TIME ZONE DEFAULT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_webhook_endpoints_deleted_at ON webhook_endpoints(deleted_at);
+
+/* Nhật ký gửi webhook: mỗi event một dòng cho mỗi endpoint, kèm kết quả lần gửi gần nhất */
+CREATE TABLE IF NOT EXISTS webhook_deliveries (
+    id BIGSERIAL PRIMARY KEY,
+    endpoint_id INTEGER NOT NULL,
+    event_id VARCHAR(64) NOT NULL,
+    event_type VARCHAR(100) NOT NULL,
+    payload JSONB NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending',
+    attempts INTEGER NOT NULL DEFAULT 0,
+    next_attempt_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    response_status INTEGER,
+    response_body TEXT,
+    last_error TEXT,
+    duration_ms BIGINT,
+    delivered_at TIMESTAMP WITH TIME ZONE DEFAULT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+/* Mỗi event chỉ gửi một lần cho mỗi endpoint, kể cả khi broker giao lại message */
+CREATE UNIQUE INDEX IF NOT EXISTS idx_webhook_deliveries_endpoint_event
+    ON webhook_deliveries(endpoint_id, event_id);
+
+/* Index cho worker: các delivery đến hạn gửi */
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_due ON webhook_deliveries(status, next_attempt_at);
+
+/* Index cho nhật ký và việc dọn dẹp delivery cũ */
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_created_at ON webhook_deliveries(created_at);
+
 -- ===========================================
 -- INDEXES (GORM tự động tạo một số index)
 -- ===========================================
@@ -0,0 +1,115 @@
+package services
+
+import (
+	"errors"
+	"regexp"
+
+	"baseApi/apperror"
+	"baseApi/dto"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"gorm.io/gorm"
+)
+
+// Postgres SQLSTATE codes for constraint violations
+const (
+	pgUniqueViolation = "23505"
+	pgCheckViolation  = "23514"
+)
+
+var (
+	// ErrUserNotFound is returned when no (non-deleted) user matches the lookup
+	ErrUserNotFound = apperror.NotFound("User")
+	// ErrWebhookNotFound is returned when no (non-deleted) webhook endpoint matches the lookup
+	ErrWebhookNotFound = apperror.NotFound("Webhook")
+	// ErrWebhookDeliveryNotFound is returned when the delivery doesn't exist or belongs to another webhook
+	ErrWebhookDeliveryNotFound = apperror.NotFound("WebhookDelivery")
+	// ErrVersionMismatch is returned when a write's If-Match versions do not match the stored user
+	ErrVersionMismatch = apperror.PreconditionFailed("User has been modified; refetch and retry with the current ETag").
+				WithKey("error.version_mismatch", nil)
+	// ErrConcurrentUpdate is returned when the user changed between read and write
+	ErrConcurrentUpdate = apperror.Conflict("User was modified concurrently; refetch and retry").
+				WithKey("error.concurrent_update", nil)
+	// ErrInvalidCursor is returned when a page cursor is malformed or was issued for another search
+	ErrInvalidCursor = apperror.BadRequest("Invalid page cursor").WithKey("error.invalid_cursor", nil)
+	// ErrUserStreamDisabled is returned by WatchUsers when USER_STREAM_ENABLED is off and no events reach the hub
+	ErrUserStreamDisabled = apperror.PreconditionFailed("User stream is disabled").WithKey("error.user_stream_disabled", nil)
+	// ErrUserStreamLagged is returned by Serve when the subscriber fell too far behind and was dropped
+	ErrUserStreamLagged = apperror.Conflict("Fell too far behind the user stream; resume with the last event id").
+				WithKey("error.user_stream_lagged", nil)
+	// ErrUserStreamClosed is returned by Serve when the hub was closed because the server is shutting down
+	ErrUserStreamClosed = errors.New("user stream closed: server shutting down")
+)
+
+// constraintFields maps named constraints (manual_setup.sql and GORM defaults) to API fields
+var constraintFields = map[string]string{
+	"users_username_key":           "username",
+	"users_email_key":              "email",
+	"idx_users_username":           "username",
+	"idx_users_email":              "email",
+	"uni_users_username":           "username",
+	"uni_users_email":              "email",
+	"chk_users_email_format":       "email",
+	"chk_users_username_format":    "username",
+	"chk_users_password_not_empty": "password",
+}
+
+// columnFields maps users columns to their JSON field names
+var columnFields = map[string]string{
+	"username":   "username",
+	"email":      "email",
+	"password":   "password",
+	"first_name": "firstName",
+	"last_name":  "lastName",
+	"is_active":  "isActive",
+}
+
+// detailKeyPattern extracts the column from details like "Key (email)=(a@b.c) already exists."
+var detailKeyPattern = regexp.MustCompile(`^Key \(([a-zA-Z0-9_]+)\)=`)
+
+/*
+userDBError converts errors of reads and writes on the users table into application
+errors with safe messages: missing rows are ErrUserNotFound, constraint violations
+name the user field. Other tables have their own (webhookDBError); violations there,
+or on constraints that can't be tied to a user field, are internal errors so they never
+reach the client as "User already exists" and their SQL details stay in the logs.
+*/
+func userDBError(err error, message string) error {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ErrUserNotFound
+	}
+
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.TableName == "users" {
+		if field, ok := constraintField(pgErr); ok {
+			switch pgErr.Code {
+			case pgUniqueViolation:
+				return apperror.AlreadyExists("User", field).WithCause(err)
+			case pgCheckViolation:
+				return apperror.Unprocessable([]dto.ValidationError{{
+					Field:   field,
+					Rule:    "check",
+					Message: field + " has an invalid format",
+					Key:     "validation.check_violation",
+					Params:  map[string]string{"field": field},
+				}}).WithCause(err)
+			}
+		}
+	}
+
+	return apperror.Internal(dto.ErrorCodeDatabaseError, message, err)
+}
+
+/* constraintField works out which user field a constraint violation refers to */
+func constraintField(pgErr *pgconn.PgError) (string, bool) {
+	if field, ok := constraintFields[pgErr.ConstraintName]; ok {
+		return field, true
+	}
+
+	column := pgErr.ColumnName
+	if matches := detailKeyPattern.FindStringSubmatch(pgErr.Detail); len(matches) == 2 {
+		column = matches[1]
+	}
+	field, ok := columnFields[column]
+	return field, ok
+}
@@ -0,0 +1,125 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+
+	"baseApi/apperror"
+	"baseApi/dto"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"gorm.io/gorm"
+)
+
+/* TestUserDBError maps users table errors to client errors and keeps everything else internal */
+func TestUserDBError(t *testing.T) {
+	tests := []struct {
+		name      string
+		err       error
+		wantKind  apperror.Kind
+		wantField string
+	}{
+		{
+			name:     "missing row",
+			err:      gorm.ErrRecordNotFound,
+			wantKind: apperror.KindNotFound,
+		},
+		{
+			name: "username taken",
+			err: &pgconn.PgError{Code: pgUniqueViolation, TableName: "users", ConstraintName: "users_username_key",
+				Message: `duplicate key value violates unique constraint "users_username_key"`},
+			wantKind:  apperror.KindAlreadyExists,
+			wantField: "username",
+		},
+		{
+			name:      "email taken (GORM index name)",
+			err:       &pgconn.PgError{Code: pgUniqueViolation, TableName: "users", ConstraintName: "idx_users_email"},
+			wantKind:  apperror.KindAlreadyExists,
+			wantField: "email",
+		},
+		{
+			name: "email taken, constraint found from the detail",
+			err: &pgconn.PgError{Code: pgUniqueViolation, TableName: "users", ConstraintName: "users_email_idx2",
+				Detail: "Key (email)=(john@example.com) already exists."},
+			wantKind:  apperror.KindAlreadyExists,
+			wantField: "email",
+		},
+		{
+			name: "wrapped violation",
+			err: fmt.Errorf("create: %w", &pgconn.PgError{Code: pgUniqueViolation, TableName: "users",
+				ConstraintName: "uni_users_username"}),
+			wantKind:  apperror.KindAlreadyExists,
+			wantField: "username",
+		},
+		{
+			name:      "check violation",
+			err:       &pgconn.PgError{Code: pgCheckViolation, TableName: "users", ConstraintName: "chk_users_email_format"},
+			wantKind:  apperror.KindUnprocessable,
+			wantField: "email",
+		},
+		{
+			name: "unknown constraint",
+			err: &pgconn.PgError{Code: pgUniqueViolation, TableName: "users", ConstraintName: "users_nickname_key",
+				Detail: "Key (nickname)=(johnny) already exists."},
+			wantKind: apperror.KindInternal,
+		},
+		{
+			name:     "unknown check constraint",
+			err:      &pgconn.PgError{Code: pgCheckViolation, TableName: "users", ConstraintName: "chk_users_something"},
+			wantKind: apperror.KindInternal,
+		},
+		{
+			name: "another table",
+			err: &pgconn.PgError{Code: pgUniqueViolation, TableName: "outbox", ConstraintName: "outbox_event_id_key",
+				Detail: "Key (email)=(john@example.com) already exists."},
+			wantKind: apperror.KindInternal,
+		},
+		{
+			name:     "other SQL error",
+			err:      &pgconn.PgError{Code: "42P01", TableName: "users", Message: `relation "users" does not exist`},
+			wantKind: apperror.KindInternal,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			appErr := apperror.From(userDBError(tt.err, "Failed to update user"))
+			if appErr.Kind != tt.wantKind {
+				t.Fatalf("got kind %s (%v), want %s", appErr.Kind, appErr, tt.wantKind)
+			}
+
+			switch tt.wantKind {
+			case apperror.KindAlreadyExists:
+				if appErr.Status != dto.StatusConflict {
+					t.Errorf("got status %d, want 409", appErr.Status)
+				}
+			case apperror.KindUnprocessable:
+				if appErr.Status != dto.StatusUnprocessableEntity {
+					t.Errorf("got status %d, want 422", appErr.Status)
+				}
+			case apperror.KindInternal:
+				if appErr.Status != dto.StatusInternalServerError || appErr.Code != dto.ErrorCodeDatabaseError {
+					t.Errorf("got %d %s, want 500 %s", appErr.Status, appErr.Code, dto.ErrorCodeDatabaseError)
+				}
+				if appErr.Message != "Failed to update user" {
+					t.Errorf("got message %q, want the safe message", appErr.Message)
+				}
+			}
+			if tt.wantField != "" {
+				if len(appErr.Validations) != 1 || appErr.Validations[0].Field != tt.wantField {
+					t.Errorf("got validations %+v, want field %s", appErr.Validations, tt.wantField)
+				}
+			}
+
+			// Nothing from the database reaches the response
+			body, _ := json.Marshal(appErr.ToResponse())
+			for _, leak := range []string{"duplicate key", "users_", "relation", "Key (", "nickname", "outbox"} {
+				if strings.Contains(string(body), leak) {
+					t.Errorf("response %s leaks %q", body, leak)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,53 @@
+package services
+
+import (
+	"encoding/json"
+	"time"
+
+	"baseApi/apperror"
+	"baseApi/dto"
+	"baseApi/messaging"
+	"baseApi/models"
+
+	"gorm.io/gorm"
+)
+
+// User domain event types, published as user.<type>
+const (
+	UserEventCreated = "created"
+	UserEventUpdated = "updated"
+	UserEventDeleted = "deleted"
+)
+
+/* enqueueUserEvent records a user event in the outbox using the caller's transaction */
+func enqueueUserEvent(tx *gorm.DB, eventType string, user *models.User) error {
+	var data interface{} = user.ToDTO()
+	if eventType == UserEventDeleted {
+		data = map[string]uint{"id": user.ID}
+	}
+
+	// Schema violations are programming errors; failing here rolls back the write
+	cloudEvent, err := messaging.NewUserEvent(eventType, user.ID, data)
+	if err != nil {
+		return apperror.Internal(dto.ErrorCodeInternalServer, "Failed to record user event", err)
+	}
+	body, err := json.Marshal(cloudEvent)
+	if err != nil {
+		return apperror.Internal(dto.ErrorCodeInternalServer, "Failed to record user event", err)
+	}
+
+	event := models.OutboxEvent{
+		EventID:       cloudEvent.ID,
+		AggregateType: "user",
+		AggregateID:   user.ID,
+		EventType:     eventType,
+		RoutingKey:    messaging.UserEventRoutingKey(eventType),
+		Payload:       body,
+		NextAttemptAt: time.Now(),
+	}
+	if err := tx.Create(&event).Error; err != nil {
+		// Outbox rows are internal; no constraint violation there is the client's doing
+		return apperror.Internal(dto.ErrorCodeDatabaseError, "Failed to record user event", err)
+	}
+	return nil
+}
@@ -0,0 +1,180 @@
+package services
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"baseApi/database"
+	"baseApi/dto"
+	"baseApi/models"
+
+	"gorm.io/gorm"
+)
+
+/*
+userCursor is the position after the last user of a page.
+
+It records the search it belongs to so a cursor can't be replayed against
+different filters or ordering, which would skip or repeat users.
+*/
+type userCursor struct {
+	SortBy   string          `json:"s"`
+	Desc     bool            `json:"d,omitempty"`
+	Query    string          `json:"q,omitempty"`
+	IsActive *bool           `json:"a,omitempty"`
+	Value    json.RawMessage `json:"v"` // sort column value of the last user
+	ID       uint            `json:"i"` // tie-breaker for equal sort values
+}
+
+/*
+ListUsers returns one page of users in keyset order (sort column, then id).
+
+Unlike GetAllUsers' offsets, cursors stay stable while users are created or
+deleted between pages. Pass NextCursor back with the same search to continue.
+*/
+func (s *UserService) ListUsers(req dto.UserCursorRequest) (*dto.UserCursorResponse, error) {
+	req.SetDefaults()
+
+	var cursor *userCursor
+	var cursorValue interface{}
+	if req.Cursor != "" {
+		var err error
+		if cursor, cursorValue, err = decodeUserCursor(req); err != nil {
+			return nil, err
+		}
+	}
+
+	query := filterUsers(database.DB.Model(&models.User{}), req.Query, req.IsActive)
+
+	var totalCount int64
+	if err := query.Count(&totalCount).Error; err != nil {
+		return nil, userDBError(err, "Failed to retrieve users")
+	}
+
+	column := userSortColumn(req.SortBy)
+	direction, after := "ASC", ">"
+	if req.SortDesc {
+		direction, after = "DESC", "<"
+	}
+	if cursor != nil {
+		query = query.Where(fmt.Sprintf("(%s, id) %s (?, ?)", column, after), cursorValue, cursor.ID)
+	}
+
+	// One extra row tells whether there is a next page
+	var users []models.User
+	if err := query.Order(column + " " + direction + ", id " + direction).Limit(req.Limit + 1).Find(&users).Error; err != nil {
+		return nil, userDBError(err, "Failed to retrieve users")
+	}
+
+	response := dto.UserCursorResponse{TotalItems: totalCount}
+	if len(users) > req.Limit {
+		users = users[:req.Limit]
+		response.NextCursor = encodeUserCursor(req, users[len(users)-1])
+	}
+	response.Users = make([]dto.UserResponse, len(users))
+	for i := range users {
+		response.Users[i] = users[i].ToDTO()
+	}
+	return &response, nil
+}
+
+/* filterUsers applies the free-text search and active filter shared by the user listings */
+func filterUsers(query *gorm.DB, search string, isActive *bool) *gorm.DB {
+	if search != "" {
+		searchTerm := "%" + search + "%"
+		query = query.Where(
+			"username ILIKE ? OR email ILIKE ? OR first_name ILIKE ? OR last_name ILIKE ?",
+			searchTerm, searchTerm, searchTerm, searchTerm,
+		)
+	}
+	if isActive != nil {
+		query = query.Where("is_active = ?", *isActive)
+	}
+	return query
+}
+
+/* userSortColumn maps an API sort field to its column; unknown fields sort by creation time */
+func userSortColumn(sortBy string) string {
+	switch sortBy {
+	case "username", "email":
+		return sortBy
+	case "createdAt":
+		return "created_at"
+	case "updatedAt":
+		return "updated_at"
+	case "firstName":
+		return "first_name"
+	case "lastName":
+		return "last_name"
+	case "isActive":
+		return "is_active"
+	default:
+		return "created_at"
+	}
+}
+
+/* encodeUserCursor returns the opaque cursor positioned after user */
+func encodeUserCursor(req dto.UserCursorRequest, user models.User) string {
+	var value interface{}
+	switch req.SortBy {
+	case "username":
+		value = user.Username
+	case "email":
+		value = user.Email
+	case "firstName":
+		value = user.FirstName
+	case "lastName":
+		value = user.LastName
+	case "isActive":
+		value = user.IsActive
+	case "updatedAt":
+		value = user.UpdatedAt
+	default:
+		value = user.CreatedAt
+	}
+	raw, _ := json.Marshal(value)
+
+	data, _ := json.Marshal(userCursor{
+		SortBy:   req.SortBy,
+		Desc:     req.SortDesc,
+		Query:    req.Query,
+		IsActive: req.IsActive,
+		Value:    raw,
+		ID:       user.ID,
+	})
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+/* decodeUserCursor parses req.Cursor and returns it with its sort value, typed for the query */
+func decodeUserCursor(req dto.UserCursorRequest) (*userCursor, interface{}, error) {
+	data, err := base64.RawURLEncoding.DecodeString(req.Cursor)
+	if err != nil {
+		return nil, nil, ErrInvalidCursor.WithCause(err)
+	}
+	var cursor userCursor
+	if err := json.Unmarshal(data, &cursor); err != nil {
+		return nil, nil, ErrInvalidCursor.WithCause(err)
+	}
+
+	sameFilter := (cursor.IsActive == nil) == (req.IsActive == nil) &&
+		(cursor.IsActive == nil || *cursor.IsActive == *req.IsActive)
+	if cursor.SortBy != req.SortBy || cursor.Desc != req.SortDesc || cursor.Query != req.Query || !sameFilter {
+		return nil, nil, ErrInvalidCursor.WithDetails("cursor was issued for a different search")
+	}
+
+	var value interface{}
+	switch cursor.SortBy {
+	case "isActive":
+		value = new(bool)
+	case "createdAt", "updatedAt":
+		value = new(time.Time)
+	default:
+		value = new(string)
+	}
+	if err := json.Unmarshal(cursor.Value, value); err != nil {
+		return nil, nil, ErrInvalidCursor.WithCause(err)
+	}
+	return &cursor, value, nil
+}
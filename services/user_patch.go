@@ -0,0 +1,113 @@
+package services
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"baseApi/apperror"
+	"baseApi/cache"
+	"baseApi/database"
+	"baseApi/dto"
+	"baseApi/models"
+	"baseApi/validation"
+
+	jsonpatch "github.com/evanphx/json-patch/v5"
+	"github.com/gin-gonic/gin/binding"
+)
+
+var (
+	// ErrUnsupportedPatchType is returned when the patch media type is neither merge patch nor JSON patch
+	ErrUnsupportedPatchType = apperror.UnsupportedMediaType(
+		"Content-Type must be "+patchMediaTypes,
+	).WithKey("error.unsupported_patch_type", map[string]string{"types": patchMediaTypes})
+	// ErrInvalidPatch is returned when the patch document cannot be decoded or applied
+	ErrInvalidPatch = apperror.InvalidFormat("Invalid patch document").WithKey("error.invalid_patch", nil)
+	// ErrPatchTestFailed is returned when a JSON patch "test" operation does not match
+	ErrPatchTestFailed = apperror.Conflict("Patch test operation failed").WithKey("error.patch_test_failed", nil)
+)
+
+const patchMediaTypes = dto.ContentTypeMergePatch + " or " + dto.ContentTypeJSONPatch
+
+/* PatchUser applies a JSON Merge Patch (RFC 7386) or JSON Patch (RFC 6902) to a user */
+func (s *UserService) PatchUser(id uint, contentType string, patch []byte, ifMatch ...uint) (*dto.UserResponse, error) {
+	var user models.User
+	if err := database.DB.First(&user, id).Error; err != nil {
+		return nil, userDBError(err, "Failed to retrieve user")
+	}
+
+	if !versionMatches(user.Version, ifMatch) {
+		return nil, ErrVersionMismatch
+	}
+
+	doc, err := applyUserPatch(user.ToPatchDocument(), contentType, patch)
+	if err != nil {
+		return nil, err
+	}
+
+	// Validate the patched result with the same rules as creation
+	if err := binding.Validator.ValidateStruct(&doc); err != nil {
+		return nil, apperror.Validation(validation.Translate(err))
+	}
+
+	user.ApplyPatchDocument(doc)
+
+	if err := saveUserWithEvent(&user, len(ifMatch) > 0); err != nil {
+		return nil, err
+	}
+
+	// Update cache
+	cacheKey := fmt.Sprintf("user:%d", user.ID)
+	cache.Set(cacheKey, user, 1*time.Hour)
+
+	response := user.ToDTO()
+	return &response, nil
+}
+
+/* applyUserPatch applies the patch to the current document and decodes the result */
+func applyUserPatch(current dto.PatchUserDocument, contentType string, patch []byte) (dto.PatchUserDocument, error) {
+	original, err := json.Marshal(current)
+	if err != nil {
+		return current, apperror.Internal(dto.ErrorCodeInternalServer, "Failed to update user", err)
+	}
+
+	var patched []byte
+	switch contentType {
+	case dto.ContentTypeMergePatch:
+		patched, err = jsonpatch.MergePatch(original, patch)
+		if err != nil {
+			return current, invalidPatch(err)
+		}
+	case dto.ContentTypeJSONPatch:
+		ops, err := jsonpatch.DecodePatch(patch)
+		if err != nil {
+			return current, invalidPatch(err)
+		}
+		patched, err = ops.Apply(original)
+		if err != nil {
+			if errors.Is(err, jsonpatch.ErrTestFailed) {
+				return current, ErrPatchTestFailed.WithDetails(err.Error()).WithCause(err)
+			}
+			return current, invalidPatch(err)
+		}
+	default:
+		return current, ErrUnsupportedPatchType
+	}
+
+	// Reject patches that add fields outside the patchable document (e.g. password)
+	var doc dto.PatchUserDocument
+	decoder := json.NewDecoder(bytes.NewReader(patched))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&doc); err != nil {
+		return current, invalidPatch(err)
+	}
+
+	return doc, nil
+}
+
+/* invalidPatch wraps a patch library error as an invalid patch document error */
+func invalidPatch(err error) error {
+	return ErrInvalidPatch.WithDetails(err.Error()).WithCause(err)
+}
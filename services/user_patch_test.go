@@ -0,0 +1,166 @@
+package services
+
+import (
+	"testing"
+
+	"baseApi/apperror"
+	"baseApi/dto"
+)
+
+/* TestApplyUserPatch applies merge patches and JSON patches to the patchable user document */
+func TestApplyUserPatch(t *testing.T) {
+	active := true
+	current := dto.PatchUserDocument{
+		Username:  "john",
+		Email:     "john@example.com",
+		FirstName: "John",
+		LastName:  "Doe",
+		IsActive:  &active,
+	}
+
+	tests := []struct {
+		name        string
+		contentType string
+		patch       string
+		check       func(t *testing.T, doc dto.PatchUserDocument)
+		wantKey     string
+	}{
+		{
+			name:        "merge patch changes a field",
+			contentType: dto.ContentTypeMergePatch,
+			patch:       `{"firstName":"Johnny"}`,
+			check: func(t *testing.T, doc dto.PatchUserDocument) {
+				if doc.FirstName != "Johnny" || doc.LastName != "Doe" || doc.Username != "john" {
+					t.Errorf("got %+v, want only firstName changed", doc)
+				}
+			},
+		},
+		{
+			name:        "merge patch null removes a field",
+			contentType: dto.ContentTypeMergePatch,
+			patch:       `{"lastName":null}`,
+			check: func(t *testing.T, doc dto.PatchUserDocument) {
+				if doc.LastName != "" || doc.FirstName != "John" {
+					t.Errorf("got %+v, want lastName cleared", doc)
+				}
+			},
+		},
+		{
+			name:        "merge patch null on a required field leaves it for validation",
+			contentType: dto.ContentTypeMergePatch,
+			patch:       `{"isActive":null}`,
+			check: func(t *testing.T, doc dto.PatchUserDocument) {
+				if doc.IsActive != nil {
+					t.Errorf("got isActive %v, want nil", *doc.IsActive)
+				}
+			},
+		},
+		{
+			name:        "merge patch on id is rejected",
+			contentType: dto.ContentTypeMergePatch,
+			patch:       `{"id":99}`,
+			wantKey:     "error.invalid_patch",
+		},
+		{
+			name:        "merge patch on version is rejected",
+			contentType: dto.ContentTypeMergePatch,
+			patch:       `{"version":7}`,
+			wantKey:     "error.invalid_patch",
+		},
+		{
+			name:        "merge patch on password is rejected",
+			contentType: dto.ContentTypeMergePatch,
+			patch:       `{"password":"hunter22"}`,
+			wantKey:     "error.invalid_patch",
+		},
+		{
+			name:        "malformed merge patch",
+			contentType: dto.ContentTypeMergePatch,
+			patch:       `{"firstName":`,
+			wantKey:     "error.invalid_patch",
+		},
+		{
+			name:        "JSON patch replaces a field",
+			contentType: dto.ContentTypeJSONPatch,
+			patch:       `[{"op":"replace","path":"/email","value":"johnny@example.com"}]`,
+			check: func(t *testing.T, doc dto.PatchUserDocument) {
+				if doc.Email != "johnny@example.com" {
+					t.Errorf("got email %q, want johnny@example.com", doc.Email)
+				}
+			},
+		},
+		{
+			name:        "JSON patch removes a field",
+			contentType: dto.ContentTypeJSONPatch,
+			patch:       `[{"op":"remove","path":"/firstName"}]`,
+			check: func(t *testing.T, doc dto.PatchUserDocument) {
+				if doc.FirstName != "" {
+					t.Errorf("got firstName %q, want it removed", doc.FirstName)
+				}
+			},
+		},
+		{
+			name:        "JSON patch test then replace",
+			contentType: dto.ContentTypeJSONPatch,
+			patch:       `[{"op":"test","path":"/username","value":"john"},{"op":"replace","path":"/isActive","value":false}]`,
+			check: func(t *testing.T, doc dto.PatchUserDocument) {
+				if doc.IsActive == nil || *doc.IsActive {
+					t.Errorf("got isActive %v, want false", doc.IsActive)
+				}
+			},
+		},
+		{
+			name:        "JSON patch failing test",
+			contentType: dto.ContentTypeJSONPatch,
+			patch:       `[{"op":"test","path":"/username","value":"jane"},{"op":"replace","path":"/username","value":"janet"}]`,
+			wantKey:     "error.patch_test_failed",
+		},
+		{
+			name:        "JSON patch replacing id is rejected",
+			contentType: dto.ContentTypeJSONPatch,
+			patch:       `[{"op":"replace","path":"/id","value":99}]`,
+			wantKey:     "error.invalid_patch",
+		},
+		{
+			name:        "JSON patch adding version is rejected",
+			contentType: dto.ContentTypeJSONPatch,
+			patch:       `[{"op":"add","path":"/version","value":7}]`,
+			wantKey:     "error.invalid_patch",
+		},
+		{
+			name:        "JSON patch that is not an array",
+			contentType: dto.ContentTypeJSONPatch,
+			patch:       `{"op":"remove","path":"/firstName"}`,
+			wantKey:     "error.invalid_patch",
+		},
+		{
+			name:        "unsupported content type",
+			contentType: dto.ContentTypeJSON,
+			patch:       `{"firstName":"Johnny"}`,
+			wantKey:     "error.unsupported_patch_type",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, err := applyUserPatch(current, tt.contentType, []byte(tt.patch))
+			if tt.wantKey != "" {
+				if err == nil {
+					t.Fatalf("got %+v, want error %s", doc, tt.wantKey)
+				}
+				if key := apperror.From(err).Key; key != tt.wantKey {
+					t.Fatalf("got error %s (%v), want %s", key, err, tt.wantKey)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			tt.check(t, doc)
+		})
+	}
+
+	if current.FirstName != "John" || !*current.IsActive {
+		t.Errorf("patching changed the current document: %+v", current)
+	}
+}
@@ -1,10 +1,11 @@
 package services
 
 import (
-	"errors"
+	"encoding/json"
 	"fmt"
 	"time"
 
+	"baseApi/apperror"
 	"baseApi/cache"
 	"baseApi/database"
 	"baseApi/dto"
@@ -26,14 +27,20 @@ func (s *UserService) CreateUser(req dto.CreateUserRequest) (*dto.UserResponse,
 	// Hash password
 	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
 	if err != nil {
-		return nil, err
+		return nil, apperror.Internal(dto.ErrorCodeInternalServer, "Failed to create user", err)
 	}
 
 	var user models.User
 	user.FromCreateDTO(req)
 	user.Password = string(hashedPassword) // Override with hashed password
 
-	if err := database.DB.Create(&user).Error; err != nil {
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&user).Error; err != nil {
+			return userDBError(err, "Failed to create user")
+		}
+		return enqueueUserEvent(tx, UserEventCreated, &user)
+	})
+	if err != nil {
 		return nil, err
 	}
 
@@ -58,10 +65,7 @@ func (s *UserService) GetUserByID(id uint) (*dto.UserResponse, error) {
 	// If not in cache, get from database
 	var user models.User
 	if err := database.DB.First(&user, id).Error; err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return nil, errors.New("user not found")
-		}
-		return nil, err
+		return nil, userDBError(err, "Failed to retrieve user")
 	}
 
 	// Cache the user
@@ -71,14 +75,57 @@ func (s *UserService) GetUserByID(id uint) (*dto.UserResponse, error) {
 	return &response, nil
 }
 
+/*
+GetUsersByIDs retrieves several users at once: the cached ones in one Redis round
+trip and the rest in one query, which are then cached like GetUserByID does.
+
+Users that don't exist are missing from the result.
+*/
+func (s *UserService) GetUsersByIDs(ids []uint) (map[uint]*dto.UserResponse, error) {
+	result := make(map[uint]*dto.UserResponse, len(ids))
+	if len(ids) == 0 {
+		return result, nil
+	}
+
+	cacheKeys := make([]string, len(ids))
+	for i, id := range ids {
+		cacheKeys[i] = fmt.Sprintf("user:%d", id)
+	}
+	cache.GetMany(cacheKeys, func(i int, value []byte) {
+		var cachedUser models.User
+		if json.Unmarshal(value, &cachedUser) == nil {
+			response := cachedUser.ToDTO()
+			result[ids[i]] = &response
+		}
+	})
+
+	var missing []uint
+	for _, id := range ids {
+		if _, ok := result[id]; !ok {
+			missing = append(missing, id)
+		}
+	}
+	if len(missing) == 0 {
+		return result, nil
+	}
+
+	var users []models.User
+	if err := database.DB.Where("id IN ?", missing).Find(&users).Error; err != nil {
+		return nil, userDBError(err, "Failed to retrieve users")
+	}
+	for _, user := range users {
+		cache.Set(fmt.Sprintf("user:%d", user.ID), user, 1*time.Hour)
+		response := user.ToDTO()
+		result[user.ID] = &response
+	}
+	return result, nil
+}
+
 /* GetUserByUsername retrieves a user by username */
 func (s *UserService) GetUserByUsername(username string) (*dto.UserResponse, error) {
 	var user models.User
 	if err := database.DB.Where("username = ?", username).First(&user).Error; err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return nil, errors.New("user not found")
-		}
-		return nil, err
+		return nil, userDBError(err, "Failed to retrieve user")
 	}
 
 	response := user.ToDTO()
@@ -92,43 +139,16 @@ func (s *UserService) GetAllUsers(req dto.UserSearchRequest) (*dto.UserListRespo
 	var users []models.User
 	var totalCount int64
 
-	query := database.DB.Model(&models.User{})
-
-	// Apply search filter
-	if req.Query != "" {
-		searchTerm := "%" + req.Query + "%"
-		query = query.Where(
-			"username ILIKE ? OR email ILIKE ? OR first_name ILIKE ? OR last_name ILIKE ?",
-			searchTerm, searchTerm, searchTerm, searchTerm,
-		)
-	}
-
-	// Apply active filter
-	if req.IsActive != nil {
-		query = query.Where("is_active = ?", *req.IsActive)
-	}
+	// Apply search and active filters
+	query := filterUsers(database.DB.Model(&models.User{}), req.Query, req.IsActive)
 
 	// Get total count
 	if err := query.Count(&totalCount).Error; err != nil {
-		return nil, err
+		return nil, userDBError(err, "Failed to retrieve users")
 	}
 
 	// Apply sorting with field mapping
-	var orderField string
-	switch req.SortBy {
-	case "createdAt":
-		orderField = "created_at"
-	case "updatedAt":
-		orderField = "updated_at"
-	case "firstName":
-		orderField = "first_name"
-	case "lastName":
-		orderField = "last_name"
-	case "isActive":
-		orderField = "is_active"
-	default:
-		orderField = req.SortBy // username, email use same name
-	}
+	orderField := userSortColumn(req.SortBy)
 	
 	orderClause := orderField
 	if req.SortDesc {
@@ -141,7 +161,7 @@ func (s *UserService) GetAllUsers(req dto.UserSearchRequest) (*dto.UserListRespo
 	// Apply pagination
 	offset := (req.Page - 1) * req.Limit
 	if err := query.Offset(offset).Limit(req.Limit).Find(&users).Error; err != nil {
-		return nil, err
+		return nil, userDBError(err, "Failed to retrieve users")
 	}
 
 	// Create pagination metadata
@@ -152,20 +172,21 @@ func (s *UserService) GetAllUsers(req dto.UserSearchRequest) (*dto.UserListRespo
 	return &response, nil
 }
 
-/* UpdateUser updates a user */
-func (s *UserService) UpdateUser(id uint, req dto.UpdateUserRequest) (*dto.UserResponse, error) {
+/* UpdateUser updates a user, optionally requiring one of the given If-Match versions */
+func (s *UserService) UpdateUser(id uint, req dto.UpdateUserRequest, ifMatch ...uint) (*dto.UserResponse, error) {
 	var user models.User
 	if err := database.DB.First(&user, id).Error; err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return nil, errors.New("user not found")
-		}
-		return nil, err
+		return nil, userDBError(err, "Failed to retrieve user")
+	}
+
+	if !versionMatches(user.Version, ifMatch) {
+		return nil, ErrVersionMismatch
 	}
 
 	// Update fields using DTO
 	user.UpdateFromDTO(req)
 
-	if err := database.DB.Save(&user).Error; err != nil {
+	if err := saveUserWithEvent(&user, len(ifMatch) > 0); err != nil {
 		return nil, err
 	}
 
@@ -177,17 +198,28 @@ func (s *UserService) UpdateUser(id uint, req dto.UpdateUserRequest) (*dto.UserR
 	return &response, nil
 }
 
-/* DeleteUser soft deletes a user */
-func (s *UserService) DeleteUser(id uint) error {
+/* DeleteUser soft deletes a user, optionally requiring one of the given If-Match versions */
+func (s *UserService) DeleteUser(id uint, ifMatch ...uint) error {
 	var user models.User
 	if err := database.DB.First(&user, id).Error; err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return errors.New("user not found")
-		}
-		return err
+		return userDBError(err, "Failed to retrieve user")
+	}
+
+	if !versionMatches(user.Version, ifMatch) {
+		return ErrVersionMismatch
 	}
 
-	if err := database.DB.Delete(&user).Error; err != nil {
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		result := tx.Where("version = ?", user.Version).Delete(&user)
+		if result.Error != nil {
+			return userDBError(result.Error, "Failed to delete user")
+		}
+		if result.RowsAffected == 0 {
+			return conflictError(len(ifMatch) > 0)
+		}
+		return enqueueUserEvent(tx, UserEventDeleted, &user)
+	})
+	if err != nil {
 		return err
 	}
 
@@ -202,7 +234,55 @@ func (s *UserService) DeleteUser(id uint) error {
 func (s *UserService) GetUserCount() (int64, error) {
 	var count int64
 	if err := database.DB.Model(&models.User{}).Count(&count).Error; err != nil {
-		return 0, err
+		return 0, userDBError(err, "Failed to count users")
 	}
 	return count, nil
-}
\ No newline at end of file
+}
+
+/* saveUserWithEvent saves a versioned user and records the update event in one transaction */
+func saveUserWithEvent(user *models.User, conditional bool) error {
+	return database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := saveVersioned(tx, user, conditional); err != nil {
+			return err
+		}
+		return enqueueUserEvent(tx, UserEventUpdated, user)
+	})
+}
+
+/* saveVersioned saves a user only if its stored version is unchanged, bumping the version */
+func saveVersioned(tx *gorm.DB, user *models.User, conditional bool) error {
+	previous := user.Version
+	user.Version = previous + 1
+
+	result := tx.Model(user).Where("version = ?", previous).Select("*").Updates(user)
+	if result.Error != nil {
+		user.Version = previous
+		return userDBError(result.Error, "Failed to update user")
+	}
+	if result.RowsAffected == 0 {
+		user.Version = previous
+		return conflictError(conditional)
+	}
+	return nil
+}
+
+/* versionMatches reports whether the current version satisfies the If-Match versions */
+func versionMatches(current uint, ifMatch []uint) bool {
+	if len(ifMatch) == 0 {
+		return true
+	}
+	for _, v := range ifMatch {
+		if v == current {
+			return true
+		}
+	}
+	return false
+}
+
+/* conflictError picks the error for a lost race, depending on whether the client sent If-Match */
+func conflictError(conditional bool) error {
+	if conditional {
+		return ErrVersionMismatch
+	}
+	return ErrConcurrentUpdate
+}
@@ -0,0 +1,143 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"baseApi/apperror"
+	"baseApi/database"
+	"baseApi/dto"
+	"baseApi/logger"
+	"baseApi/models"
+	"baseApi/streaming"
+
+	"gorm.io/gorm"
+)
+
+// Reasons sent with reset events
+const (
+	resetResumeExpired = "resume_point_expired"
+	resetReplayFailed  = "replay_failed"
+)
+
+type UserStreamService struct {
+	replayLimit int
+}
+
+/* NewUserStreamService creates a stream service that replays at most replayLimit missed events */
+func NewUserStreamService(replayLimit int) *UserStreamService {
+	return &UserStreamService{replayLimit: replayLimit}
+}
+
+/*
+Replay returns the user events recorded after lastEventID, oldest first.
+
+Events come from the outbox, so they include changes still waiting for the relay;
+the stream handler skips their live copies when they arrive. complete is false when
+lastEventID is no longer in the outbox (purged after OUTBOX_RETENTION) or more than
+replayLimit events were missed: the client has to reload instead.
+*/
+func (s *UserStreamService) Replay(lastEventID string, filter streaming.Filter) (events []streaming.Event, complete bool, err error) {
+	var anchor models.OutboxEvent
+	err = database.DB.Select("id").
+		Where("event_id = ? AND aggregate_type = ?", lastEventID, "user").
+		First(&anchor).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, apperror.Internal(dto.ErrorCodeDatabaseError, "Failed to replay user events", err)
+	}
+
+	var rows []models.OutboxEvent
+	err = database.DB.
+		Where("id > ? AND aggregate_type = ? AND failed_at IS NULL AND event_id <> ''", anchor.ID, "user").
+		Order("id").
+		Limit(s.replayLimit + 1).
+		Find(&rows).Error
+	if err != nil {
+		return nil, false, apperror.Internal(dto.ErrorCodeDatabaseError, "Failed to replay user events", err)
+	}
+	if len(rows) > s.replayLimit {
+		return nil, false, nil
+	}
+
+	for _, row := range rows {
+		event, err := streaming.NewEvent(row.RoutingKey, row.Payload)
+		if err != nil || !filter.Matches(event) {
+			continue
+		}
+		events = append(events, event)
+	}
+	return events, true, nil
+}
+
+/*
+Serve subscribes to hub, replays what the client missed, then forwards live events
+until ctx ends (nil), send or ping fails (their error), the client falls behind
+(ErrUserStreamLagged) or the hub closes on shutdown (ErrUserStreamClosed).
+
+The subscription starts before the replay query so nothing committed in between is
+lost; live copies of replayed events are skipped. ping runs every heartbeat; a zero
+heartbeat sends none (gRPC streams rely on HTTP/2 keepalives).
+*/
+func (s *UserStreamService) Serve(ctx context.Context, hub *streaming.Hub, filter streaming.Filter, lastEventID string,
+	heartbeat time.Duration, send func(streaming.Event) error, ping func() error) error {
+	subscriber := hub.Subscribe(filter)
+	defer hub.Unsubscribe(subscriber)
+
+	replayed := make(map[string]bool)
+	if lastEventID != "" {
+		events, complete, err := s.Replay(lastEventID, filter)
+		switch {
+		case err != nil:
+			logger.Error("User stream replay failed:", err)
+			if err := send(streaming.ResetEvent(resetReplayFailed)); err != nil {
+				return err
+			}
+		case !complete:
+			if err := send(streaming.ResetEvent(resetResumeExpired)); err != nil {
+				return err
+			}
+		}
+		for _, event := range events {
+			if err := send(event); err != nil {
+				return err
+			}
+			replayed[event.ID] = true
+		}
+	}
+
+	var heartbeats <-chan time.Time
+	if heartbeat > 0 {
+		ticker := time.NewTicker(heartbeat)
+		defer ticker.Stop()
+		heartbeats = ticker.C
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-heartbeats:
+			if err := ping(); err != nil {
+				return err
+			}
+		case event, ok := <-subscriber.Events():
+			if !ok {
+				if subscriber.Lagged() {
+					// The client reconnects and catches up through its last event id
+					logger.Warn("User stream subscriber fell behind, disconnecting")
+					return ErrUserStreamLagged
+				}
+				return ErrUserStreamClosed
+			}
+			if replayed[event.ID] {
+				continue
+			}
+			if err := send(event); err != nil {
+				return err
+			}
+		}
+	}
+}
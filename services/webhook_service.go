@@ -0,0 +1,199 @@
+package services
+
+import (
+	"errors"
+	"time"
+
+	"baseApi/apperror"
+	"baseApi/database"
+	"baseApi/dto"
+	"baseApi/models"
+	"baseApi/webhooks"
+
+	"gorm.io/gorm"
+)
+
+// disabledByOperator is the disabled reason recorded when an endpoint is switched off through the API
+const disabledByOperator = "disabled via API"
+
+type WebhookService struct{}
+
+/* NewWebhookService creates a new webhook service instance */
+func NewWebhookService() *WebhookService {
+	return &WebhookService{}
+}
+
+/* CreateWebhook registers an endpoint; the signing secret is only returned here */
+func (s *WebhookService) CreateWebhook(req dto.CreateWebhookRequest) (*dto.WebhookResponse, error) {
+	secret := req.Secret
+	if secret == "" {
+		generated, err := webhooks.GenerateSecret()
+		if err != nil {
+			return nil, apperror.Internal(dto.ErrorCodeInternalServer, "Failed to create webhook", err)
+		}
+		secret = generated
+	}
+
+	endpoint := models.WebhookEndpoint{
+		URL:         req.URL,
+		Description: req.Description,
+		Events:      models.StringList(req.Events),
+		Secret:      secret,
+		IsActive:    true,
+	}
+	if err := database.DB.Create(&endpoint).Error; err != nil {
+		return nil, webhookDBError(err, ErrWebhookNotFound, "Failed to create webhook")
+	}
+
+	response := endpoint.ToDTO()
+	response.Secret = endpoint.Secret
+	return &response, nil
+}
+
+/* GetWebhook retrieves a webhook endpoint by ID */
+func (s *WebhookService) GetWebhook(id uint) (*dto.WebhookResponse, error) {
+	endpoint, err := findWebhook(id)
+	if err != nil {
+		return nil, err
+	}
+
+	response := endpoint.ToDTO()
+	return &response, nil
+}
+
+/* ListWebhooks retrieves all webhook endpoints, oldest first */
+func (s *WebhookService) ListWebhooks() ([]dto.WebhookResponse, error) {
+	var endpoints []models.WebhookEndpoint
+	if err := database.DB.Order("id").Find(&endpoints).Error; err != nil {
+		return nil, webhookDBError(err, ErrWebhookNotFound, "Failed to retrieve webhooks")
+	}
+
+	responses := make([]dto.WebhookResponse, len(endpoints))
+	for i := range endpoints {
+		responses[i] = endpoints[i].ToDTO()
+	}
+	return responses, nil
+}
+
+/* UpdateWebhook updates an endpoint; re-activating it clears the failure streak of an auto-disabled one */
+func (s *WebhookService) UpdateWebhook(id uint, req dto.UpdateWebhookRequest) (*dto.WebhookResponse, error) {
+	endpoint, err := findWebhook(id)
+	if err != nil {
+		return nil, err
+	}
+
+	updates := map[string]interface{}{}
+	if req.URL != "" {
+		updates["url"] = req.URL
+	}
+	if req.Description != nil {
+		updates["description"] = *req.Description
+	}
+	if len(req.Events) > 0 {
+		updates["events"] = models.StringList(req.Events)
+	}
+	if req.IsActive != nil && *req.IsActive != endpoint.IsActive {
+		updates["is_active"] = *req.IsActive
+		if *req.IsActive {
+			updates["consecutive_failures"] = 0
+			updates["disabled_at"] = nil
+			updates["disabled_reason"] = ""
+		} else {
+			updates["disabled_at"] = time.Now()
+			updates["disabled_reason"] = disabledByOperator
+		}
+	}
+
+	if len(updates) > 0 {
+		if err := database.DB.Model(endpoint).Updates(updates).Error; err != nil {
+			return nil, webhookDBError(err, ErrWebhookNotFound, "Failed to update webhook")
+		}
+		if endpoint, err = findWebhook(id); err != nil {
+			return nil, err
+		}
+	}
+
+	response := endpoint.ToDTO()
+	return &response, nil
+}
+
+/* DeleteWebhook soft deletes an endpoint; pending deliveries to it are failed by the delivery worker */
+func (s *WebhookService) DeleteWebhook(id uint) error {
+	result := database.DB.Delete(&models.WebhookEndpoint{}, id)
+	if result.Error != nil {
+		return webhookDBError(result.Error, ErrWebhookNotFound, "Failed to delete webhook")
+	}
+	if result.RowsAffected == 0 {
+		return ErrWebhookNotFound
+	}
+	return nil
+}
+
+/* ListDeliveries retrieves a page of an endpoint's delivery log, newest first, without payloads */
+func (s *WebhookService) ListDeliveries(id uint, req dto.WebhookDeliverySearchRequest) (*dto.WebhookDeliveryListResponse, error) {
+	req.SetDefaults()
+
+	if _, err := findWebhook(id); err != nil {
+		return nil, err
+	}
+
+	query := database.DB.Model(&models.WebhookDelivery{}).Where("endpoint_id = ?", id)
+	if req.Status != "" {
+		query = query.Where("status = ?", req.Status)
+	}
+	if req.EventType != "" {
+		query = query.Where("event_type = ?", req.EventType)
+	}
+
+	var totalCount int64
+	if err := query.Count(&totalCount).Error; err != nil {
+		return nil, webhookDBError(err, ErrWebhookDeliveryNotFound, "Failed to retrieve webhook deliveries")
+	}
+
+	var deliveries []models.WebhookDelivery
+	offset := (req.Page - 1) * req.Limit
+	if err := query.Omit("payload").Order("id DESC").Offset(offset).Limit(req.Limit).Find(&deliveries).Error; err != nil {
+		return nil, webhookDBError(err, ErrWebhookDeliveryNotFound, "Failed to retrieve webhook deliveries")
+	}
+
+	response := dto.WebhookDeliveryListResponse{
+		Deliveries: make([]dto.WebhookDeliveryResponse, len(deliveries)),
+		Pagination: *dto.NewPaginationMeta(req.Page, req.Limit, totalCount),
+	}
+	for i := range deliveries {
+		response.Deliveries[i] = deliveries[i].ToDTO()
+	}
+	return &response, nil
+}
+
+/* GetDelivery retrieves one delivery of an endpoint, including the payload that was sent */
+func (s *WebhookService) GetDelivery(id uint, deliveryID uint64) (*dto.WebhookDeliveryResponse, error) {
+	if _, err := findWebhook(id); err != nil {
+		return nil, err
+	}
+
+	var delivery models.WebhookDelivery
+	if err := database.DB.Where("endpoint_id = ?", id).First(&delivery, deliveryID).Error; err != nil {
+		return nil, webhookDBError(err, ErrWebhookDeliveryNotFound, "Failed to retrieve webhook delivery")
+	}
+
+	response := delivery.ToDTO()
+	return &response, nil
+}
+
+/* findWebhook loads a non-deleted endpoint */
+func findWebhook(id uint) (*models.WebhookEndpoint, error) {
+	var endpoint models.WebhookEndpoint
+	if err := database.DB.First(&endpoint, id).Error; err != nil {
+		return nil, webhookDBError(err, ErrWebhookNotFound, "Failed to retrieve webhook")
+	}
+	return &endpoint, nil
+}
+
+/* webhookDBError maps missing rows to notFound and anything else to a database error */
+func webhookDBError(err error, notFound error, message string) error {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return notFound
+	}
+	return apperror.Internal(dto.ErrorCodeDatabaseError, message, err)
+}
@@ -0,0 +1,134 @@
+package streaming
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"baseApi/messaging"
+)
+
+// EventReset tells a client to refetch its data: the events it missed can't be replayed
+const EventReset = "reset"
+
+// maxFilterValues bounds the number of event patterns or user IDs in one filter
+const maxFilterValues = 50
+
+/* Event is one user change pushed to stream subscribers */
+type Event struct {
+	ID     string          `json:"id"`    // CloudEvent id, used as the SSE id and for Last-Event-ID resume
+	Event  string          `json:"event"` // routing key (user.created, user.updated, user.deleted) or "reset"
+	UserID uint            `json:"-"`
+	Data   json.RawMessage `json:"data"` // the CloudEvent, as published to the broker
+}
+
+/* NewEvent builds a stream event from a published CloudEvent */
+func NewEvent(routingKey string, body []byte) (Event, error) {
+	var cloudEvent messaging.CloudEvent
+	if err := json.Unmarshal(body, &cloudEvent); err != nil {
+		return Event{}, fmt.Errorf("user stream: body is not a CloudEvent: %w", err)
+	}
+	if cloudEvent.ID == "" {
+		return Event{}, errors.New("user stream: CloudEvent has no id")
+	}
+
+	// Subjects are "users/<id>"
+	var userID uint
+	if id, err := strconv.ParseUint(strings.TrimPrefix(cloudEvent.Subject, "users/"), 10, 32); err == nil {
+		userID = uint(id)
+	}
+
+	return Event{ID: cloudEvent.ID, Event: routingKey, UserID: userID, Data: body}, nil
+}
+
+/* ResetEvent tells the client to reload, with the reason in data */
+func ResetEvent(reason string) Event {
+	data, _ := json.Marshal(map[string]string{"reason": reason})
+	return Event{Event: EventReset, Data: data}
+}
+
+/* FilterError reports an invalid ?events= or ?userId= value */
+type FilterError struct {
+	Field string // query parameter
+	Value string
+	Msg   string
+}
+
+/* Error implements error */
+func (e *FilterError) Error() string {
+	return e.Field + ": " + e.Msg
+}
+
+/* Filter selects the events a subscriber receives; empty fields match everything */
+type Filter struct {
+	Events  []string      // routing-key patterns (user.created, user.*)
+	UserIDs map[uint]bool // only changes to these users
+}
+
+/*
+ParseFilter parses the comma-separated ?events= and ?userId= query values.
+
+Event names without a "user." prefix get one, so events=created,deleted works too.
+*/
+func ParseFilter(events, userIDs string) (Filter, error) {
+	var filter Filter
+
+	for _, name := range splitList(events) {
+		if !strings.HasPrefix(name, "user.") && name != "#" {
+			name = "user." + name
+		}
+		filter.Events = append(filter.Events, name)
+	}
+	if len(filter.Events) > maxFilterValues {
+		return Filter{}, &FilterError{Field: "events", Value: events, Msg: fmt.Sprintf("at most %d event filters are allowed", maxFilterValues)}
+	}
+
+	ids := splitList(userIDs)
+	if len(ids) > maxFilterValues {
+		return Filter{}, &FilterError{Field: "userId", Value: userIDs, Msg: fmt.Sprintf("at most %d user IDs are allowed", maxFilterValues)}
+	}
+	for _, value := range ids {
+		id, err := strconv.ParseUint(value, 10, 32)
+		if err != nil || id == 0 {
+			return Filter{}, &FilterError{Field: "userId", Value: value, Msg: "not a user ID"}
+		}
+		if filter.UserIDs == nil {
+			filter.UserIDs = make(map[uint]bool)
+		}
+		filter.UserIDs[uint(id)] = true
+	}
+
+	return filter, nil
+}
+
+/* Matches reports whether the event passes the filter; reset events always do */
+func (f Filter) Matches(e Event) bool {
+	if e.Event == EventReset {
+		return true
+	}
+	if len(f.UserIDs) > 0 && !f.UserIDs[e.UserID] {
+		return false
+	}
+	if len(f.Events) == 0 {
+		return true
+	}
+	for _, pattern := range f.Events {
+		if messaging.TopicMatch(pattern, e.Event) {
+			return true
+		}
+	}
+	return false
+}
+
+/* splitList splits a comma-separated value, dropping blanks */
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
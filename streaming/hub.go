@@ -0,0 +1,163 @@
+package streaming
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sync"
+
+	"baseApi/messaging"
+)
+
+// defaultBuffer is how many events a subscriber may fall behind before it is disconnected
+const defaultBuffer = 64
+
+/* Subscriber receives the events matching its filter until it is unsubscribed */
+type Subscriber struct {
+	events chan Event
+	filter Filter
+	lagged bool
+}
+
+/* Events is closed when the subscriber is unsubscribed or fell too far behind (see Lagged) */
+func (s *Subscriber) Events() <-chan Event {
+	return s.events
+}
+
+/* Lagged reports whether the subscriber was dropped for not keeping up; valid once Events is closed */
+func (s *Subscriber) Lagged() bool {
+	return s.lagged
+}
+
+/*
+Hub fans user events out to the stream subscribers connected to this process.
+
+Every API worker consumes the user events from the broker on its own auto-delete
+queue (see Consumer), so a subscriber sees every change whichever worker made it.
+*/
+type Hub struct {
+	mu          sync.Mutex
+	subscribers map[*Subscriber]struct{}
+	buffer      int
+	closed      bool
+}
+
+/* NewHub creates a hub whose subscribers may buffer up to buffer events */
+func NewHub(buffer int) *Hub {
+	if buffer <= 0 {
+		buffer = defaultBuffer
+	}
+	return &Hub{subscribers: make(map[*Subscriber]struct{}), buffer: buffer}
+}
+
+/* Subscribe registers a subscriber for events matching filter */
+func (h *Hub) Subscribe(filter Filter) *Subscriber {
+	s := &Subscriber{events: make(chan Event, h.buffer), filter: filter}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.closed {
+		close(s.events)
+		return s
+	}
+	h.subscribers[s] = struct{}{}
+	return s
+}
+
+/* Unsubscribe removes a subscriber and closes its channel; safe to call more than once */
+func (h *Hub) Unsubscribe(s *Subscriber) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.remove(s)
+}
+
+/*
+Close disconnects every subscriber and ends new subscriptions right away, so
+long-lived streams don't hold up a graceful shutdown; clients reconnect to
+another worker with their last event id.
+*/
+func (h *Hub) Close() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.closed = true
+	for s := range h.subscribers {
+		h.remove(s)
+	}
+}
+
+/* Len returns the number of connected subscribers */
+func (h *Hub) Len() int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return len(h.subscribers)
+}
+
+/*
+Broadcast delivers an event to every matching subscriber without blocking.
+
+A subscriber whose buffer is full is dropped rather than slowing everyone else
+down; its client reconnects with Last-Event-ID and catches up from the outbox.
+*/
+func (h *Hub) Broadcast(e Event) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for s := range h.subscribers {
+		if !s.filter.Matches(e) {
+			continue
+		}
+		select {
+		case s.events <- e:
+		default:
+			s.lagged = true
+			h.remove(s)
+		}
+	}
+}
+
+/* remove unregisters s; callers hold h.mu */
+func (h *Hub) remove(s *Subscriber) {
+	if _, ok := h.subscribers[s]; ok {
+		delete(h.subscribers, s)
+		close(s.events)
+	}
+}
+
+/* Handle is the broker handler that broadcasts user events */
+func (h *Hub) Handle(ctx context.Context, msg *messaging.Message) error {
+	event, err := NewEvent(msg.RoutingKey, msg.Body)
+	if err != nil {
+		return err
+	}
+	h.Broadcast(event)
+	return nil
+}
+
+/*
+Consumer returns a broker consumer feeding the hub from a queue private to this process.
+
+The queue is "<prefix>.<hostname>-<pid>" and auto-deleted when the process stops, so
+each worker gets its own copy of every user event. Events published while no worker
+was listening are recovered through Last-Event-ID replay, not the queue.
+*/
+func (h *Hub) Consumer(prefix string) *messaging.Consumer {
+	hostname, _ := os.Hostname()
+	return messaging.NewConsumer(messaging.ConsumerConfig{
+		Queue:      fmt.Sprintf("%s.%s-%d", prefix, hostname, os.Getpid()),
+		AutoDelete: true,
+		Workers:    1, // keep events in publish order
+	}).Handle("user.*", h.Handle)
+}
+
+// hub is the process-wide hub used by the stream handlers
+var hub = NewHub(defaultBuffer)
+
+/* GetHub returns the process-wide hub */
+func GetHub() *Hub {
+	return hub
+}
+
+/* SetHub replaces the process-wide hub; call at startup, before routes are set up */
+func SetHub(h *Hub) {
+	hub = h
+}
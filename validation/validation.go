@@ -0,0 +1,164 @@
+package validation
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"reflect"
+	"regexp"
+	"strings"
+	"sync"
+
+	"baseApi/dto"
+	"baseApi/i18n"
+
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+)
+
+// Patterns mirroring the CHECK constraints in scripts/manual_setup.sql
+var (
+	usernamePattern = regexp.MustCompile(`^[a-zA-Z0-9_]{3,50}$`)
+	emailPattern    = regexp.MustCompile(`^[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}$`)
+)
+
+// topicPattern matches event filters: dot-separated words, "*" (one word) or "#" (any number of words)
+var topicPattern = regexp.MustCompile(`^([a-z0-9_]+|\*|#)(\.([a-z0-9_]+|\*|#))*$`)
+
+// MaxWebhookEventLength bounds the event filters accepted by the webhook_event rule
+const MaxWebhookEventLength = 100
+
+var registerOnce sync.Once
+
+/* RegisterRules registers custom rules and JSON field naming on Gin's validator */
+func RegisterRules() {
+	registerOnce.Do(func() {
+		v, ok := binding.Validator.Engine().(*validator.Validate)
+		if !ok {
+			return
+		}
+
+		// Report fields by their JSON (or form) name instead of the Go struct field
+		v.RegisterTagNameFunc(func(f reflect.StructField) string {
+			for _, tag := range []string{"json", "form"} {
+				name := strings.Split(f.Tag.Get(tag), ",")[0]
+				if name == "-" {
+					return ""
+				}
+				if name != "" {
+					return name
+				}
+			}
+			return f.Name
+		})
+
+		// chk_users_username_format
+		v.RegisterValidation("username_format", func(fl validator.FieldLevel) bool {
+			return usernamePattern.MatchString(fl.Field().String())
+		})
+		// chk_users_email_format (stricter than the built-in "email" rule)
+		v.RegisterValidation("email_format", func(fl validator.FieldLevel) bool {
+			return emailPattern.MatchString(fl.Field().String())
+		})
+		// Absolute http(s) URL with a host (webhook endpoints)
+		v.RegisterValidation("http_url", func(fl validator.FieldLevel) bool {
+			u, err := url.Parse(fl.Field().String())
+			return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+		})
+		// Event type or topic pattern ("user.created", "user.*")
+		v.RegisterValidation("webhook_event", func(fl validator.FieldLevel) bool {
+			value := fl.Field().String()
+			return len(value) <= MaxWebhookEventLength && topicPattern.MatchString(value)
+		})
+	})
+}
+
+/* Pattern returns the regular expression checked by a custom string rule, for API documentation */
+func Pattern(rule string) (string, bool) {
+	switch rule {
+	case "username_format":
+		return usernamePattern.String(), true
+	case "email_format":
+		return emailPattern.String(), true
+	case "webhook_event":
+		return topicPattern.String(), true
+	}
+	return "", false
+}
+
+/* Translate converts binding errors into one ValidationError per JSON field */
+func Translate(err error) []dto.ValidationError {
+	var fieldErrors validator.ValidationErrors
+	if errors.As(err, &fieldErrors) {
+		validations := make([]dto.ValidationError, 0, len(fieldErrors))
+		seen := make(map[string]bool, len(fieldErrors))
+		for _, fe := range fieldErrors {
+			field := fe.Field()
+			if seen[field] {
+				continue
+			}
+			seen[field] = true
+			key, params := messageKey(fe)
+			validations = append(validations, newValidationError(field, fe.Tag(), key, params, safeValue(field, fe.Value())))
+		}
+		return validations
+	}
+
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) && typeErr.Field != "" {
+		params := map[string]string{"field": typeErr.Field, "param": typeErr.Type.String()}
+		return []dto.ValidationError{newValidationError(typeErr.Field, "type", "validation.type", params, "")}
+	}
+
+	return []dto.ValidationError{newValidationError("request", "format", "validation.format", nil, "")}
+}
+
+/* newValidationError builds a ValidationError whose message can be re-localized later */
+func newValidationError(field, rule, key string, params map[string]string, value string) dto.ValidationError {
+	return dto.ValidationError{
+		Field:   field,
+		Rule:    rule,
+		Message: i18n.T(i18n.DefaultLocale, key, params),
+		Value:   value,
+		Key:     key,
+		Params:  params,
+	}
+}
+
+/* messageKey picks the catalog key and placeholders for a failed rule */
+func messageKey(fe validator.FieldError) (string, map[string]string) {
+	params := map[string]string{"field": fe.Field(), "param": fe.Param()}
+
+	switch fe.Tag() {
+	case "min", "max":
+		if fe.Kind() == reflect.String {
+			return "validation." + fe.Tag() + ".string", params
+		}
+		return "validation." + fe.Tag(), params
+	case "oneof":
+		params["param"] = strings.ReplaceAll(fe.Param(), " ", ", ")
+		return "validation.oneof", params
+	}
+
+	key := "validation." + fe.Tag()
+	if _, ok := i18n.Lookup(i18n.DefaultLocale, key); ok {
+		return key, params
+	}
+	return "validation.invalid", params
+}
+
+/* safeValue echoes the rejected value back unless the field is sensitive */
+func safeValue(field string, value interface{}) string {
+	if lower := strings.ToLower(field); strings.Contains(lower, "password") || strings.Contains(lower, "secret") {
+		return ""
+	}
+	rv := reflect.ValueOf(value)
+	if rv.Kind() == reflect.Ptr {
+		if rv.IsNil() {
+			return ""
+		}
+		value = rv.Elem().Interface()
+	}
+	return fmt.Sprintf("%v", value)
+}
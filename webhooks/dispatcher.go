@@ -0,0 +1,102 @@
+package webhooks
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"baseApi/logger"
+	"baseApi/messaging"
+	"baseApi/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// EventPatterns are the routing keys the dispatcher's queue is bound to
+var EventPatterns = []string{"user.*"}
+
+/* Dispatcher turns broker events into one pending delivery per subscribed endpoint */
+type Dispatcher struct {
+	db *gorm.DB
+}
+
+/* NewDispatcher creates a dispatcher that records deliveries in db */
+func NewDispatcher(db *gorm.DB) *Dispatcher {
+	return &Dispatcher{db: db}
+}
+
+/*
+Consumer returns a broker consumer on queue that feeds the dispatcher.
+
+Database errors are requeued with the consumer's retry policy; events that aren't
+CloudEvents are rejected (dead-lettered) since retrying can't fix them.
+*/
+func (d *Dispatcher) Consumer(queue string) *messaging.Consumer {
+	consumer := messaging.NewConsumer(messaging.ConsumerConfig{
+		Queue: queue,
+		Retry: &messaging.RetryPolicy{},
+	})
+	for _, pattern := range EventPatterns {
+		consumer.Handle(pattern, d.Handle)
+	}
+	return consumer
+}
+
+/* Handle records a pending delivery of msg for every active endpoint subscribed to its routing key */
+func (d *Dispatcher) Handle(ctx context.Context, msg *messaging.Message) error {
+	var event messaging.CloudEvent
+	if err := json.Unmarshal(msg.Body, &event); err != nil {
+		return fmt.Errorf("webhook dispatcher: body is not a CloudEvent: %w", err)
+	}
+	if event.ID == "" {
+		return errors.New("webhook dispatcher: CloudEvent has no id")
+	}
+
+	var endpoints []models.WebhookEndpoint
+	if err := d.db.WithContext(ctx).Where("is_active = ?", true).Find(&endpoints).Error; err != nil {
+		return messaging.Requeue(err)
+	}
+
+	now := time.Now()
+	var deliveries []models.WebhookDelivery
+	for _, endpoint := range endpoints {
+		if !Subscribed(endpoint.Events, msg.RoutingKey) {
+			continue
+		}
+		deliveries = append(deliveries, models.WebhookDelivery{
+			EndpointID:    endpoint.ID,
+			EventID:       event.ID,
+			EventType:     msg.RoutingKey,
+			Payload:       msg.Body,
+			Status:        models.DeliveryPending,
+			NextAttemptAt: now,
+		})
+	}
+	if len(deliveries) == 0 {
+		return nil
+	}
+
+	// A redelivered event finds its deliveries already recorded
+	err := d.db.WithContext(ctx).
+		Clauses(clause.OnConflict{Columns: []clause.Column{{Name: "endpoint_id"}, {Name: "event_id"}}, DoNothing: true}).
+		Create(&deliveries).Error
+	if err != nil {
+		return messaging.Requeue(err)
+	}
+
+	logger.Debug(fmt.Sprintf("Webhook event %s (%s) queued for %d endpoints", event.ID, msg.RoutingKey, len(deliveries)))
+	return nil
+}
+
+/* Subscribed reports whether any of an endpoint's event filters matches eventType */
+func Subscribed(events []string, eventType string) bool {
+	for _, pattern := range events {
+		if messaging.TopicMatch(pattern, eventType) {
+			return true
+		}
+	}
+	return false
+}
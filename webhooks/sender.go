@@ -0,0 +1,94 @@
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"baseApi/messaging"
+	"baseApi/models"
+)
+
+// userAgent identifies webhook requests in receivers' logs
+const userAgent = "baseApi-Webhooks/1.0"
+
+// maxResponseBody caps how much of a receiver's response is kept in the delivery log
+const maxResponseBody = 1024
+
+/* Result is the outcome of one delivery attempt */
+type Result struct {
+	StatusCode int           // 0 when no response was received
+	Body       string        // start of the response body
+	Duration   time.Duration // time until the response (or error)
+	Err        error         // transport error or non-2xx status
+}
+
+/* OK reports whether the receiver accepted the event */
+func (r Result) OK() bool {
+	return r.Err == nil
+}
+
+/* Sender POSTs signed webhook payloads */
+type Sender struct {
+	client *http.Client
+}
+
+/*
+NewSender creates a sender whose requests give up after timeout.
+
+Redirects are not followed: a 3xx counts as a failed attempt, so a moved endpoint
+shows up in the delivery log instead of silently sending events elsewhere.
+*/
+func NewSender(timeout time.Duration) *Sender {
+	return &Sender{
+		client: &http.Client{
+			Timeout: timeout,
+			CheckRedirect: func(*http.Request, []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+	}
+}
+
+/* Send delivers one attempt of a delivery to url, signed with secret */
+func (s *Sender) Send(ctx context.Context, url, secret string, delivery *models.WebhookDelivery) Result {
+	started := time.Now()
+	result := s.send(ctx, url, secret, delivery, started)
+	result.Duration = time.Since(started)
+	return result
+}
+
+/* send builds and performs the signed request */
+func (s *Sender) send(ctx context.Context, url, secret string, delivery *models.WebhookDelivery, sentAt time.Time) Result {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		return Result{Err: fmt.Errorf("invalid webhook request: %w", err)}
+	}
+	req.Header.Set("Content-Type", messaging.CloudEventsContentType)
+	req.Header.Set("User-Agent", userAgent)
+	req.Header.Set(HeaderEventID, delivery.EventID)
+	req.Header.Set(HeaderEventType, delivery.EventType)
+	req.Header.Set(HeaderDelivery, strconv.FormatUint(delivery.ID, 10))
+	req.Header.Set(HeaderAttempt, strconv.Itoa(delivery.Attempts+1))
+	req.Header.Set(HeaderSignature, Sign(secret, sentAt, delivery.Payload))
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return Result{Err: err}
+	}
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, maxResponseBody))
+	// Drain a little more so the connection can be reused
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64*1024))
+
+	result := Result{StatusCode: resp.StatusCode, Body: string(body)}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		result.Err = fmt.Errorf("receiver responded %s", resp.Status)
+	}
+	return result
+}
@@ -0,0 +1,272 @@
+package webhooks
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"baseApi/config"
+	"baseApi/logger"
+	"baseApi/models"
+
+	"gorm.io/gorm"
+)
+
+const (
+	// baseBackoff is the delay before the second attempt; it doubles per failed attempt
+	baseBackoff = time.Minute
+	// maxBackoff caps the delay between attempts of one delivery
+	maxBackoff = 6 * time.Hour
+	// leaseGrace is added to the request timeout when leasing a delivery
+	leaseGrace = 30 * time.Second
+	// purgeInterval is how often finished deliveries past the retention window are deleted
+	purgeInterval = time.Hour
+)
+
+/* Worker delivers due webhook deliveries and records each attempt */
+type Worker struct {
+	db           *gorm.DB
+	sender       *Sender
+	pollInterval time.Duration
+	batchSize    int
+	concurrency  int
+	maxAttempts  int
+	disableAfter int
+	leaseFor     time.Duration
+	retention    time.Duration
+	lastPurge    time.Time
+}
+
+/* NewWorker creates a delivery worker from the webhook settings */
+func NewWorker(db *gorm.DB, cfg *config.Config) *Worker {
+	concurrency := cfg.WebhookConcurrency
+	if concurrency <= 0 {
+		concurrency = 1
+	}
+	return &Worker{
+		db:           db,
+		sender:       NewSender(cfg.WebhookTimeout),
+		pollInterval: cfg.WebhookPollInterval,
+		batchSize:    cfg.WebhookBatchSize,
+		concurrency:  concurrency,
+		maxAttempts:  cfg.WebhookMaxAttempts,
+		disableAfter: cfg.WebhookDisableAfterFailures,
+		leaseFor:     cfg.WebhookTimeout + leaseGrace,
+		retention:    cfg.WebhookRetention,
+	}
+}
+
+/* Run polls for due deliveries until the context is cancelled */
+func (w *Worker) Run(ctx context.Context) {
+	ticker := time.NewTicker(w.pollInterval)
+	defer ticker.Stop()
+
+	logger.Info("Webhook worker started, polling every", w.pollInterval)
+	for {
+		select {
+		case <-ctx.Done():
+			logger.Info("Webhook worker stopped")
+			return
+		case <-ticker.C:
+			w.drain(ctx)
+		}
+	}
+}
+
+/* drain delivers full batches until nothing is due */
+func (w *Worker) drain(ctx context.Context) {
+	for ctx.Err() == nil {
+		delivered, err := w.deliverBatch(ctx)
+		if err != nil {
+			logger.Error("Webhook delivery batch failed:", err)
+			return
+		}
+		if delivered < w.batchSize {
+			w.purgeFinished(ctx)
+			return
+		}
+	}
+}
+
+/* deliverBatch leases due deliveries and sends them concurrently */
+func (w *Worker) deliverBatch(ctx context.Context) (int, error) {
+	deliveries, err := w.lease(ctx)
+	if err != nil || len(deliveries) == 0 {
+		return 0, err
+	}
+
+	endpointIDs := make([]uint, 0, len(deliveries))
+	for _, delivery := range deliveries {
+		endpointIDs = append(endpointIDs, delivery.EndpointID)
+	}
+	var endpoints []models.WebhookEndpoint
+	if err := w.db.WithContext(ctx).Where("id IN ?", endpointIDs).Find(&endpoints).Error; err != nil {
+		return 0, err
+	}
+	byID := make(map[uint]*models.WebhookEndpoint, len(endpoints))
+	for i := range endpoints {
+		byID[endpoints[i].ID] = &endpoints[i]
+	}
+
+	var wg sync.WaitGroup
+	slots := make(chan struct{}, w.concurrency)
+	for i := range deliveries {
+		slots <- struct{}{}
+		wg.Add(1)
+		go func(delivery *models.WebhookDelivery) {
+			defer func() { <-slots; wg.Done() }()
+			w.deliver(ctx, delivery, byID[delivery.EndpointID])
+		}(&deliveries[i])
+	}
+	wg.Wait()
+
+	return len(deliveries), nil
+}
+
+/*
+lease claims up to batchSize due deliveries by pushing their next attempt past the
+request timeout.
+
+SKIP LOCKED lets every API worker run a delivery worker without sending twice. If
+the process dies mid-attempt, the lease expires and the delivery is picked up again
+without counting the lost attempt; receivers should dedupe on Webhook-Id.
+*/
+func (w *Worker) lease(ctx context.Context) ([]models.WebhookDelivery, error) {
+	now := time.Now()
+	var deliveries []models.WebhookDelivery
+	err := w.db.WithContext(ctx).Raw(`
+		UPDATE webhook_deliveries SET next_attempt_at = ?, updated_at = ?
+		WHERE id IN (
+			SELECT id FROM webhook_deliveries
+			WHERE status IN (?, ?) AND next_attempt_at <= ?
+			ORDER BY next_attempt_at
+			LIMIT ?
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING *`,
+		now.Add(w.leaseFor), now, models.DeliveryPending, models.DeliveryRetrying, now, w.batchSize,
+	).Scan(&deliveries).Error
+	return deliveries, err
+}
+
+/* deliver sends one attempt, or fails the delivery when its endpoint is gone or disabled */
+func (w *Worker) deliver(ctx context.Context, delivery *models.WebhookDelivery, endpoint *models.WebhookEndpoint) {
+	switch {
+	case endpoint == nil:
+		w.abandon(ctx, delivery, "endpoint deleted")
+		return
+	case !endpoint.IsActive:
+		w.abandon(ctx, delivery, "endpoint disabled")
+		return
+	}
+
+	result := w.sender.Send(ctx, endpoint.URL, endpoint.Secret, delivery)
+	if ctx.Err() != nil {
+		// Shutting down; the lease expires and the attempt is repeated
+		return
+	}
+	if err := w.record(ctx, delivery, endpoint, result); err != nil {
+		logger.Error(fmt.Sprintf("Failed to record webhook delivery %d:", delivery.ID), err)
+	}
+}
+
+/* record stores the outcome of an attempt and updates the endpoint's failure streak */
+func (w *Worker) record(ctx context.Context, delivery *models.WebhookDelivery, endpoint *models.WebhookEndpoint, result Result) error {
+	now := time.Now()
+	delivery.Attempts++
+	updates := map[string]interface{}{
+		"attempts":        delivery.Attempts,
+		"response_status": result.StatusCode,
+		"response_body":   result.Body,
+		"duration_ms":     result.Duration.Milliseconds(),
+		"updated_at":      now,
+	}
+
+	if result.OK() {
+		updates["status"] = models.DeliverySucceeded
+		updates["delivered_at"] = now
+		updates["last_error"] = ""
+	} else {
+		updates["last_error"] = result.Err.Error()
+		if delivery.Attempts >= w.maxAttempts {
+			updates["status"] = models.DeliveryFailed
+			logger.Warn(fmt.Sprintf("Webhook delivery %d (%s to endpoint %d) failed after %d attempts:", delivery.ID, delivery.EventType, endpoint.ID, delivery.Attempts), result.Err)
+		} else {
+			updates["status"] = models.DeliveryRetrying
+			updates["next_attempt_at"] = now.Add(Backoff(delivery.Attempts))
+		}
+	}
+
+	return w.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.WebhookDelivery{}).Where("id = ?", delivery.ID).Updates(updates).Error; err != nil {
+			return err
+		}
+		if result.OK() {
+			return tx.Model(&models.WebhookEndpoint{}).Where("id = ?", endpoint.ID).
+				Updates(map[string]interface{}{"consecutive_failures": 0, "last_success_at": now}).Error
+		}
+		return w.recordEndpointFailure(tx, endpoint, now)
+	})
+}
+
+/* recordEndpointFailure extends the endpoint's failure streak and disables it once the streak reaches disableAfter */
+func (w *Worker) recordEndpointFailure(tx *gorm.DB, endpoint *models.WebhookEndpoint, now time.Time) error {
+	err := tx.Model(&models.WebhookEndpoint{}).Where("id = ?", endpoint.ID).
+		UpdateColumn("consecutive_failures", gorm.Expr("consecutive_failures + 1")).Error
+	if err != nil || w.disableAfter <= 0 {
+		return err
+	}
+
+	reason := fmt.Sprintf("%d consecutive failed deliveries", w.disableAfter)
+	result := tx.Model(&models.WebhookEndpoint{}).
+		Where("id = ? AND is_active AND consecutive_failures >= ?", endpoint.ID, w.disableAfter).
+		Updates(map[string]interface{}{"is_active": false, "disabled_at": now, "disabled_reason": reason})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected > 0 {
+		logger.Warn(fmt.Sprintf("Webhook endpoint %d (%s) disabled after %s", endpoint.ID, endpoint.URL, reason))
+	}
+	return nil
+}
+
+/* abandon fails a delivery without sending it */
+func (w *Worker) abandon(ctx context.Context, delivery *models.WebhookDelivery, reason string) {
+	err := w.db.WithContext(ctx).Model(&models.WebhookDelivery{}).Where("id = ?", delivery.ID).
+		Updates(map[string]interface{}{"status": models.DeliveryFailed, "last_error": reason, "updated_at": time.Now()}).Error
+	if err != nil {
+		logger.Error(fmt.Sprintf("Failed to abandon webhook delivery %d:", delivery.ID), err)
+	}
+}
+
+/* purgeFinished deletes succeeded and failed deliveries older than the retention window, at most hourly */
+func (w *Worker) purgeFinished(ctx context.Context) {
+	if w.retention <= 0 || time.Since(w.lastPurge) < purgeInterval {
+		return
+	}
+	w.lastPurge = time.Now()
+
+	cutoff := time.Now().Add(-w.retention)
+	err := w.db.WithContext(ctx).
+		Where("status IN ? AND updated_at < ?", []string{models.DeliverySucceeded, models.DeliveryFailed}, cutoff).
+		Delete(&models.WebhookDelivery{}).Error
+	if err != nil {
+		logger.Error("Failed to purge finished webhook deliveries:", err)
+	}
+}
+
+/* Backoff returns the delay after a failed attempt: 1m, 2m, 4m ... capped at 6h */
+func Backoff(attempts int) time.Duration {
+	if attempts < 1 {
+		attempts = 1
+	}
+	if attempts > 12 {
+		return maxBackoff
+	}
+	backoff := baseBackoff << uint(attempts-1)
+	if backoff > maxBackoff {
+		return maxBackoff
+	}
+	return backoff
+}